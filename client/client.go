@@ -0,0 +1,292 @@
+// Package client provides a Go client for reading, writing and subscribing to boardcast boards.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// board is the board the server serves.
+const board = "default"
+
+var (
+	// ErrUnauthorized is returned when the server rejects the credentials.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrConflict is returned when a conditional write targets a stale revision.
+	ErrConflict = errors.New("revision conflict")
+)
+
+// Content is a board's content at a given revision. ETag identifies the
+// content for conditional writes.
+type Content struct {
+	Text     string
+	Revision uint64
+	ETag     string
+}
+
+// Client talks to a boardcast server over HTTP and WebSocket.
+type Client struct {
+	baseURL    *url.URL
+	token      string
+	password   string
+	user       string
+	httpClient *http.Client
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithToken authenticates with an API token sent as a bearer credential.
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithPassword authenticates by logging in with the board password.
+func WithPassword(password string) Option {
+	return func(c *Client) { c.password = password }
+}
+
+// WithUser sets the name recorded for edits made after password login.
+func WithUser(user string) Option {
+	return func(c *Client) { c.user = user }
+}
+
+// WithHTTPClient sets the HTTP client used for requests. The client is copied,
+// and the copy gets a cookie jar for password authentication if it has none.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithBackoff sets the minimum and maximum delay between reconnect attempts.
+func WithBackoff(min, max time.Duration) Option {
+	return func(c *Client) {
+		c.minBackoff = min
+		c.maxBackoff = max
+	}
+}
+
+// Connect creates a client for the server at serverURL and verifies the credentials.
+func Connect(ctx context.Context, serverURL string, opts ...Option) (*Client, error) {
+	baseURL, err := url.Parse(strings.TrimSuffix(serverURL, "/"))
+	if err != nil || baseURL.Host == "" {
+		return nil, fmt.Errorf("invalid server URL: %s", serverURL)
+	}
+
+	c := &Client{
+		baseURL:    baseURL,
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.token == "" && c.password == "" {
+		return nil, errors.New("either a token or a password is required")
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 30 * time.Second}
+	} else {
+		httpClient := *c.httpClient
+		c.httpClient = &httpClient
+	}
+
+	if c.token == "" {
+		if c.httpClient.Jar == nil {
+			jar, err := cookiejar.New(nil)
+			if err != nil {
+				return nil, err
+			}
+			c.httpClient.Jar = jar
+		}
+		if err := c.login(ctx); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	if _, err := c.Get(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the current board content and revision.
+func (c *Client) Get(ctx context.Context) (Content, error) {
+	resp, err := c.do(ctx, http.MethodGet, c.contentPath(), nil, nil)
+	if err != nil {
+		return Content{}, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusOK); err != nil {
+		return Content{}, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Content{}, err
+	}
+
+	etag := resp.Header.Get("ETag")
+	revision, _ := parseETag(etag)
+	return Content{Text: string(data), Revision: revision, ETag: etag}, nil
+}
+
+// Set replaces the board content unconditionally and returns the new revision.
+func (c *Client) Set(ctx context.Context, text string) (uint64, error) {
+	return c.put(ctx, text, "*")
+}
+
+// SetIf replaces the board content only if it still holds base, as returned
+// by Get. It returns ErrConflict when the board has changed since.
+func (c *Client) SetIf(ctx context.Context, text string, base Content) (uint64, error) {
+	return c.put(ctx, text, base.ETag)
+}
+
+// Append appends text to the end of the board content and returns the new revision.
+func (c *Client) Append(ctx context.Context, text string) (uint64, error) {
+	body, err := json.Marshal(map[string]string{"op": "append", "text": text})
+	if err != nil {
+		return 0, err
+	}
+
+	return c.write(ctx, http.MethodPatch, body, http.Header{
+		"Content-Type": {"application/json"},
+	})
+}
+
+// put sends a conditional PUT with the given If-Match value.
+func (c *Client) put(ctx context.Context, text, match string) (uint64, error) {
+	return c.write(ctx, http.MethodPut, []byte(text), http.Header{
+		"If-Match":     {match},
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+}
+
+// write performs a content write and extracts the resulting revision.
+func (c *Client) write(ctx context.Context, method string, body []byte, header http.Header) (uint64, error) {
+	resp, err := c.do(ctx, method, c.contentPath(), body, header)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp, http.StatusNoContent); err != nil {
+		return 0, err
+	}
+
+	revision, _ := parseETag(resp.Header.Get("ETag"))
+	return revision, nil
+}
+
+// login establishes a session cookie using the password.
+func (c *Client) login(ctx context.Context) error {
+	body, err := json.Marshal(map[string]string{"password": c.password, "user": c.user})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint("/auth"), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp, http.StatusOK)
+}
+
+// do sends an authenticated request, logging in again once if the session expired.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path), bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		for key, values := range c.authHeader() {
+			req.Header[key] = values
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusUnauthorized || c.token != "" || attempt > 0 {
+			return resp, nil
+		}
+		resp.Body.Close()
+
+		if err := c.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authHeader returns the Authorization header for token authentication.
+func (c *Client) authHeader() http.Header {
+	header := http.Header{}
+	if c.token != "" {
+		header.Set("Authorization", "Bearer "+c.token)
+	}
+	return header
+}
+
+// endpoint resolves path against the server URL.
+func (c *Client) endpoint(path string) string {
+	return c.baseURL.String() + path
+}
+
+// contentPath returns the REST path of the board content.
+func (c *Client) contentPath() string {
+	return "/api/v1/boards/" + board + "/content"
+}
+
+// checkResponse converts unexpected status codes into errors.
+func checkResponse(resp *http.Response, expected int) error {
+	switch resp.StatusCode {
+	case expected:
+		return nil
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusPreconditionFailed:
+		return ErrConflict
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+}
+
+// parseETag extracts the revision that starts an entity tag.
+func parseETag(tag string) (uint64, bool) {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	rev, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+	revision, err := strconv.ParseUint(rev, 10, 64)
+	return revision, err == nil
+}
@@ -0,0 +1,156 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/client"
+	"github.com/yosebyte/boardcast/server"
+)
+
+// newTestServer starts a server accepting the password "pw" and the token "tk".
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s, err := server.New(server.WithPassword("pw"), server.WithToken("tk"), server.WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(func() {
+		srv.Close()
+		s.Shutdown(context.Background())
+	})
+	return srv
+}
+
+func TestConnect(t *testing.T) {
+	srv := newTestServer(t)
+	tests := []struct {
+		name    string
+		url     string
+		opts    []client.Option
+		wantErr error
+	}{
+		{"token", srv.URL, []client.Option{client.WithToken("tk")}, nil},
+		{"password", srv.URL + "/", []client.Option{client.WithPassword("pw"), client.WithUser("alice")}, nil},
+		{"wrong token", srv.URL, []client.Option{client.WithToken("x")}, client.ErrUnauthorized},
+		{"wrong password", srv.URL, []client.Option{client.WithPassword("x")}, client.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Connect(context.Background(), tt.url, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Connect() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// The caller's HTTP client is left as it was.
+	for _, opt := range []client.Option{client.WithToken("tk"), client.WithPassword("pw")} {
+		httpClient := &http.Client{Timeout: 10 * time.Second}
+		if _, err := client.Connect(context.Background(), srv.URL, opt, client.WithHTTPClient(httpClient)); err != nil {
+			t.Fatal(err)
+		}
+		if httpClient.Jar != nil {
+			t.Error("Connect() installed a cookie jar on the caller's HTTP client")
+		}
+	}
+
+	for _, url := range []string{"", "localhost"} {
+		if _, err := client.Connect(context.Background(), url, client.WithToken("tk")); err == nil {
+			t.Errorf("Connect(%q) accepted an invalid URL", url)
+		}
+	}
+	if _, err := client.Connect(context.Background(), srv.URL); err == nil {
+		t.Error("Connect() accepted no credentials")
+	}
+}
+
+func TestWrite(t *testing.T) {
+	srv := newTestServer(t)
+	ctx := context.Background()
+	for _, opt := range []client.Option{client.WithToken("tk"), client.WithPassword("pw")} {
+		c, err := client.Connect(ctx, srv.URL, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := c.Set(ctx, "a\n"); err != nil {
+			t.Fatal(err)
+		}
+		base, err := c.Get(ctx)
+		if err != nil || base.Text != "a\n" {
+			t.Fatalf("Get() = %+v, %v, want %q", base, err, "a\n")
+		}
+		revision, err := c.Append(ctx, "b")
+		if err != nil || revision != base.Revision+1 {
+			t.Fatalf("Append() = %d, %v, want revision %d", revision, err, base.Revision+1)
+		}
+
+		// The board changed since base was read.
+		if _, err := c.SetIf(ctx, "c\n", base); !errors.Is(err, client.ErrConflict) {
+			t.Errorf("SetIf(stale) error = %v, want %v", err, client.ErrConflict)
+		}
+		current, err := c.Get(ctx)
+		if err != nil || current.Text != "a\nb" || current.Revision != revision {
+			t.Fatalf("Get() = %+v, %v, want %q at revision %d", current, err, "a\nb", revision)
+		}
+		if current.ETag == "" || current.ETag == base.ETag {
+			t.Fatalf("Get() ETag = %q, want a new one after %q", current.ETag, base.ETag)
+		}
+		revision, err = c.SetIf(ctx, "c\n", current)
+		if err != nil || revision != current.Revision+1 {
+			t.Errorf("SetIf(current) = %d, %v, want revision %d", revision, err, current.Revision+1)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.Connect(ctx, srv.URL, client.WithToken("tk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set(ctx, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	updates, err := c.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := func() string {
+		t.Helper()
+		select {
+		case u, ok := <-updates:
+			if !ok || u.Err != nil {
+				t.Fatalf("subscription ended: %v", u.Err)
+			}
+			return u.Content
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an update")
+			return ""
+		}
+	}
+
+	if got := next(); got != "first" {
+		t.Errorf("first update = %q, want the current content", got)
+	}
+	if _, err := c.Set(ctx, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if got := next(); got != "second" {
+		t.Errorf("update = %q, want %q", got, "second")
+	}
+
+	cancel()
+	for range updates {
+	}
+}
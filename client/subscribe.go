@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Update is a content update received from the server. Err is set instead of
+// Content when the connection failed; the subscription reconnects afterwards.
+type Update struct {
+	Content string
+	Err     error
+}
+
+// Subscribe streams board updates until ctx is cancelled, reconnecting with
+// exponential backoff whenever the connection drops. The channel is closed on return.
+func (c *Client) Subscribe(ctx context.Context) (<-chan Update, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(chan Update, 16)
+	go c.subscribe(ctx, conn, updates)
+	return updates, nil
+}
+
+// subscribe reads from conn and redials after failures.
+func (c *Client) subscribe(ctx context.Context, conn *websocket.Conn, updates chan<- Update) {
+	defer close(updates)
+
+	backoff := c.minBackoff
+	for {
+		if conn != nil {
+			backoff = c.minBackoff
+			err := c.read(ctx, conn, updates)
+			if ctx.Err() != nil {
+				return
+			}
+			if !c.deliver(ctx, updates, Update{Err: err}) {
+				return
+			}
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, c.maxBackoff)
+
+		var err error
+		if conn, err = c.dial(ctx); err != nil {
+			if !c.deliver(ctx, updates, Update{Err: err}) {
+				return
+			}
+		}
+	}
+}
+
+// read forwards messages from conn until it fails or ctx is cancelled.
+func (c *Client) read(ctx context.Context, conn *websocket.Conn, updates chan<- Update) error {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+
+		if !c.deliver(ctx, updates, Update{Content: string(message)}) {
+			return ctx.Err()
+		}
+	}
+}
+
+// deliver sends update unless ctx is cancelled first.
+func (c *Client) deliver(ctx context.Context, updates chan<- Update, update Update) bool {
+	select {
+	case updates <- update:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// dial opens an authenticated WebSocket connection, logging in again once if required.
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	wsURL := *c.baseURL
+	switch wsURL.Scheme {
+	case "https":
+		wsURL.Scheme = "wss"
+	default:
+		wsURL.Scheme = "ws"
+	}
+	wsURL.Path += "/ws"
+
+	dialer := websocket.Dialer{
+		Jar:              c.httpClient.Jar,
+		HandshakeTimeout: 10 * time.Second,
+	}
+
+	for attempt := 0; ; attempt++ {
+		conn, resp, err := dialer.DialContext(ctx, wsURL.String(), c.authHeader())
+		if err == nil {
+			return conn, nil
+		}
+		if resp == nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+		}
+		if c.token != "" || attempt > 0 {
+			return nil, ErrUnauthorized
+		}
+
+		if err := c.login(ctx); err != nil {
+			if errors.Is(err, ErrUnauthorized) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("login failed: %w", err)
+		}
+	}
+}
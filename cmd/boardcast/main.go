@@ -3,14 +3,32 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/yosebyte/boardcast/internal"
+	"github.com/yosebyte/boardcast/internal/cli"
 	"github.com/yosebyte/boardcast/internal/config"
 )
 
 var version = "dev"
 
 func main() {
+	// Dispatch client subcommands
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if err := cli.Run(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatalf("%s: %v", os.Args[1], err)
+		}
+		return
+	}
+
+	// Re-encrypt stored data with the current storage key
+	if len(os.Args) > 1 && os.Args[1] == "rekey" {
+		if err := internal.Rekey(os.Args[2:]); err != nil {
+			log.Fatalf("rekey: %v", err)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg := config.Load(version)
 
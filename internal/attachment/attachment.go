@@ -0,0 +1,189 @@
+// Package attachment stores uploaded files by content hash and removes those
+// no longer referenced by any board.
+package attachment
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSize is the default upload size limit in bytes.
+const DefaultMaxSize = 10 << 20
+
+// gracePeriod protects fresh uploads that have not been inserted into a board yet.
+const gracePeriod = time.Hour
+
+var (
+	// ErrTooLarge is returned when an upload exceeds the size limit.
+	ErrTooLarge = errors.New("attachment too large")
+	// ErrUnsupportedType is returned when an upload's detected type is not allowed.
+	ErrUnsupportedType = errors.New("unsupported attachment type")
+	// ErrNotFound is returned when no attachment has the requested name.
+	ErrNotFound = errors.New("attachment not found")
+)
+
+// types maps allowed content types to the extension they are stored with.
+// Markup formats such as HTML and SVG are excluded since they can run scripts.
+var types = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"text/plain":      ".txt",
+}
+
+// namePattern matches stored attachment names and references to them in board content.
+var namePattern = regexp.MustCompile(`[0-9a-f]{64}\.(?:png|jpg|gif|webp|pdf|txt)`)
+
+// Attachment describes a stored file.
+type Attachment struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+}
+
+// IsImage reports whether the attachment can be shown inline as an image.
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.Type, "image/")
+}
+
+// Store keeps attachments as files named after the SHA-256 of their content.
+type Store struct {
+	dir     string
+	maxSize int64
+}
+
+// NewStore creates a store in dir, creating the directory if needed.
+func NewStore(dir string, maxSize int64) (*Store, error) {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, maxSize: maxSize}, nil
+}
+
+// Put stores the content read from r. Identical content is stored once.
+func (s *Store) Put(r io.Reader) (Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxSize+1))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if int64(len(data)) > s.maxSize {
+		return Attachment{}, ErrTooLarge
+	}
+
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	ext, ok := types[contentType]
+	if !ok {
+		return Attachment{}, ErrUnsupportedType
+	}
+
+	sum := sha256.Sum256(data)
+	a := Attachment{
+		Name: hex.EncodeToString(sum[:]) + ext,
+		Type: contentType,
+		Size: int64(len(data)),
+	}
+
+	path := filepath.Join(s.dir, a.Name)
+	if _, err := os.Stat(path); err == nil {
+		// Refresh the timestamp so a pending collection does not remove it.
+		now := time.Now()
+		return a, os.Chtimes(path, now, now)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bytes.NewReader(data)); err != nil {
+		tmp.Close()
+		return Attachment{}, err
+	}
+	if err := tmp.Close(); err != nil {
+		return Attachment{}, err
+	}
+	return a, os.Rename(tmp.Name(), path)
+}
+
+// Open returns the attachment with the given name.
+func (s *Store) Open(name string) (*os.File, Attachment, error) {
+	if namePattern.FindString(name) != name {
+		return nil, Attachment{}, ErrNotFound
+	}
+
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, Attachment{}, ErrNotFound
+	} else if err != nil {
+		return nil, Attachment{}, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, Attachment{}, err
+	}
+
+	a := Attachment{Name: name, Size: info.Size()}
+	for contentType, ext := range types {
+		if filepath.Ext(name) == ext {
+			a.Type = contentType
+		}
+	}
+	return f, a, nil
+}
+
+// References returns the names of attachments referenced in content.
+func References(content string) []string {
+	return namePattern.FindAllString(content, -1)
+}
+
+// Collect removes attachments not referenced by any of contents, skipping
+// uploads younger than the grace period. It returns the number removed.
+func (s *Store) Collect(contents ...string) (int, error) {
+	referenced := make(map[string]bool)
+	for _, content := range contents {
+		for _, name := range References(content) {
+			referenced[name] = true
+		}
+	}
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || referenced[name] || namePattern.FindString(name) != name {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < gracePeriod {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
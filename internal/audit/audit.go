@@ -0,0 +1,155 @@
+// Package audit records who changed what and when in an append-only log.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionLogin       = "login"
+	ActionLoginFailed = "login_failed"
+	ActionLogout      = "logout"
+	ActionEdit        = "edit"
+	ActionSave        = "save"
+	ActionRestore     = "restore"
+	ActionJoin        = "join"
+	ActionLeave       = "leave"
+	ActionConflict    = "conflict"
+	ActionWipe        = "wipe"
+)
+
+// Actor identifies who performed an action and from where.
+type Actor struct {
+	User       string `json:"user,omitempty"`
+	Session    string `json:"session,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+}
+
+// Entry is a single audit record.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Actor
+	Board     string `json:"board,omitempty"`
+	Revision  uint64 `json:"revision,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	SizeDelta int    `json:"size_delta,omitempty"`
+	Edits     int    `json:"edits,omitempty"`
+	// Reason says why a board was wiped.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Filter selects entries when querying the log. Zero values match everything.
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	User   string
+	Action string
+	Limit  int
+}
+
+// matches reports whether e satisfies the filter.
+func (f Filter) matches(e Entry) bool {
+	switch {
+	case !f.From.IsZero() && e.Time.Before(f.From):
+		return false
+	case !f.To.IsZero() && e.Time.After(f.To):
+		return false
+	case f.User != "" && e.User != f.User:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	}
+	return true
+}
+
+// Log stores and queries audit entries.
+type Log interface {
+	// Record appends an entry, stamping the current time if unset.
+	Record(e Entry) error
+	// Query returns matching entries in chronological order, keeping the most
+	// recent ones when a limit is set.
+	Query(f Filter) ([]Entry, error)
+}
+
+// Discard is a Log that drops every entry.
+var Discard Log = discard{}
+
+type discard struct{}
+
+func (discard) Record(Entry) error            { return nil }
+func (discard) Query(Filter) ([]Entry, error) { return nil, nil }
+
+// FileLog appends entries as JSON lines to a file.
+type FileLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewFileLog opens or creates the JSON lines file at path for appending.
+func NewFileLog(path string) (*FileLog, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLog{path: path, file: file}, nil
+}
+
+// Record appends e as a single JSON line.
+func (l *FileLog) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+// Query scans the file for matching entries.
+func (l *FileLog) Query(f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		if !f.matches(e) {
+			continue
+		}
+		entries = append(entries, e)
+		if f.Limit > 0 && len(entries) > f.Limit {
+			entries = entries[1:]
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// Close closes the underlying file.
+func (l *FileLog) Close() error {
+	return l.file.Close()
+}
@@ -3,40 +3,128 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/sessions"
+	"github.com/yosebyte/boardcast/internal/audit"
 	"golang.org/x/crypto/bcrypt"
 )
 
 const (
 	SessionName = "boardcast-session"
 	AuthKey     = "authenticated"
+	SessionKey  = "session_id"
+	UserKey     = "user"
 )
 
+// Users attributed to requests that do not carry a name.
+const (
+	AnonymousUser = "anonymous"
+	TokenUser     = "api"
+)
+
+// Provider authenticates requests and handles login and logout.
+type Provider interface {
+	// IsAuthenticated reports whether the request carries valid credentials.
+	IsAuthenticated(r *http.Request) bool
+	// Login processes a login request and writes the response.
+	Login(w http.ResponseWriter, r *http.Request)
+	// Logout processes a logout request and writes the response.
+	Logout(w http.ResponseWriter, r *http.Request)
+}
+
+// Identity describes who made a request.
+type Identity struct {
+	Session string
+	User    string
+}
+
+// Actor returns the audit actor for identity making request r.
+func (i Identity) Actor(r *http.Request) audit.Actor {
+	return audit.Actor{User: i.User, Session: i.Session, RemoteAddr: r.RemoteAddr}
+}
+
+// Identifier is implemented by providers that can attribute requests to a user.
+type Identifier interface {
+	// Identify returns the identity of an authenticated request.
+	Identify(r *http.Request) Identity
+}
+
+// IdentityOf returns the identity of r when p implements Identifier.
+func IdentityOf(p Provider, r *http.Request) Identity {
+	if identifier, ok := p.(Identifier); ok {
+		return identifier.Identify(r)
+	}
+	return Identity{User: AnonymousUser}
+}
+
+// Administrator is implemented by providers that tell administrative
+// credentials apart from the shared board password.
+type Administrator interface {
+	// IsAdmin reports whether the request carries administrative credentials.
+	IsAdmin(r *http.Request) bool
+}
+
+// IsAdmin reports whether r carries administrative credentials. Providers
+// not implementing Administrator have none.
+func IsAdmin(p Provider, r *http.Request) bool {
+	if admin, ok := p.(Administrator); ok {
+		return admin.IsAdmin(r)
+	}
+	return false
+}
+
+// Options configures a Manager.
+type Options struct {
+	// Token is the API token accepted as a bearer credential; empty disables it.
+	Token string
+	// BasePath scopes the session cookie.
+	BasePath string
+	// SessionKey signs session cookies. Instances sharing the key accept each
+	// other's sessions; a random key is generated when empty.
+	SessionKey []byte
+	Logger     *slog.Logger
+	Audit      audit.Log
+}
+
 // Manager handles authentication operations.
 type Manager struct {
 	hashedPassword []byte
+	token          []byte
 	store          *sessions.CookieStore
+	logger         *slog.Logger
+	audit          audit.Log
 }
 
-// NewManager creates a new authentication manager.
-func NewManager(password string) (*Manager, error) {
+// NewManager creates a new authentication manager for password.
+func NewManager(password string, opts Options) (*Manager, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 
-	sessionKey := make([]byte, 32)
-	if _, err := rand.Read(sessionKey); err != nil {
-		return nil, err
+	sessionKey := opts.SessionKey
+	if len(sessionKey) == 0 {
+		sessionKey = make([]byte, 32)
+		if _, err := rand.Read(sessionKey); err != nil {
+			return nil, err
+		}
 	}
 
 	store := sessions.NewCookieStore(sessionKey)
 
+	cookiePath := opts.BasePath
+	if cookiePath == "" {
+		cookiePath = "/"
+	}
+
 	store.Options = &sessions.Options{
-		Path:     "/",
+		Path:     cookiePath,
 		MaxAge:   86400 * 7,
 		HttpOnly: true,
 		Secure:   false,
@@ -45,17 +133,26 @@ func NewManager(password string) (*Manager, error) {
 
 	return &Manager{
 		hashedPassword: hashedPassword,
+		token:          []byte(opts.Token),
 		store:          store,
+		logger:         opts.Logger,
+		audit:          opts.Audit,
 	}, nil
 }
 
 // LoginRequest represents the JSON structure for login requests.
+// User optionally names the person logging in for attribution.
 type LoginRequest struct {
 	Password string `json:"password"`
+	User     string `json:"user,omitempty"`
 }
 
 // IsAuthenticated checks if the request is authenticated.
 func (m *Manager) IsAuthenticated(r *http.Request) bool {
+	if m.hasValidToken(r) {
+		return true
+	}
+
 	session, err := m.store.Get(r, SessionName)
 	if err != nil {
 		return false
@@ -69,6 +166,45 @@ func (m *Manager) IsAuthenticated(r *http.Request) bool {
 	return ok && auth
 }
 
+// Identify returns the session and user of an authenticated request.
+func (m *Manager) Identify(r *http.Request) Identity {
+	if m.hasValidToken(r) {
+		return Identity{User: TokenUser}
+	}
+
+	session, err := m.store.Get(r, SessionName)
+	if err != nil {
+		return Identity{User: AnonymousUser}
+	}
+
+	id, _ := session.Values[SessionKey].(string)
+	user, _ := session.Values[UserKey].(string)
+	if user == "" {
+		user = AnonymousUser
+	}
+	return Identity{Session: id, User: user}
+}
+
+// IsAdmin reports whether the request carries the API token, which unlike
+// the board password is not shared with everyone using the board.
+func (m *Manager) IsAdmin(r *http.Request) bool {
+	return m.hasValidToken(r)
+}
+
+// hasValidToken checks the Authorization header for the configured bearer token.
+func (m *Manager) hasValidToken(r *http.Request) bool {
+	if len(m.token) == 0 {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), m.token) == 1
+}
+
 // Login processes authentication requests.
 func (m *Manager) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
@@ -78,32 +214,57 @@ func (m *Manager) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := bcrypt.CompareHashAndPassword(m.hashedPassword, []byte(req.Password)); err != nil {
+		m.logger.Warn("Login failed", "remote_addr", r.RemoteAddr, "user", req.User)
+		m.record(audit.ActionLoginFailed, r, Identity{User: req.User})
 		http.Error(w, "Invalid password", http.StatusUnauthorized)
 		return
 	}
 
-	if err := m.setAuthStatus(w, r, true); err != nil {
+	identity := Identity{Session: newSessionID(), User: req.User}
+	if identity.User == "" {
+		identity.User = AnonymousUser
+	}
+
+	if err := m.setAuthStatus(w, r, true, identity); err != nil {
+		m.logger.Error("Failed to save session", "remote_addr", r.RemoteAddr, "error", err)
 		http.Error(w, "Session error", http.StatusInternalServerError)
 		return
 	}
 
+	m.logger.Info("Login succeeded", "remote_addr", r.RemoteAddr, "session", identity.Session, "user", identity.User)
+	m.record(audit.ActionLogin, r, identity)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("authenticated"))
 }
 
 // Logout processes logout requests.
 func (m *Manager) Logout(w http.ResponseWriter, r *http.Request) {
-	if err := m.setAuthStatus(w, r, false); err != nil {
+	identity := m.Identify(r)
+	if err := m.setAuthStatus(w, r, false, Identity{}); err != nil {
+		m.logger.Error("Failed to save session", "remote_addr", r.RemoteAddr, "error", err)
 		http.Error(w, "Session error", http.StatusInternalServerError)
 		return
 	}
 
+	m.logger.Info("Logged out", "remote_addr", r.RemoteAddr, "session", identity.Session, "user", identity.User)
+	m.record(audit.ActionLogout, r, identity)
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("logged out"))
 }
 
-// setAuthStatus sets the authentication status in the session.
-func (m *Manager) setAuthStatus(w http.ResponseWriter, r *http.Request, authenticated bool) error {
+// record writes an authentication event to the audit log.
+func (m *Manager) record(action string, r *http.Request, identity Identity) {
+	err := m.audit.Record(audit.Entry{
+		Action: action,
+		Actor:  identity.Actor(r),
+	})
+	if err != nil {
+		m.logger.Error("Failed to write audit log", "action", action, "error", err)
+	}
+}
+
+// setAuthStatus sets the authentication status and identity in the session.
+func (m *Manager) setAuthStatus(w http.ResponseWriter, r *http.Request, authenticated bool, identity Identity) error {
 	session, err := m.store.Get(r, SessionName)
 	if err != nil {
 		session = sessions.NewSession(m.store, SessionName)
@@ -111,8 +272,17 @@ func (m *Manager) setAuthStatus(w http.ResponseWriter, r *http.Request, authenti
 	}
 
 	session.Values[AuthKey] = authenticated
+	session.Values[SessionKey] = identity.Session
+	session.Values[UserKey] = identity.User
 
 	session.Options = m.store.Options
 
 	return session.Save(r, w)
 }
+
+// newSessionID returns a random identifier used to correlate a session in logs.
+func newSessionID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
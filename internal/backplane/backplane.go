@@ -0,0 +1,90 @@
+// Package backplane relays board updates between boardcast instances so that
+// clients connected to any instance see the same content.
+package backplane
+
+import (
+	"sync"
+)
+
+// Message types.
+const (
+	// TypeUpdate carries the content and revision of a board.
+	TypeUpdate = "update"
+	// TypeSync asks every other instance to publish its current state.
+	TypeSync = "sync"
+	// TypeConnected is delivered locally whenever the subscription is
+	// (re)established, since messages may have been missed meanwhile.
+	TypeConnected = "connected"
+)
+
+// Message is a notification exchanged between instances.
+type Message struct {
+	Type     string `json:"type"`
+	Origin   string `json:"origin"`
+	Board    string `json:"board,omitempty"`
+	Content  string `json:"content,omitempty"`
+	Revision uint64 `json:"revision,omitempty"`
+}
+
+// Backplane is a publish/subscribe channel shared by all instances.
+type Backplane interface {
+	// Publish sends m to every subscribed instance, including this one. It
+	// must not block on the network.
+	Publish(m Message) error
+	// Subscribe calls handle for each message until the returned cancel
+	// function is called. Handlers must not block; they may call Publish.
+	Subscribe(handle func(Message)) (cancel func(), err error)
+	// Close stops delivery to every subscriber and releases resources.
+	Close() error
+}
+
+// Local is an in-process Backplane connecting hubs in the same process.
+type Local struct {
+	mu       sync.RWMutex
+	handlers map[int]func(Message)
+	nextID   int
+}
+
+// NewLocal returns an in-process backplane.
+func NewLocal() *Local {
+	return &Local{handlers: make(map[int]func(Message))}
+}
+
+// Publish delivers m synchronously to every subscriber.
+func (l *Local) Publish(m Message) error {
+	l.mu.RLock()
+	handlers := make([]func(Message), 0, len(l.handlers))
+	for _, handle := range l.handlers {
+		handlers = append(handlers, handle)
+	}
+	l.mu.RUnlock()
+
+	for _, handle := range handlers {
+		handle(m)
+	}
+	return nil
+}
+
+// Subscribe registers handle and immediately reports the subscription as connected.
+func (l *Local) Subscribe(handle func(Message)) (func(), error) {
+	l.mu.Lock()
+	id := l.nextID
+	l.nextID++
+	l.handlers[id] = handle
+	l.mu.Unlock()
+
+	handle(Message{Type: TypeConnected})
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.handlers, id)
+	}, nil
+}
+
+// Close removes every subscriber.
+func (l *Local) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	clear(l.handlers)
+	return nil
+}
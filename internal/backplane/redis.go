@@ -0,0 +1,236 @@
+package backplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/resp"
+)
+
+// DefaultChannel is the pub/sub channel used by Redis backplanes.
+const DefaultChannel = "boardcast:updates"
+
+const (
+	publishQueueSize = 256
+	pingInterval     = 30 * time.Second
+	maxRetryDelay    = 30 * time.Second
+)
+
+// ErrQueueFull is returned when messages are published faster than they can be sent.
+var ErrQueueFull = errors.New("backplane publish queue full")
+
+// Redis is a Backplane using the pub/sub commands of a Redis-protocol server.
+type Redis struct {
+	client  *resp.Client
+	channel string
+	logger  *slog.Logger
+	queue   chan Message
+
+	mu        sync.Mutex
+	handlers  map[int]func(Message)
+	nextID    int
+	connected bool
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// NewRedis connects a backplane to the server at rawURL, a redis:// URL, and
+// starts publishing and listening in the background.
+func NewRedis(rawURL string, logger *slog.Logger) (*Redis, error) {
+	client, err := resp.NewClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Redis{
+		client:   client,
+		channel:  DefaultChannel,
+		logger:   logger,
+		queue:    make(chan Message, publishQueueSize),
+		handlers: make(map[int]func(Message)),
+		stop:     make(chan struct{}),
+	}
+
+	r.wg.Add(2)
+	go r.publishLoop()
+	go r.subscribeLoop()
+	return r, nil
+}
+
+// Publish queues m for sending.
+func (r *Redis) Publish(m Message) error {
+	select {
+	case r.queue <- m:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Subscribe registers handle, reporting the subscription as connected right
+// away if it already is.
+func (r *Redis) Subscribe(handle func(Message)) (func(), error) {
+	r.mu.Lock()
+	id := r.nextID
+	r.nextID++
+	r.handlers[id] = handle
+	connected := r.connected
+	r.mu.Unlock()
+
+	if connected {
+		handle(Message{Type: TypeConnected})
+	}
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.handlers, id)
+	}, nil
+}
+
+// Close stops the background goroutines and closes the connections.
+func (r *Redis) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	r.wg.Wait()
+	return r.client.Close()
+}
+
+// dispatch delivers m to every handler.
+func (r *Redis) dispatch(m Message) {
+	r.mu.Lock()
+	handlers := make([]func(Message), 0, len(r.handlers))
+	for _, handle := range r.handlers {
+		handlers = append(handlers, handle)
+	}
+	r.mu.Unlock()
+
+	for _, handle := range handlers {
+		handle(m)
+	}
+}
+
+// publishLoop sends queued messages.
+func (r *Redis) publishLoop() {
+	defer r.wg.Done()
+	for {
+		select {
+		case m := <-r.queue:
+			data, err := json.Marshal(m)
+			if err != nil {
+				continue
+			}
+			if _, err := r.client.Do("PUBLISH", r.channel, string(data)); err != nil {
+				r.logger.Warn("Failed to publish to backplane", "type", m.Type, "error", err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// subscribeLoop keeps a subscription open, reconnecting with backoff.
+func (r *Redis) subscribeLoop() {
+	defer r.wg.Done()
+
+	delay := time.Second
+	for {
+		start := time.Now()
+		err := r.listen()
+
+		r.mu.Lock()
+		r.connected = false
+		r.mu.Unlock()
+
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		if time.Since(start) > maxRetryDelay {
+			delay = time.Second
+		}
+		r.logger.Warn("Backplane subscription lost, reconnecting", "error", err, "delay", delay)
+
+		select {
+		case <-time.After(delay):
+			delay = min(delay*2, maxRetryDelay)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// listen subscribes on a dedicated connection and dispatches messages until
+// the connection fails or the backplane is closed.
+func (r *Redis) listen() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	conn, err := resp.Dial(ctx, r.client.Options())
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// Closing the connection unblocks Receive on shutdown; periodic pings
+		// detect connections that died silently. Send is safe to call while
+		// the loop below sends or receives.
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		defer conn.Close()
+		for {
+			select {
+			case <-ticker.C:
+				if conn.Send("PING") != nil {
+					return
+				}
+			case <-r.stop:
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Do("SUBSCRIBE", r.channel); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.connected = true
+	r.mu.Unlock()
+	r.logger.Info("Backplane subscribed", "channel", r.channel)
+	r.dispatch(Message{Type: TypeConnected})
+
+	for {
+		conn.SetDeadline(time.Now().Add(3 * pingInterval))
+		reply, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+
+		items, ok := reply.([]any)
+		if !ok || len(items) != 3 {
+			continue
+		}
+		if kind, _ := items[0].([]byte); string(kind) != "message" {
+			continue
+		}
+		data, _ := items[2].([]byte)
+
+		var m Message
+		if err := json.Unmarshal(data, &m); err != nil {
+			r.logger.Warn("Ignoring malformed backplane message", "error", err)
+			continue
+		}
+		r.dispatch(m)
+	}
+}
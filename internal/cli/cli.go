@@ -0,0 +1,175 @@
+// Package cli provides the command-line client subcommands for the boardcast application.
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/yosebyte/boardcast/client"
+)
+
+// command is a client subcommand operating on a remote server.
+type command struct {
+	usage string
+	run   runFunc
+	// flags, if set, registers the subcommand's own flags and returns the
+	// function run instead of run.
+	flags func(fs *flag.FlagSet) runFunc
+}
+
+// runFunc executes a subcommand with a connected client.
+type runFunc func(ctx context.Context, c *client.Client, args []string) error
+
+var commands = map[string]command{
+	"pull": {
+		usage: "pull [flags]",
+		run:   runPull,
+	},
+	"push": {
+		usage: "push [flags] < file",
+		run:   runPush,
+	},
+	"append": {
+		usage: "append [flags] [text...]",
+		run:   runAppend,
+	},
+	"watch": {
+		usage: "watch [flags]",
+		run:   runWatch,
+	},
+	"clip": {
+		usage: "clip [flags]",
+		flags: clipFlags,
+	},
+}
+
+// IsCommand reports whether name is a client subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// Run parses the flags for the named subcommand and executes it.
+func Run(name string, args []string) error {
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command: %s", name)
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: boardcast %s\n", cmd.usage)
+		fs.PrintDefaults()
+	}
+
+	var (
+		server   = fs.String("server", envOr("BOARDCAST_SERVER", "http://localhost:8200"), "Server URL")
+		token    = fs.String("token", os.Getenv("BOARDCAST_TOKEN"), "API token")
+		password = fs.String("password", os.Getenv("BOARDCAST_PASSWORD"), "Authentication password")
+		user     = fs.String("user", envOr("BOARDCAST_USER", os.Getenv("USER")), "Name recorded for your edits")
+	)
+	run := cmd.run
+	if cmd.flags != nil {
+		run = cmd.flags(fs)
+	}
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	opts := []client.Option{client.WithUser(*user)}
+	if *token != "" {
+		opts = append(opts, client.WithToken(*token))
+	}
+	if *password != "" {
+		opts = append(opts, client.WithPassword(*password))
+	}
+
+	c, err := client.Connect(ctx, *server, opts...)
+	if err != nil {
+		return err
+	}
+
+	return run(ctx, c, fs.Args())
+}
+
+// runPull writes the board content to stdout.
+func runPull(ctx context.Context, c *client.Client, _ []string) error {
+	content, err := c.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(os.Stdout, content.Text)
+	return err
+}
+
+// runPush replaces the board content with stdin.
+func runPush(ctx context.Context, c *client.Client, _ []string) error {
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.Set(ctx, string(content))
+	return err
+}
+
+// runAppend appends the arguments, or stdin when none are given, as a new line.
+func runAppend(ctx context.Context, c *client.Client, args []string) error {
+	text := strings.Join(args, " ")
+	if len(args) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+
+	_, err := c.Append(ctx, text)
+	return err
+}
+
+// runWatch streams board updates to stdout until interrupted.
+func runWatch(ctx context.Context, c *client.Client, _ []string) error {
+	updates, err := c.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	for update := range updates {
+		if update.Err != nil {
+			log.Printf("Connection lost, reconnecting: %v", update.Err)
+			continue
+		}
+
+		content := update.Content
+		if !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if _, err := io.WriteString(os.Stdout, content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// envOr returns the environment variable key or fallback when unset.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/client"
+	"github.com/yosebyte/boardcast/server"
+)
+
+// newTestServer starts a server accepting the token "tk".
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s, err := server.New(server.WithPassword("pw"), server.WithToken("tk"), server.WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(func() {
+		srv.Close()
+		s.Shutdown(context.Background())
+	})
+	return srv
+}
+
+// run runs the named subcommand against srv with stdin as its standard input
+// and returns what it wrote to standard output.
+func run(t *testing.T, srv *httptest.Server, stdin, name string, args ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "stdin"), filepath.Join(dir, "stdout")
+	if err := os.WriteFile(in, []byte(stdin), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	inFile, err := os.Open(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inFile.Close()
+	outFile, err := os.Create(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outFile.Close()
+
+	stdinBefore, stdoutBefore := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = inFile, outFile
+	err = Run(name, append([]string{"-server", srv.URL, "-token", "tk"}, args...))
+	os.Stdin, os.Stdout = stdinBefore, stdoutBefore
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRun(t *testing.T) {
+	srv := newTestServer(t)
+
+	run(t, srv, "first\n", "push")
+	if got := run(t, srv, "", "pull"); got != "first\n" {
+		t.Errorf("pull after push = %q, want %q", got, "first\n")
+	}
+
+	// Appended text always ends a line.
+	run(t, srv, "", "append", "second", "line")
+	run(t, srv, "third", "append")
+	if got, want := run(t, srv, "", "pull"), "first\nsecond line\nthird\n"; got != want {
+		t.Errorf("pull after append = %q, want %q", got, want)
+	}
+
+	if err := Run("pull", []string{"-server", srv.URL, "-token", "x"}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("pull with a wrong token = %v, want %v", err, client.ErrUnauthorized)
+	}
+	if err := Run("serve", nil); err == nil {
+		t.Error("Run accepted an unknown command")
+	}
+}
+
+func TestIsCommand(t *testing.T) {
+	for _, name := range []string{"pull", "push", "append", "watch", "clip"} {
+		if !IsCommand(name) {
+			t.Errorf("IsCommand(%q) = false", name)
+		}
+	}
+	if IsCommand("-port") {
+		t.Error("IsCommand accepted a server flag")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c, err := client.Connect(ctx, srv.URL, client.WithToken("tk"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Set(ctx, "first"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan error, 1)
+	go func() { done <- runWatch(ctx, c, nil) }()
+
+	// Each update is written as whole lines.
+	lines := bufio.NewScanner(r)
+	for _, want := range []string{"first", "second"} {
+		if !lines.Scan() || lines.Text() != want {
+			t.Fatalf("watch wrote %q, want %q", lines.Text(), want)
+		}
+		if want == "first" {
+			if _, err := c.Set(ctx, "second\n"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("watch = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop")
+	}
+	w.Close()
+}
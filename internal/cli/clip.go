@@ -0,0 +1,34 @@
+package cli
+
+import (
+	"context"
+	"flag"
+
+	"github.com/yosebyte/boardcast/client"
+	"github.com/yosebyte/boardcast/internal/clipboard"
+)
+
+// clipFlags registers the flags of the clip subcommand.
+func clipFlags(fs *flag.FlagSet) runFunc {
+	var (
+		provider = fs.String("clipboard", "system", "Clipboard provider: system, file or memory")
+		file     = fs.String("clipboard-file", "", "File used as the clipboard by the file provider")
+		interval = fs.Duration("interval", clipboard.DefaultInterval, "How often the clipboard is checked for changes")
+		maxSize  = fs.Int("max-size", clipboard.DefaultMaxSize, "Largest text in bytes synced in either direction")
+	)
+
+	// runClip keeps the clipboard and the board in sync until interrupted.
+	return func(ctx context.Context, c *client.Client, _ []string) error {
+		p, err := clipboard.New(*provider, *file)
+		if err != nil {
+			return err
+		}
+
+		return clipboard.NewSyncer(clipboard.Options{
+			Provider: p,
+			Board:    c,
+			Interval: *interval,
+			MaxSize:  *maxSize,
+		}).Run(ctx)
+	}
+}
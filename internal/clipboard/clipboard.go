@@ -0,0 +1,166 @@
+// Package clipboard reads and writes the local clipboard and keeps it in sync
+// with a board.
+package clipboard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ErrNoProvider is returned when no clipboard tool is available.
+var ErrNoProvider = errors.New("no clipboard tool found")
+
+// Provider gives access to a clipboard holding text.
+type Provider interface {
+	// Read returns the clipboard text.
+	Read(ctx context.Context) (string, error)
+	// Write replaces the clipboard text.
+	Write(ctx context.Context, text string) error
+}
+
+// Memory is a clipboard held in memory.
+type Memory struct {
+	mu   sync.Mutex
+	text string
+}
+
+// Read returns the text last written.
+func (m *Memory) Read(context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.text, nil
+}
+
+// Write stores text.
+func (m *Memory) Write(_ context.Context, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text = text
+	return nil
+}
+
+// File is a clipboard kept in a file, which reads as empty until created.
+type File struct {
+	Path string
+}
+
+// Read returns the file content.
+func (f File) Read(context.Context) (string, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// Write atomically replaces the file content.
+func (f File) Write(_ context.Context, text string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), "."+filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// Command is a clipboard accessed through external programs. Paste prints the
+// clipboard text and Copy reads the new text from its standard input.
+type Command struct {
+	Paste []string
+	Copy  []string
+}
+
+// Read runs the paste command.
+func (c Command) Read(ctx context.Context) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Paste[0], c.Paste[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", commandError(c.Paste[0], err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// Write runs the copy command. Its output is not captured, as some copy
+// commands leave a process behind serving the clipboard.
+func (c Command) Write(ctx context.Context, text string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.Copy[0], c.Copy[1:]...)
+	cmd.Stdin, cmd.Stderr = strings.NewReader(text), &stderr
+	if err := cmd.Run(); err != nil {
+		return commandError(c.Copy[0], err, stderr.String())
+	}
+	return nil
+}
+
+// commandError describes a failed clipboard command.
+func commandError(name string, err error, stderr string) error {
+	if stderr = strings.TrimSpace(stderr); stderr != "" {
+		return fmt.Errorf("%s: %w: %s", name, err, stderr)
+	}
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// System returns a provider for the clipboard of the desktop session, using
+// the first clipboard tool found for the platform.
+func System() (Provider, error) {
+	var candidates []Command
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = []Command{{Paste: []string{"pbpaste"}, Copy: []string{"pbcopy"}}}
+	case "windows":
+		candidates = []Command{{
+			Paste: []string{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"},
+			Copy:  []string{"powershell", "-NoProfile", "-Command", "Set-Clipboard -Value ([Console]::In.ReadToEnd())"},
+		}}
+	default:
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, Command{Paste: []string{"wl-paste", "--no-newline"}, Copy: []string{"wl-copy"}})
+		}
+		candidates = append(candidates,
+			Command{Paste: []string{"xclip", "-selection", "clipboard", "-o"}, Copy: []string{"xclip", "-selection", "clipboard", "-i"}},
+			Command{Paste: []string{"xsel", "--clipboard", "--output"}, Copy: []string{"xsel", "--clipboard", "--input"}},
+		)
+	}
+
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c.Paste[0]); err == nil {
+			return c, nil
+		}
+	}
+	return nil, ErrNoProvider
+}
+
+// New returns the provider with the given name: "system" for the desktop
+// clipboard, "file" for the file at path or "memory".
+func New(name, path string) (Provider, error) {
+	switch name {
+	case "system":
+		return System()
+	case "file":
+		if path == "" {
+			return nil, errors.New("the file clipboard requires a path")
+		}
+		return File{Path: path}, nil
+	case "memory":
+		return &Memory{}, nil
+	default:
+		return nil, fmt.Errorf("unknown clipboard provider: %s", name)
+	}
+}
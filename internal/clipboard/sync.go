@@ -0,0 +1,174 @@
+package clipboard
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/yosebyte/boardcast/client"
+)
+
+// Syncer defaults.
+const (
+	DefaultInterval = 500 * time.Millisecond
+	DefaultMaxSize  = 1 << 20
+)
+
+// Board is the board a clipboard is synced with. It is implemented by
+// *client.Client.
+type Board interface {
+	Set(ctx context.Context, text string) (uint64, error)
+	Subscribe(ctx context.Context) (<-chan client.Update, error)
+}
+
+// Options configures a Syncer. Zero values use the defaults.
+type Options struct {
+	Provider Provider
+	Board    Board
+	// Interval is how often the clipboard is checked for changes.
+	Interval time.Duration
+	// MaxSize is the largest text in bytes copied in either direction.
+	MaxSize int
+	Logger  *slog.Logger
+}
+
+// Syncer pushes clipboard changes to a board and copies board updates to
+// the clipboard.
+type Syncer struct {
+	provider Provider
+	board    Board
+	interval time.Duration
+	maxSize  int
+	logger   *slog.Logger
+
+	// clip and content are the text last seen on the clipboard and on the
+	// board. Only changes from these are copied, so that text the syncer
+	// copied to one side is not sent back from it.
+	clip    string
+	content string
+	// dirty marks a clipboard change not yet pushed to the board.
+	dirty bool
+	// failure is the last reported error, logged once until it changes.
+	failure string
+}
+
+// NewSyncer creates a syncer for opts.
+func NewSyncer(opts Options) *Syncer {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultInterval
+	}
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Syncer{
+		provider: opts.Provider,
+		board:    opts.Board,
+		interval: opts.Interval,
+		maxSize:  opts.MaxSize,
+		logger:   opts.Logger,
+	}
+}
+
+// Run syncs until ctx is cancelled. The clipboard takes the board content
+// on start; text already on the clipboard is only pushed once it changes.
+func (s *Syncer) Run(ctx context.Context) error {
+	updates, err := s.board.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	if text, err := s.provider.Read(ctx); err == nil {
+		s.clip = text
+	}
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if update.Err != nil {
+				s.logger.Warn("Connection lost, reconnecting", "error", update.Err)
+				continue
+			}
+			s.apply(ctx, update.Content)
+		case <-ticker.C:
+			s.poll(ctx)
+		}
+	}
+}
+
+// poll pushes the clipboard to the board if it changed.
+func (s *Syncer) poll(ctx context.Context) {
+	text, err := s.provider.Read(ctx)
+	if err != nil {
+		s.fail("Failed to read clipboard", err)
+		return
+	}
+
+	if text != s.clip {
+		s.clip = text
+		s.dirty = text != s.content && text != ""
+		if s.dirty && len(text) > s.maxSize {
+			s.logger.Warn("Clipboard exceeds size cap, not pushed", "size", len(text), "limit", s.maxSize)
+			s.dirty = false
+		}
+	}
+	if !s.dirty {
+		return
+	}
+
+	if _, err := s.board.Set(ctx, text); err != nil {
+		s.fail("Failed to push clipboard", err)
+		return
+	}
+	s.dirty = false
+	s.content = text
+	s.failure = ""
+	s.logger.Info("Pushed clipboard to board", "size", len(text))
+}
+
+// apply copies board content to the clipboard if it changed.
+func (s *Syncer) apply(ctx context.Context, text string) {
+	if text == s.content {
+		return
+	}
+	s.content = text
+	if text == s.clip {
+		s.dirty = false
+		return
+	}
+	if len(text) > s.maxSize {
+		s.logger.Warn("Board exceeds size cap, not copied to clipboard", "size", len(text), "limit", s.maxSize)
+		return
+	}
+
+	if err := s.provider.Write(ctx, text); err != nil {
+		s.fail("Failed to write clipboard", err)
+		return
+	}
+	// Clipboard tools may alter line endings, so remember the text as the
+	// clipboard returns it rather than as written.
+	s.clip = text
+	if read, err := s.provider.Read(ctx); err == nil {
+		s.clip = read
+	}
+	s.dirty = false
+	s.failure = ""
+	s.logger.Info("Copied board to clipboard", "size", len(text))
+}
+
+// fail logs err unless it repeats the last failure.
+func (s *Syncer) fail(msg string, err error) {
+	if failure := msg + ": " + err.Error(); failure != s.failure {
+		s.failure = failure
+		s.logger.Warn(msg, "error", err)
+	}
+}
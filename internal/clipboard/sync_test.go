@@ -0,0 +1,132 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/client"
+)
+
+// testBoard records the content pushed to it, failing the first fail pushes.
+type testBoard struct {
+	sets    []string
+	fail    int
+	updates chan client.Update
+}
+
+func (b *testBoard) Set(_ context.Context, text string) (uint64, error) {
+	if b.fail > 0 {
+		b.fail--
+		return 0, errors.New("unavailable")
+	}
+	b.sets = append(b.sets, text)
+	return uint64(len(b.sets)), nil
+}
+
+func (b *testBoard) Subscribe(context.Context) (<-chan client.Update, error) {
+	return b.updates, nil
+}
+
+// crlfClipboard is a clipboard that converts line endings on write, as some
+// clipboard tools do.
+type crlfClipboard struct {
+	Memory
+	writes int
+}
+
+func (c *crlfClipboard) Write(ctx context.Context, text string) error {
+	c.writes++
+	return c.Memory.Write(ctx, strings.ReplaceAll(text, "\n", "\r\n"))
+}
+
+func TestSyncer(t *testing.T) {
+	// Each step copies text to the clipboard ('c') or receives it from the
+	// board ('b'), then polls the clipboard.
+	type step struct {
+		kind byte
+		text string
+	}
+	tests := []struct {
+		name       string
+		steps      []step
+		maxSize    int
+		fail       int
+		wantSets   []string
+		wantClip   string
+		wantWrites int
+	}{
+		{"clipboard pushed", []step{{'c', "a"}}, 0, 0, []string{"a"}, "a", 0},
+		{"board copied", []step{{'b', "a"}}, 0, 0, nil, "a", 1},
+		{"board copy not pushed back", []step{{'b', "a\nb"}, {'b', "a\nb"}}, 0, 0, nil, "a\r\nb", 1},
+		{"push echo not copied back", []step{{'c', "a"}, {'b', "a"}}, 0, 0, []string{"a"}, "a", 0},
+		{"alternating changes", []step{{'c', "a"}, {'b', "b"}, {'c', "c"}}, 0, 0, []string{"a", "c"}, "c", 1},
+		{"clipboard changed back", []step{{'b', "a"}, {'c', "b"}, {'c', "a"}}, 0, 0, []string{"b", "a"}, "a", 1},
+		{"same text not pushed again", []step{{'c', "a"}, {'c', "a"}}, 0, 0, []string{"a"}, "a", 0},
+		{"empty clipboard not pushed", []step{{'b', "a"}, {'c', ""}}, 0, 0, nil, "", 1},
+		{"oversized clipboard not pushed", []step{{'c', "abcd"}}, 3, 0, nil, "abcd", 0},
+		{"oversized board not copied", []step{{'b', "abcd"}}, 3, 0, nil, "", 0},
+		{"failed push retried", []step{{'c', "a"}}, 0, 1, []string{"a"}, "a", 0},
+		{"failed push replaced by board", []step{{'c', "a"}, {'b', "b"}}, 0, 2, nil, "b", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			clip := &crlfClipboard{}
+			board := &testBoard{fail: tt.fail}
+			s := NewSyncer(Options{Provider: clip, Board: board, MaxSize: tt.maxSize, Logger: slog.New(slog.DiscardHandler)})
+
+			for _, st := range tt.steps {
+				if st.kind == 'c' {
+					clip.Memory.Write(ctx, st.text)
+				} else {
+					s.apply(ctx, st.text)
+				}
+				s.poll(ctx)
+			}
+			// A failed push is retried on the next poll.
+			s.poll(ctx)
+
+			if !reflect.DeepEqual(board.sets, tt.wantSets) {
+				t.Errorf("pushed %q, want %q", board.sets, tt.wantSets)
+			}
+			if text, _ := clip.Read(ctx); text != tt.wantClip {
+				t.Errorf("clipboard = %q, want %q", text, tt.wantClip)
+			}
+			if clip.writes != tt.wantWrites {
+				t.Errorf("clipboard written %d times, want %d", clip.writes, tt.wantWrites)
+			}
+		})
+	}
+}
+
+// TestSyncerRun checks that the clipboard takes the board content on start
+// without its earlier text being pushed.
+func TestSyncerRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clip := &Memory{text: "before"}
+	board := &testBoard{updates: make(chan client.Update)}
+	s := NewSyncer(Options{Provider: clip, Board: board, Interval: time.Millisecond, Logger: slog.New(slog.DiscardHandler)})
+
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	board.updates <- client.Update{Content: "board"}
+	// A second update is only received once the first was applied.
+	board.updates <- client.Update{Err: errors.New("reconnecting")}
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(board.sets) != 0 {
+		t.Errorf("pushed %q, want nothing", board.sets)
+	}
+	if text, _ := clip.Read(ctx); text != "board" {
+		t.Errorf("clipboard = %q, want %q", text, "board")
+	}
+}
@@ -7,23 +7,86 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all configuration values for the application.
 type Config struct {
-	Port     string
-	Password string
-	Version  string
+	Port          string
+	Password      string
+	Token         string
+	BasePath      string
+	ShutdownDelay time.Duration
+	LogLevel      string
+	LogFormat     string
+	AuditLog      string
+	Webhooks      string
+	InboundHooks  string
+	DataDir       string
+	MaxAttachment int64
+	MaxMessage    int64
+	MaxBoard      int64
+	RateLimit     float64
+	UserRateLimit float64
+	RateBurst     int
+	BroadcastTick time.Duration
+	Compression   bool
+	CompressLevel int
+	CompressMin   int
+	RedisURL      string
+	Store         string
+	SessionKey    []byte
+	ReplicateFrom string
+	ReplicaToken  string
+	BoardTTL      time.Duration
+	BurnAfterRead bool
+	E2E           bool
+	// KeyFile and StorageKey give the keys encrypting data at rest, from a
+	// file or the BOARDCAST_STORAGE_KEY environment variable.
+	KeyFile    string
+	StorageKey string
+	Version    string
 }
 
 // Load parses command line flags and returns a validated Config instance.
 func Load(version string) *Config {
 	var (
-		port        = flag.String("port", "8200", "Server port number")
-		password    = flag.String("password", "", "Authentication password")
-		versionFlag = flag.Bool("version", false, "Show version and exit")
+		port          = flag.String("port", "8200", "Server port number")
+		password      = flag.String("password", "", "Authentication password")
+		token         = flag.String("token", "", "API token accepted as a bearer credential and required for the audit log")
+		basePath      = flag.String("base-path", "", "URL path prefix when served behind a reverse proxy, e.g. /board")
+		shutdownDelay = flag.Duration("shutdown-delay", 0, "Time to keep serving with failing readiness before shutting down")
+		logLevel      = flag.String("log-level", "info", "Log level: debug, info, warn or error")
+		logFormat     = flag.String("log-format", "text", "Log format: text or json")
+		auditLog      = flag.String("audit-log", "", "Path of the JSON lines audit log (disabled when empty)")
+		webhooks      = flag.String("webhooks", "", "Path of a JSON file configuring outgoing webhooks")
+		inboundHooks  = flag.String("inbound-hooks", "", "Path of a JSON file configuring inbound webhooks")
+		dataDir       = flag.String("data-dir", ".", "Directory for snapshots and attachments")
+		maxAttachment = flag.Int64("max-attachment-size", 10<<20, "Maximum attachment upload size in bytes")
+		maxMessage    = flag.Int64("max-message-size", 1<<20, "Maximum WebSocket message and HTTP write body size in bytes")
+		maxBoard      = flag.Int64("max-board-size", 1<<20, "Maximum board content size in bytes")
+		rateLimit     = flag.Float64("rate-limit", 20, "WebSocket updates per second allowed per connection (negative disables)")
+		userRateLimit = flag.Float64("user-rate-limit", 50, "WebSocket updates per second allowed per user (negative disables)")
+		rateBurst     = flag.Int("rate-burst", 40, "WebSocket updates allowed in a burst above the rate limits")
+		broadcastTick = flag.Duration("broadcast-tick", 50*time.Millisecond, "Interval for coalescing updates into one broadcast (negative disables)")
+		compression   = flag.Bool("compression", true, "Negotiate permessage-deflate compression on WebSocket connections")
+		compressLevel = flag.Int("compression-level", 1, "Deflate compression level from -2 (Huffman only) to 9 (best), excluding 0")
+		compressMin   = flag.Int("compression-threshold", 1024, "Minimum WebSocket message size in bytes to compress")
+		redisURL      = flag.String("redis-url", "", "Redis-protocol server relaying edits between instances, e.g. redis://localhost:6379/0")
+		store         = flag.String("store", "file", "Snapshot storage: file (in the data directory) or redis (at -redis-url)")
+		sessionKey    = flag.String("session-key", "", "Hex-encoded key of at least 32 bytes signing session cookies, shared by all instances")
+		replicateFrom = flag.String("replicate-from", "", "Base URL of a boardcast server whose board to replicate, e.g. https://office.example.com")
+		replicaToken  = flag.String("replicate-token", "", "API token of the server given by -replicate-from")
+		boardTTL      = flag.Duration("board-ttl", 0, "Wipe board content this long after the last edit (0 keeps it)")
+		burnAfterRead = flag.Bool("burn-after-reading", false, "Wipe board content once viewed by someone other than its author")
+		e2e           = flag.Bool("e2e", false, "Accept only board content encrypted in the browser with a passphrase kept in the URL fragment")
+		keyFile       = flag.String("storage-key-file", "", "File of id:hexkey lines whose first key encrypts snapshots and history at rest (or set BOARDCAST_STORAGE_KEY)")
+		versionFlag   = flag.Bool("version", false, "Show version and exit")
 	)
 	flag.Parse()
 
@@ -37,9 +100,45 @@ func Load(version string) *Config {
 	}
 
 	cfg := &Config{
-		Port:     *port,
-		Password: *password,
-		Version:  version,
+		Port:          *port,
+		Password:      *password,
+		Token:         *token,
+		BasePath:      *basePath,
+		ShutdownDelay: *shutdownDelay,
+		LogLevel:      *logLevel,
+		LogFormat:     *logFormat,
+		AuditLog:      *auditLog,
+		Webhooks:      *webhooks,
+		InboundHooks:  *inboundHooks,
+		DataDir:       *dataDir,
+		MaxAttachment: *maxAttachment,
+		MaxMessage:    *maxMessage,
+		MaxBoard:      *maxBoard,
+		RateLimit:     *rateLimit,
+		UserRateLimit: *userRateLimit,
+		RateBurst:     *rateBurst,
+		BroadcastTick: *broadcastTick,
+		Compression:   *compression,
+		CompressLevel: *compressLevel,
+		CompressMin:   *compressMin,
+		RedisURL:      *redisURL,
+		Store:         *store,
+		ReplicateFrom: *replicateFrom,
+		ReplicaToken:  *replicaToken,
+		BoardTTL:      *boardTTL,
+		BurnAfterRead: *burnAfterRead,
+		E2E:           *e2e,
+		KeyFile:       *keyFile,
+		StorageKey:    os.Getenv("BOARDCAST_STORAGE_KEY"),
+		Version:       version,
+	}
+
+	if *sessionKey != "" {
+		key, err := hex.DecodeString(*sessionKey)
+		if err != nil || len(key) < 32 {
+			log.Fatal("invalid session key: must be at least 32 hex-encoded bytes")
+		}
+		cfg.SessionKey = key
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -55,6 +154,86 @@ func (c *Config) validate() error {
 		return fmt.Errorf("invalid port number: %s (must be 1-65535)", c.Port)
 	}
 
+	if strings.ContainsAny(c.BasePath, "{}?# ") {
+		return fmt.Errorf("invalid base path: %s", c.BasePath)
+	}
+
+	if c.ShutdownDelay < 0 {
+		return fmt.Errorf("invalid shutdown delay: %s (must not be negative)", c.ShutdownDelay)
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level: %s (must be debug, info, warn or error)", c.LogLevel)
+	}
+
+	if c.LogFormat != "text" && c.LogFormat != "json" {
+		return fmt.Errorf("invalid log format: %s (must be text or json)", c.LogFormat)
+	}
+
+	if c.DataDir == "" {
+		return fmt.Errorf("invalid data directory: must not be empty")
+	}
+
+	if c.MaxAttachment <= 0 {
+		return fmt.Errorf("invalid max attachment size: %d (must be positive)", c.MaxAttachment)
+	}
+
+	if c.MaxMessage <= 0 {
+		return fmt.Errorf("invalid max message size: %d (must be positive)", c.MaxMessage)
+	}
+
+	if c.MaxBoard <= 0 {
+		return fmt.Errorf("invalid max board size: %d (must be positive)", c.MaxBoard)
+	}
+
+	if c.RateLimit == 0 || c.UserRateLimit == 0 {
+		return fmt.Errorf("invalid rate limit: must be positive, or negative to disable")
+	}
+
+	if c.RateBurst < 1 {
+		return fmt.Errorf("invalid rate burst: %d (must be at least 1)", c.RateBurst)
+	}
+
+	if c.BroadcastTick == 0 {
+		return fmt.Errorf("invalid broadcast tick: must be positive, or negative to disable")
+	}
+
+	if c.CompressLevel < -2 || c.CompressLevel > 9 || c.CompressLevel == 0 {
+		return fmt.Errorf("invalid compression level: %d (must be -2 to 9, excluding 0)", c.CompressLevel)
+	}
+
+	if c.CompressMin < 1 {
+		return fmt.Errorf("invalid compression threshold: %d (must be positive)", c.CompressMin)
+	}
+
+	if c.BoardTTL < 0 {
+		return fmt.Errorf("invalid board TTL: %s (must not be negative)", c.BoardTTL)
+	}
+
+	if c.KeyFile != "" && c.StorageKey != "" {
+		return fmt.Errorf("invalid storage key: set -storage-key-file or BOARDCAST_STORAGE_KEY, not both")
+	}
+
+	switch c.Store {
+	case "file":
+	case "redis":
+		if c.RedisURL == "" {
+			return fmt.Errorf("invalid store: redis requires -redis-url")
+		}
+	default:
+		return fmt.Errorf("invalid store: %s (must be file or redis)", c.Store)
+	}
+
+	if c.ReplicateFrom != "" {
+		if u, err := url.Parse(c.ReplicateFrom); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid replication source: %s (must be an http or https URL)", c.ReplicateFrom)
+		}
+		if c.ReplicaToken == "" {
+			return fmt.Errorf("invalid replication source: -replicate-from requires -replicate-token")
+		}
+	}
+
 	return nil
 }
 
@@ -63,6 +242,18 @@ func (c *Config) GetAddr() string {
 	return ":" + c.Port
 }
 
+// NewLogger returns a structured logger writing to stderr with the configured level and format.
+func (c *Config) NewLogger() *slog.Logger {
+	var level slog.Level
+	level.UnmarshalText([]byte(c.LogLevel))
+
+	opts := &slog.HandlerOptions{Level: level}
+	if c.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
+}
+
 // generatePassword creates a random password for the user.
 func generatePassword() string {
 	bytes := make([]byte, 4)
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns a configuration that passes validation.
+func validConfig() *Config {
+	return &Config{
+		Port:          "8200",
+		LogLevel:      "info",
+		LogFormat:     "text",
+		DataDir:       ".",
+		MaxAttachment: 1,
+		MaxMessage:    1,
+		MaxBoard:      1,
+		RateLimit:     1,
+		UserRateLimit: 1,
+		RateBurst:     1,
+		BroadcastTick: time.Millisecond,
+		CompressLevel: 1,
+		CompressMin:   1,
+		Store:         "file",
+	}
+}
+
+func TestValidateLogging(t *testing.T) {
+	tests := []struct {
+		level, format string
+		err           string
+	}{
+		{"debug", "json", ""},
+		{"WARN", "text", ""},
+		{"error", "text", ""},
+		{"verbose", "text", "invalid log level"},
+		{"info", "logfmt", "invalid log format"},
+	}
+	for _, tt := range tests {
+		cfg := validConfig()
+		cfg.LogLevel, cfg.LogFormat = tt.level, tt.format
+		err := cfg.validate()
+		if (tt.err == "") != (err == nil) || err != nil && !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("validate(%s, %s) = %v, want %q", tt.level, tt.format, err, tt.err)
+		}
+	}
+}
+
+// captureStderr redirects os.Stderr to a file while fn runs and returns what was written.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stderr")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stderr := os.Stderr
+	os.Stderr = f
+	fn()
+	os.Stderr = stderr
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNewLogger(t *testing.T) {
+	cfg := validConfig()
+	cfg.LogLevel, cfg.LogFormat = "warn", "json"
+	out := captureStderr(t, func() {
+		logger := cfg.NewLogger()
+		logger.Info("hidden")
+		logger.Warn("shown", "board", "default")
+	})
+
+	var record map[string]any
+	if err := json.Unmarshal([]byte(out), &record); err != nil {
+		t.Fatalf("output %q is not one JSON record: %v", out, err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "shown" || record["board"] != "default" {
+		t.Errorf("record = %v, want the warning with its board", record)
+	}
+
+	cfg.LogLevel, cfg.LogFormat = "debug", "text"
+	out = captureStderr(t, func() { cfg.NewLogger().Debug("detail", "conn_id", 7) })
+	if !strings.Contains(out, "level=DEBUG") || !strings.Contains(out, "msg=detail conn_id=7") {
+		t.Errorf("text output = %q, want the debug record", out)
+	}
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// Patch operations supported by PATCH /api/v1/boards/{name}/content.
+const (
+	PatchAppend       = "append"
+	PatchPrepend      = "prepend"
+	PatchReplaceRange = "replace-range"
+)
+
+// PatchRequest represents the JSON structure for content patch requests.
+// Start and End are character offsets used by replace-range.
+type PatchRequest struct {
+	Op    string `json:"op"`
+	Text  string `json:"text"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// apply returns content with the patch applied.
+func (p PatchRequest) apply(content string) (string, error) {
+	switch p.Op {
+	case PatchAppend:
+		return content + p.Text, nil
+	case PatchPrepend:
+		return p.Text + content, nil
+	case PatchReplaceRange:
+		runes := []rune(content)
+		if p.Start < 0 || p.End < p.Start || p.End > len(runes) {
+			return "", errInvalidRange
+		}
+		return string(runes[:p.Start]) + p.Text + string(runes[p.End:]), nil
+	default:
+		return "", errUnknownOp
+	}
+}
+
+var (
+	errInvalidRange = errors.New("invalid range")
+	errUnknownOp    = errors.New("unknown patch operation")
+)
+
+// HandleBoardContent serves GET, PUT and PATCH requests for a board's content.
+func (h *Handlers) HandleBoardContent(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.PathValue("name") != websocket.DefaultBoard {
+		http.Error(w, "Board not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.getBoardContent(w, r)
+	case http.MethodPut:
+		h.putBoardContent(w, r)
+	case http.MethodPatch:
+		h.patchBoardContent(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, PATCH")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// getBoardContent writes the current content with an ETag made from its
+// revision and digest. Only a GET sending the content counts as viewing it.
+func (h *Handlers) getBoardContent(w http.ResponseWriter, r *http.Request) {
+	content, revision := h.wsHub.GetState()
+	etag := websocket.ETag(content, revision)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		content, revision = h.wsHub.View(h.actor(r))
+		etag = websocket.ETag(content, revision)
+	}
+	w.Header().Set("ETag", etag)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write([]byte(content))
+}
+
+// putBoardContent replaces the content, requiring an If-Match precondition.
+func (h *Handlers) putBoardContent(w http.ResponseWriter, r *http.Request) {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		http.Error(w, "If-Match header required", http.StatusPreconditionRequired)
+		return
+	}
+
+	body, err := io.ReadAll(h.limitBody(w, r))
+	if err != nil {
+		h.bodyError(w, err)
+		return
+	}
+
+	h.writeBoardContent(w, h.actor(r), match, func(string) (string, error) {
+		return string(body), nil
+	})
+}
+
+// patchBoardContent applies an append, prepend or replace-range operation.
+// If-Match is optional; without it the patch applies to the latest revision.
+func (h *Handlers) patchBoardContent(w http.ResponseWriter, r *http.Request) {
+	if h.wsHub.Limits().Encrypted {
+		// Text cannot be spliced into content encrypted as a whole.
+		http.Error(w, "Board is end-to-end encrypted", http.StatusConflict)
+		return
+	}
+
+	var req PatchRequest
+	if err := json.NewDecoder(h.limitBody(w, r)).Decode(&req); err != nil {
+		h.bodyError(w, err)
+		return
+	}
+
+	h.writeBoardContent(w, h.actor(r), r.Header.Get("If-Match"), req.apply)
+}
+
+// writeBoardContent performs a conditional update and reports the new ETag.
+func (h *Handlers) writeBoardContent(w http.ResponseWriter, actor audit.Actor, match string, edit func(string) (string, error)) {
+	var precondition func(string, uint64) bool
+	if match != "" && match != "*" {
+		precondition = func(content string, revision uint64) bool {
+			return etagMatches(match, websocket.ETag(content, revision))
+		}
+	}
+
+	// The new ETag is made from the content the edit produced.
+	var content string
+	revision, err := h.wsHub.Update(actor, precondition, func(current string) (string, error) {
+		var err error
+		content, err = edit(current)
+		return content, err
+	})
+	switch {
+	case errors.Is(err, websocket.ErrRevisionMismatch):
+		w.Header().Set("ETag", websocket.ETag(h.wsHub.GetState()))
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, websocket.ErrShuttingDown):
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	case errors.Is(err, errInvalidRange), errors.Is(err, errUnknownOp):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case errors.Is(err, websocket.ErrBoardTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, websocket.ErrInvalidUTF8):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, websocket.ErrNotEncrypted):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		http.Error(w, "Failed to update content", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("ETag", websocket.ETag(content, revision))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// limitBody caps the request body at the hub's message size limit.
+func (h *Handlers) limitBody(w http.ResponseWriter, r *http.Request) io.Reader {
+	return http.MaxBytesReader(w, r.Body, h.wsHub.Limits().MaxMessageSize)
+}
+
+// bodyError reports a failure to read or decode a limited request body.
+func (h *Handlers) bodyError(w http.ResponseWriter, err error) {
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(w, "Invalid request format", http.StatusBadRequest)
+}
+
+// etagMatches reports whether etag appears in a comma-separated If-Match or
+// If-None-Match list.
+func etagMatches(list, etag string) bool {
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
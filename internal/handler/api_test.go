@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// allowAll is an auth.Provider accepting every request.
+type allowAll struct{}
+
+func (allowAll) IsAuthenticated(*http.Request) bool        { return true }
+func (allowAll) Login(http.ResponseWriter, *http.Request)  {}
+func (allowAll) Logout(http.ResponseWriter, *http.Request) {}
+
+// newTestHandlers returns handlers for a fresh hub with limits holding
+// content. opts only needs the optional dependencies; Auth and Audit default
+// to allowAll and audit.Discard.
+func newTestHandlers(t *testing.T, opts Options, limits websocket.Limits, content string) (*websocket.Hub, *Handlers) {
+	t.Helper()
+	hub := websocket.NewHub(websocket.Options{
+		Store:   storage.NewMemoryStore(),
+		Logger:  slog.New(slog.DiscardHandler),
+		Metrics: metrics.New(),
+		Audit:   audit.Discard,
+		Limits:  limits,
+	})
+	if content != "" {
+		if _, err := hub.Update(audit.Actor{}, nil, func(string) (string, error) { return content, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if opts.Auth == nil {
+		opts.Auth = allowAll{}
+	}
+	if opts.Audit == nil {
+		opts.Audit = audit.Discard
+	}
+	opts.Hub, opts.Metrics = hub, metrics.New()
+	opts.Logger = slog.New(slog.DiscardHandler)
+	return hub, New(opts)
+}
+
+// newContentServer serves the content API of a fresh hub holding content.
+func newContentServer(t *testing.T, content string) (*websocket.Hub, http.Handler) {
+	t.Helper()
+	hub, h := newTestHandlers(t, Options{}, websocket.Limits{}, content)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/boards/{name}/content", h.HandleBoardContent)
+	return hub, mux
+}
+
+func TestPatchApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   PatchRequest
+		content string
+		want    string
+		err     error
+	}{
+		{"append", PatchRequest{Op: PatchAppend, Text: "!"}, "hi", "hi!", nil},
+		{"prepend", PatchRequest{Op: PatchPrepend, Text: "> "}, "hi", "> hi", nil},
+		{"replace range", PatchRequest{Op: PatchReplaceRange, Text: "J", Start: 0, End: 1}, "hello", "Jello", nil},
+		{"insert", PatchRequest{Op: PatchReplaceRange, Text: ",", Start: 5, End: 5}, "hello world", "hello, world", nil},
+		{"delete to end", PatchRequest{Op: PatchReplaceRange, Start: 2, End: 5}, "hello", "he", nil},
+		{"rune offsets", PatchRequest{Op: PatchReplaceRange, Text: "e", Start: 1, End: 2}, "héllo", "hello", nil},
+		{"offsets after multibyte", PatchRequest{Op: PatchReplaceRange, Text: "🙂", Start: 3, End: 4}, "日本語!", "日本語🙂", nil},
+		{"emoji is one rune", PatchRequest{Op: PatchReplaceRange, Text: "x", Start: 0, End: 1}, "😀a", "xa", nil},
+		{"empty content", PatchRequest{Op: PatchReplaceRange, Text: "a"}, "", "a", nil},
+		{"end past content", PatchRequest{Op: PatchReplaceRange, Start: 0, End: 6}, "héllo", "", errInvalidRange},
+		{"byte length is not rune length", PatchRequest{Op: PatchReplaceRange, Start: 5, End: 6}, "héllo", "", errInvalidRange},
+		{"negative start", PatchRequest{Op: PatchReplaceRange, Start: -1, End: 1}, "hello", "", errInvalidRange},
+		{"end before start", PatchRequest{Op: PatchReplaceRange, Start: 3, End: 2}, "hello", "", errInvalidRange},
+		{"unknown op", PatchRequest{Op: "upsert"}, "hello", "", errUnknownOp},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.patch.apply(tt.content)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("apply(%q) error = %v, want %v", tt.content, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoardContentIfMatch(t *testing.T) {
+	const path = "/api/v1/boards/default/content"
+	current := websocket.ETag("hello", 1)
+
+	tests := []struct {
+		name       string
+		method     string
+		header     map[string]string
+		body       string
+		wantStatus int
+		wantETag   string
+		wantBoard  string
+	}{
+		{"get", http.MethodGet, nil, "", http.StatusOK, current, "hello"},
+		{"not modified", http.MethodGet, map[string]string{"If-None-Match": `"0-0000000000000000", ` + current}, "", http.StatusNotModified, current, "hello"},
+		{"put without precondition", http.MethodPut, nil, "new", http.StatusPreconditionRequired, "", "hello"},
+		{"put any", http.MethodPut, map[string]string{"If-Match": "*"}, "new", http.StatusNoContent, websocket.ETag("new", 2), "new"},
+		{"put current", http.MethodPut, map[string]string{"If-Match": current}, "new", http.StatusNoContent, websocket.ETag("new", 2), "new"},
+		{"put current in list", http.MethodPut, map[string]string{"If-Match": `"7-0000000000000000", ` + current}, "new", http.StatusNoContent, websocket.ETag("new", 2), "new"},
+		{"put stale revision", http.MethodPut, map[string]string{"If-Match": websocket.ETag("", 0)}, "new", http.StatusPreconditionFailed, current, "hello"},
+		// A tag from before a restart names the same revision but other content.
+		{"put same revision other content", http.MethodPut, map[string]string{"If-Match": websocket.ETag("before restart", 1)}, "new", http.StatusPreconditionFailed, current, "hello"},
+		{"put bare revision", http.MethodPut, map[string]string{"If-Match": `"1"`}, "new", http.StatusPreconditionFailed, current, "hello"},
+		{"put malformed", http.MethodPut, map[string]string{"If-Match": "1"}, "new", http.StatusPreconditionFailed, current, "hello"},
+		{"patch unconditional", http.MethodPatch, nil, `{"op":"append","text":"!"}`, http.StatusNoContent, websocket.ETag("hello!", 2), "hello!"},
+		{"patch current", http.MethodPatch, map[string]string{"If-Match": current}, `{"op":"replace-range","text":"J","start":0,"end":1}`, http.StatusNoContent, websocket.ETag("Jello", 2), "Jello"},
+		{"patch stale", http.MethodPatch, map[string]string{"If-Match": websocket.ETag("hello", 0)}, `{"op":"append","text":"!"}`, http.StatusPreconditionFailed, current, "hello"},
+		{"patch invalid range", http.MethodPatch, nil, `{"op":"replace-range","start":0,"end":9}`, http.StatusUnprocessableEntity, "", "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, srv := newContentServer(t, "hello")
+			req := httptest.NewRequest(tt.method, path, strings.NewReader(tt.body))
+			for key, value := range tt.header {
+				req.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			srv.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := rec.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("ETag = %s, want %s", got, tt.wantETag)
+			}
+			if got := hub.GetContent(); got != tt.wantBoard {
+				t.Errorf("board = %q, want %q", got, tt.wantBoard)
+			}
+		})
+	}
+}
+
+// TestBoardContentBurn checks that only a GET sending the content of a
+// burn-after-reading board counts as reading it.
+func TestBoardContentBurn(t *testing.T) {
+	hub := websocket.NewHub(websocket.Options{
+		Store:     storage.NewMemoryStore(),
+		Logger:    slog.New(slog.DiscardHandler),
+		Metrics:   metrics.New(),
+		Audit:     audit.Discard,
+		Expiry:    storage.Expiry{BurnAfterReading: true},
+		BurnDelay: time.Millisecond,
+	})
+	if _, err := hub.Update(audit.Actor{User: "alice"}, nil, func(string) (string, error) { return "secret", nil }); err != nil {
+		t.Fatal(err)
+	}
+	h := New(Options{Auth: allowAll{}, Hub: hub, Metrics: metrics.New(), Audit: audit.Discard, Logger: slog.New(slog.DiscardHandler)})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/boards/{name}/content", h.HandleBoardContent)
+
+	get := func(method, match string) int {
+		req := httptest.NewRequest(method, "/api/v1/boards/default/content", nil)
+		if match != "" {
+			req.Header.Set("If-None-Match", match)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := get(http.MethodGet, websocket.ETag(hub.GetState())); code != http.StatusNotModified {
+		t.Fatalf("conditional GET = %d, want %d", code, http.StatusNotModified)
+	}
+	if code := get(http.MethodHead, ""); code != http.StatusOK {
+		t.Fatalf("HEAD = %d, want %d", code, http.StatusOK)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := hub.GetContent(); got != "secret" {
+		t.Fatalf("board = %q after requests that sent no content", got)
+	}
+
+	if code := get(http.MethodGet, ""); code != http.StatusOK {
+		t.Fatalf("GET = %d, want %d", code, http.StatusOK)
+	}
+	for deadline := time.Now().Add(5 * time.Second); hub.GetContent() != ""; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("board not burned after a GET")
+		}
+	}
+}
+
+// TestBoardContentLimits checks the status codes of writes rejected by the
+// board limits.
+func TestBoardContentLimits(t *testing.T) {
+	sealed := websocket.SealedPrefix + strings.Repeat("A", 40)
+	plain := websocket.Limits{MaxMessageSize: 32, MaxBoardSize: 8}
+	encrypted := websocket.Limits{Encrypted: true}
+	tests := []struct {
+		name   string
+		limits websocket.Limits
+		method string
+		body   string
+		want   int
+	}{
+		{"within limits", plain, http.MethodPut, "12345678", http.StatusNoContent},
+		{"body too large", plain, http.MethodPut, strings.Repeat("a", 33), http.StatusRequestEntityTooLarge},
+		{"board too large", plain, http.MethodPut, "123456789", http.StatusRequestEntityTooLarge},
+		{"invalid UTF-8", plain, http.MethodPut, "a\xff", http.StatusBadRequest},
+		{"patch body too large", plain, http.MethodPatch, `{"op":"append","text":"` + strings.Repeat("a", 32) + `"}`, http.StatusRequestEntityTooLarge},
+		{"patch makes board too large", plain, http.MethodPatch, `{"op":"append","text":"12345"}`, http.StatusRequestEntityTooLarge},
+		{"patch invalid JSON", plain, http.MethodPatch, `{"op":`, http.StatusBadRequest},
+		{"encrypted", encrypted, http.MethodPut, sealed, http.StatusNoContent},
+		{"not encrypted", encrypted, http.MethodPut, "plain", http.StatusUnprocessableEntity},
+		{"patch encrypted", encrypted, http.MethodPatch, `{"op":"append","text":"a"}`, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "abcd"
+			if tt.limits.Encrypted {
+				content = ""
+			}
+			hub, h := newTestHandlers(t, Options{}, tt.limits, content)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v1/boards/{name}/content", h.HandleBoardContent)
+
+			req := httptest.NewRequest(tt.method, "/api/v1/boards/default/content", strings.NewReader(tt.body))
+			req.Header.Set("If-Match", "*")
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if rec.Code != http.StatusNoContent && hub.GetContent() != content {
+				t.Errorf("board = %q after a rejected write", hub.GetContent())
+			}
+		})
+	}
+}
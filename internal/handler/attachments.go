@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/yosebyte/boardcast/internal/attachment"
+)
+
+// UploadResponse describes a stored attachment and how to reference it.
+type UploadResponse struct {
+	attachment.Attachment
+	URL      string `json:"url"`
+	Markdown string `json:"markdown"`
+}
+
+// HandleUpload stores a file sent as the "file" field of a multipart form or
+// as the raw request body.
+func (h *Handlers) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if h.attachments == nil {
+		http.Error(w, "Attachments are disabled", http.StatusNotFound)
+		return
+	}
+	if h.wsHub.Limits().Encrypted {
+		// Uploads would be stored unencrypted, and references to them hide
+		// in content the server cannot read.
+		http.Error(w, "Attachments are not supported on end-to-end encrypted boards", http.StatusConflict)
+		return
+	}
+
+	var body io.Reader = r.Body
+	filename := "attachment"
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		part, err := formFile(r, "file")
+		if err != nil {
+			http.Error(w, "Missing file field", http.StatusBadRequest)
+			return
+		}
+		defer part.Close()
+		body = part
+		if part.FileName() != "" {
+			filename = part.FileName()
+		}
+	}
+
+	a, err := h.attachments.Put(body)
+	switch {
+	case errors.Is(err, attachment.ErrTooLarge):
+		http.Error(w, "Attachment too large", http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, attachment.ErrUnsupportedType):
+		http.Error(w, "Unsupported attachment type", http.StatusUnsupportedMediaType)
+		return
+	case err != nil:
+		h.logger.Error("Failed to store attachment", "error", err)
+		http.Error(w, "Failed to store attachment", http.StatusInternalServerError)
+		return
+	}
+
+	url := h.basePath + "/attachments/" + a.Name
+	label := strings.NewReplacer("[", "", "]", "", "\n", " ").Replace(filename)
+	markdown := "[" + label + "](" + url + ")"
+	if a.IsImage() {
+		markdown = "!" + markdown
+	}
+
+	h.logger.Info("Attachment uploaded", "name", a.Name, "type", a.Type, "size", a.Size)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(UploadResponse{Attachment: a, URL: url, Markdown: markdown})
+}
+
+// formFile returns the multipart part holding the named file field.
+func formFile(r *http.Request, field string) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == field {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// HandleAttachment serves a stored attachment to authenticated users.
+func (h *Handlers) HandleAttachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if h.attachments == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, a, err := h.attachments.Open(r.PathValue("name"))
+	if errors.Is(err, attachment.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to open attachment", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Failed to open attachment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", a.Type)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=31536000, immutable")
+	if !a.IsImage() {
+		w.Header().Set("Content-Disposition", "attachment")
+	}
+	http.ServeContent(w, r, a.Name, info.ModTime(), f)
+}
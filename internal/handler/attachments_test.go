@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/yosebyte/boardcast/internal/attachment"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+func TestHandleUpload(t *testing.T) {
+	const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00\x1f\x15\xc4\x89"
+
+	tests := []struct {
+		name       string
+		limits     websocket.Limits
+		wantStatus int
+		wantStored int
+	}{
+		{"plain board", websocket.Limits{}, http.StatusCreated, 1},
+		{"encrypted board", websocket.Limits{Encrypted: true}, http.StatusConflict, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := attachment.NewStore(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, h := newTestHandlers(t, Options{Attachments: store}, tt.limits, "")
+
+			rec := httptest.NewRecorder()
+			h.HandleUpload(rec, httptest.NewRequest(http.MethodPost, "/api/v1/attachments", strings.NewReader(png)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != tt.wantStored {
+				t.Errorf("stored %d files, want %d", len(entries), tt.wantStored)
+			}
+		})
+	}
+}
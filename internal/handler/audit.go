@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+)
+
+// defaultAuditLimit caps the number of entries returned when no limit is given.
+const defaultAuditLimit = 1000
+
+// HandleAudit returns audit log entries filtered by the from, to, user and
+// action query parameters. Times use RFC 3339. The log holds addresses and
+// sessions of everyone using the board, so it requires the API token rather
+// than a session opened with the board password.
+func (h *Handlers) HandleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !auth.IsAdmin(h.auth, r) {
+		http.Error(w, "The audit log requires the API token", http.StatusForbidden)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := audit.Filter{
+		User:   query.Get("user"),
+		Action: query.Get("action"),
+		Limit:  defaultAuditLimit,
+	}
+
+	var err error
+	if filter.From, err = parseTime(query.Get("from")); err != nil {
+		http.Error(w, "Invalid from time", http.StatusBadRequest)
+		return
+	}
+	if filter.To, err = parseTime(query.Get("to")); err != nil {
+		http.Error(w, "Invalid to time", http.StatusBadRequest)
+		return
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries, err := h.audit.Query(filter)
+	if err != nil {
+		http.Error(w, "Failed to read audit log", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entries)
+}
+
+// parseTime parses an optional RFC 3339 timestamp.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
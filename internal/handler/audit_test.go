@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// newAuditHandlers returns handlers authenticating with the password "pw" and
+// the token "tk", and an audit log holding entries.
+func newAuditHandlers(t *testing.T, entries ...audit.Entry) (*Handlers, *auth.Manager) {
+	t.Helper()
+	log, err := audit.NewFileLog(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+	for _, e := range entries {
+		if err := log.Record(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := auth.NewManager("pw", auth.Options{Token: "tk", Logger: slog.New(slog.DiscardHandler), Audit: audit.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, h := newTestHandlers(t, Options{Auth: m, Audit: log}, websocket.Limits{}, "")
+	return h, m
+}
+
+func TestHandleAuditAccess(t *testing.T) {
+	h, m := newAuditHandlers(t)
+
+	login := httptest.NewRecorder()
+	m.Login(login, httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{"password":"pw"}`)))
+	if login.Code != http.StatusOK {
+		t.Fatalf("login = %d", login.Code)
+	}
+	session := login.Result().Cookies()
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		login  bool
+		want   int
+	}{
+		{"anonymous", http.MethodGet, "", false, http.StatusUnauthorized},
+		{"wrong token", http.MethodGet, "x", false, http.StatusUnauthorized},
+		{"board session", http.MethodGet, "", true, http.StatusForbidden},
+		{"token", http.MethodGet, "tk", false, http.StatusOK},
+		{"method", http.MethodPost, "tk", false, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/audit", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.login {
+				for _, c := range session {
+					req.AddCookie(c)
+				}
+			}
+			rec := httptest.NewRecorder()
+			h.HandleAudit(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAuditFilters(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	h, _ := newAuditHandlers(t,
+		audit.Entry{Time: start, Action: audit.ActionLogin, Actor: audit.Actor{User: "alice"}},
+		audit.Entry{Time: start.Add(time.Hour), Action: audit.ActionEdit, Actor: audit.Actor{User: "alice"}},
+		audit.Entry{Time: start.Add(2 * time.Hour), Action: audit.ActionEdit, Actor: audit.Actor{User: "bob"}},
+		audit.Entry{Time: start.Add(3 * time.Hour), Action: audit.ActionLogout, Actor: audit.Actor{User: "bob"}},
+	)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		users string
+	}{
+		{"all", "", http.StatusOK, "alice alice bob bob"},
+		{"user", "?user=bob", http.StatusOK, "bob bob"},
+		{"action", "?action=edit", http.StatusOK, "alice bob"},
+		{"from", "?from=2026-01-01T02:00:00Z", http.StatusOK, "bob bob"},
+		{"to", "?to=2026-01-01T01:00:00Z", http.StatusOK, "alice alice"},
+		{"limit keeps the latest", "?limit=1", http.StatusOK, "bob"},
+		{"combined", "?user=alice&action=edit", http.StatusOK, "alice"},
+		{"no match", "?user=carol", http.StatusOK, ""},
+		{"invalid from", "?from=yesterday", http.StatusBadRequest, ""},
+		{"invalid to", "?to=2026-01-01", http.StatusBadRequest, ""},
+		{"invalid limit", "?limit=0", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/audit"+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer tk")
+			rec := httptest.NewRecorder()
+			h.HandleAudit(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+
+			var entries []audit.Entry
+			if err := json.NewDecoder(rec.Body).Decode(&entries); err != nil {
+				t.Fatal(err)
+			}
+			users := make([]string, len(entries))
+			for i, e := range entries {
+				users[i] = e.User
+			}
+			if got := strings.Join(users, " "); got != tt.users {
+				t.Errorf("entries of %q, want %q", got, tt.users)
+			}
+		})
+	}
+}
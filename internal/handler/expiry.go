@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// ExpiryRequest sets when a board's content is wiped. TTL is a duration such
+// as "10m"; empty or "0" keeps content until it is read or replaced.
+type ExpiryRequest struct {
+	TTL              string `json:"ttl"`
+	BurnAfterReading bool   `json:"burn_after_reading"`
+}
+
+// ExpiryResponse describes when a board's content is wiped. ExpiresAt is set
+// while the current content is due to expire.
+type ExpiryResponse struct {
+	TTL              string     `json:"ttl"`
+	BurnAfterReading bool       `json:"burn_after_reading"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+// HandleExpiry serves GET and PUT requests for a board's expiry.
+func (h *Handlers) HandleExpiry(w http.ResponseWriter, r *http.Request) {
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.PathValue("name") != websocket.DefaultBoard {
+		http.Error(w, "Board not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req ExpiryRequest
+		if err := json.NewDecoder(h.limitBody(w, r)).Decode(&req); err != nil {
+			h.bodyError(w, err)
+			return
+		}
+
+		var e storage.Expiry
+		if req.TTL != "" {
+			ttl, err := time.ParseDuration(req.TTL)
+			if err != nil || ttl < 0 {
+				http.Error(w, "Invalid TTL", http.StatusBadRequest)
+				return
+			}
+			e.TTL = ttl
+		}
+		e.BurnAfterReading = req.BurnAfterReading
+
+		if err := h.wsHub.SetExpiry(e); err != nil {
+			h.logger.Error("Failed to save board expiry", "board", websocket.DefaultBoard, "error", err)
+			http.Error(w, "Failed to save expiry", http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	e, expiresAt := h.wsHub.Expiry()
+	resp := ExpiryResponse{TTL: e.TTL.String(), BurnAfterReading: e.BurnAfterReading}
+	if !expiresAt.IsZero() {
+		resp.ExpiresAt = &expiresAt
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
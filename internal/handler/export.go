@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/markdown"
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/template"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// ExportResponse is a JSON export of a board. ExpiresAt is set while the
+// content is due to expire.
+type ExportResponse struct {
+	Board      string     `json:"board"`
+	Revision   uint64     `json:"revision"`
+	Size       int        `json:"size"`
+	Encrypted  bool       `json:"encrypted"`
+	ExportedAt time.Time  `json:"exported_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Content    string     `json:"content"`
+}
+
+// HandleExport downloads a board in the format given by the format query
+// parameter: md (the default), html, txt, json, or zip for all boards with
+// their history.
+func (h *Handlers) HandleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	board := r.URL.Query().Get("board")
+	if board == "" {
+		board = websocket.DefaultBoard
+	}
+	if board != websocket.DefaultBoard {
+		http.Error(w, "Board not found", http.StatusNotFound)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "md", "markdown", "json", "zip":
+	case "html", "txt", "text":
+		// Encrypted content can only be exported as it is stored.
+		if h.wsHub.Limits().Encrypted {
+			http.Error(w, "Board is end-to-end encrypted", http.StatusConflict)
+			return
+		}
+	default:
+		http.Error(w, "Unknown export format", http.StatusBadRequest)
+		return
+	}
+
+	content, revision := h.wsHub.View(h.actor(r))
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch format {
+	case "", "md", "markdown":
+		download(w, "text/markdown; charset=utf-8", board+".md")
+		io.WriteString(w, content)
+	case "html":
+		title := markdown.Title(content)
+		if title == "" {
+			title = "BoardCast - " + board
+		}
+		download(w, "text/html; charset=utf-8", board+".html")
+		fmt.Fprintf(w, template.ExportHTML, html.EscapeString(title), markdown.ToHTML(content))
+	case "txt", "text":
+		download(w, "text/plain; charset=utf-8", board+".txt")
+		io.WriteString(w, markdown.ToText(content))
+	case "json":
+		download(w, "application/json", board+".json")
+		json.NewEncoder(w).Encode(h.exportMeta(board, content, revision))
+	case "zip":
+		h.exportZip(w, board, content, revision)
+	}
+}
+
+// exportMeta describes the board with its content.
+func (h *Handlers) exportMeta(board, content string, revision uint64) ExportResponse {
+	resp := ExportResponse{
+		Board:      board,
+		Revision:   revision,
+		Size:       len(content),
+		Encrypted:  h.wsHub.Limits().Encrypted,
+		ExportedAt: time.Now().UTC(),
+		Content:    content,
+	}
+	if _, expiresAt := h.wsHub.Expiry(); !expiresAt.IsZero() {
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
+
+// exportZip writes an archive holding, for each board, its content and
+// metadata and every version in its history:
+//
+//	<board>/content.md
+//	<board>/board.json
+//	<board>/history.json
+//	<board>/history/<id>.md
+//
+// The hub serves a single board, so the archive holds the default board.
+func (h *Handlers) exportZip(w http.ResponseWriter, board, content string, revision uint64) {
+	versions, err := h.wsHub.Versions()
+	if err != nil && !errors.Is(err, websocket.ErrNoHistory) {
+		h.logger.Error("Failed to read history for export", "board", board, "error", err)
+		http.Error(w, "Failed to read history", http.StatusInternalServerError)
+		return
+	}
+	if versions == nil {
+		versions = []storage.Version{}
+	}
+
+	now := time.Now().UTC()
+	download(w, "application/zip", now.Format("20060102-150405")+".zip")
+	zw := zip.NewWriter(w)
+
+	err = writeZipFile(zw, board+"/content.md", now, []byte(content))
+	if err == nil {
+		err = writeZipJSON(zw, board+"/board.json", now, h.exportMeta(board, content, revision))
+	}
+	if err == nil {
+		err = writeZipJSON(zw, board+"/history.json", now, versions)
+	}
+	for _, v := range versions {
+		if err != nil {
+			break
+		}
+		var version storage.Version
+		if version, err = h.wsHub.Version(v.ID); err == nil {
+			err = writeZipFile(zw, board+"/history/"+v.ID+".md", v.Time, []byte(version.Content))
+		}
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if err != nil {
+		// The response has started; the client is left with a truncated
+		// archive.
+		h.logger.Error("Failed to write export archive", "board", board, "error", err)
+	}
+}
+
+// writeZipFile adds a compressed file to zw.
+func writeZipFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+// writeZipJSON adds v as an indented JSON file to zw.
+func writeZipJSON(zw *zip.Writer, name string, modified time.Time, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeZipFile(zw, name, modified, append(data, '\n'))
+}
+
+// download sets the headers of a response saved as filename.
+func download(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "boardcast-"+filename))
+}
@@ -3,37 +3,79 @@ package handler
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 
+	"github.com/yosebyte/boardcast/internal/attachment"
+	"github.com/yosebyte/boardcast/internal/audit"
 	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/replica"
 	"github.com/yosebyte/boardcast/internal/template"
+	"github.com/yosebyte/boardcast/internal/webhook"
 	"github.com/yosebyte/boardcast/internal/websocket"
 	"strconv"
 )
 
 // Handlers contains all HTTP handlers for the application.
 type Handlers struct {
-	auth    *auth.Manager
-	wsHub   *websocket.Hub
-	version string
+	auth        auth.Provider
+	wsHub       *websocket.Hub
+	version     string
+	basePath    string
+	metrics     *metrics.Metrics
+	audit       audit.Log
+	webhooks    *webhook.Dispatcher
+	inbound     *webhook.Inbound
+	attachments *attachment.Store
+	replica     *replica.Replicator
+	logger      *slog.Logger
 }
 
-// New creates a new Handlers instance.
-func New(authManager *auth.Manager, wsHub *websocket.Hub, version string) *Handlers {
+// Options holds the dependencies of Handlers.
+type Options struct {
+	Auth     auth.Provider
+	Hub      *websocket.Hub
+	Version  string
+	BasePath string
+	Metrics  *metrics.Metrics
+	Audit    audit.Log
+	Webhooks *webhook.Dispatcher
+	Inbound  *webhook.Inbound
+	// Attachments stores uploads; nil disables them.
+	Attachments *attachment.Store
+	// Replica replicates from another server; nil when not configured.
+	Replica *replica.Replicator
+	Logger  *slog.Logger
+}
+
+// New creates a new Handlers instance from opts.
+func New(opts Options) *Handlers {
 	return &Handlers{
-		auth:    authManager,
-		wsHub:   wsHub,
-		version: version,
+		auth:        opts.Auth,
+		wsHub:       opts.Hub,
+		version:     opts.Version,
+		basePath:    opts.BasePath,
+		metrics:     opts.Metrics,
+		audit:       opts.Audit,
+		webhooks:    opts.Webhooks,
+		inbound:     opts.Inbound,
+		attachments: opts.Attachments,
+		replica:     opts.Replica,
+		logger:      opts.Logger,
 	}
 }
 
 // ServeWhiteboard serves the main whiteboard page.
 func (h *Handlers) ServeWhiteboard(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	basePath := strconv.Quote(h.basePath)
+	encrypted := strconv.FormatBool(h.wsHub.Limits().Encrypted)
 	if h.auth.IsAuthenticated(r) {
-		fmt.Fprintf(w, template.WhiteboardHTML, strconv.Quote(h.wsHub.GetContent()), h.version)
+		content, _ := h.wsHub.View(h.actor(r))
+		fmt.Fprintf(w, template.WhiteboardHTML, strconv.Quote(content), basePath, encrypted, h.version)
 	} else {
-		fmt.Fprintf(w, template.WhiteboardHTML, strconv.Quote(""), h.version)
+		fmt.Fprintf(w, template.WhiteboardHTML, strconv.Quote(""), basePath, encrypted, h.version)
 	}
 }
 
@@ -43,7 +85,15 @@ func (h *Handlers) HandleAuth(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	h.auth.Login(w, r)
+
+	rec := metrics.NewStatusRecorder(w)
+	h.auth.Login(rec, r)
+
+	if rec.Status() == http.StatusOK {
+		h.metrics.Logins.Inc("success")
+	} else {
+		h.metrics.Logins.Inc("failure")
+	}
 }
 
 // HandleLogout handles logout requests.
@@ -55,13 +105,18 @@ func (h *Handlers) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	h.auth.Logout(w, r)
 }
 
+// actor returns the audit actor making request r.
+func (h *Handlers) actor(r *http.Request) audit.Actor {
+	return auth.IdentityOf(h.auth, r).Actor(r)
+}
+
 // HandleWebSocket handles WebSocket connections with authentication.
 func (h *Handlers) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	if !h.auth.IsAuthenticated(r) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	h.wsHub.HandleConnection(w, r)
+	h.wsHub.HandleConnection(w, r, auth.IdentityOf(h.auth, r))
 }
 
 // HandleContent returns the current whiteboard content with authentication.
@@ -72,7 +127,8 @@ func (h *Handlers) HandleContent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	w.Write([]byte(h.wsHub.GetContent()))
+	content, _ := h.wsHub.View(h.actor(r))
+	w.Write([]byte(content))
 }
 
 // HandleSave saves the current whiteboard content to a file.
@@ -87,7 +143,7 @@ func (h *Handlers) HandleSave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.wsHub.SaveSnapshot(); err != nil {
+	if err := h.wsHub.SaveSnapshot(h.actor(r)); err != nil {
 		http.Error(w, "Failed to save snapshot", http.StatusInternalServerError)
 		return
 	}
@@ -108,7 +164,7 @@ func (h *Handlers) HandleRestore(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.wsHub.RestoreSnapshot(); err != nil {
+	if err := h.wsHub.RestoreSnapshot(h.actor(r)); err != nil {
 		http.Error(w, "Failed to restore snapshot", http.StatusInternalServerError)
 		return
 	}
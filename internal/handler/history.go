@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// HandleHistory lists the versions of a board, oldest first, without their content.
+func (h *Handlers) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if !h.historyRequest(w, r) {
+		return
+	}
+
+	versions, err := h.wsHub.Versions()
+	if errors.Is(err, websocket.ErrNoHistory) {
+		http.Error(w, "History not available", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to read history", http.StatusInternalServerError)
+		return
+	}
+	if versions == nil {
+		versions = []storage.Version{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// HandleVersion returns one version of a board including its content.
+func (h *Handlers) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	if !h.historyRequest(w, r) {
+		return
+	}
+
+	id := r.PathValue("id")
+	if !storage.ValidVersionID(id) {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	}
+
+	version, err := h.wsHub.Version(id)
+	if errors.Is(err, storage.ErrVersionNotFound) {
+		http.Error(w, "Version not found", http.StatusNotFound)
+		return
+	} else if errors.Is(err, websocket.ErrNoHistory) {
+		http.Error(w, "History not available", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Failed to read history", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(version)
+}
+
+// historyRequest checks the method, authentication and board of a history
+// request, writing an error response if one fails.
+func (h *Handlers) historyRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if r.PathValue("name") != websocket.DefaultBoard {
+		http.Error(w, "Board not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// HandleReplication reports the state of replication from another server.
+func (h *Handlers) HandleReplication(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.replica == nil {
+		http.Error(w, "Replication not configured", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.replica.Status())
+}
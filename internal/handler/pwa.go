@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/yosebyte/boardcast/internal/template"
+)
+
+// HandleManifest serves the web app manifest.
+func (h *Handlers) HandleManifest(w http.ResponseWriter, r *http.Request) {
+	if !staticRequest(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/manifest+json")
+	w.Header().Set("Cache-Control", "no-cache")
+	io.WriteString(w, template.ManifestJSON)
+}
+
+// HandleServiceWorker serves the service worker. Its cache is named after the
+// version so that upgrading the server replaces cached assets.
+func (h *Handlers) HandleServiceWorker(w http.ResponseWriter, r *http.Request) {
+	if !staticRequest(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, template.ServiceWorkerJS, strconv.Quote(h.basePath), strconv.Quote("boardcast-"+h.version))
+}
+
+// HandleIcon serves the application icons.
+func (h *Handlers) HandleIcon(w http.ResponseWriter, r *http.Request) {
+	if !staticRequest(w, r) {
+		return
+	}
+
+	name := r.PathValue("name")
+	if name == "icon.svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		io.WriteString(w, template.IconSVG)
+		return
+	}
+
+	icon, ok := template.IconPNG(name)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(icon)
+}
+
+// staticRequest checks the method of a request for a public asset.
+func staticRequest(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// newPWAServer serves the web app files of handlers for version under basePath.
+func newPWAServer(t *testing.T, basePath, version string) http.Handler {
+	t.Helper()
+	_, h := newTestHandlers(t, Options{Auth: denyAll{}, BasePath: basePath, Version: version}, websocket.Limits{}, "")
+	mux := http.NewServeMux()
+	mux.HandleFunc(basePath+"/manifest.webmanifest", h.HandleManifest)
+	mux.HandleFunc(basePath+"/sw.js", h.HandleServiceWorker)
+	mux.HandleFunc(basePath+"/icons/{name}", h.HandleIcon)
+	return mux
+}
+
+func TestHandleManifest(t *testing.T) {
+	mux := newPWAServer(t, "/board", "1.2.3")
+
+	// The app files are public, since they are fetched before logging in.
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/board/manifest.webmanifest", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/manifest+json" {
+		t.Fatalf("manifest = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	var manifest struct {
+		StartURL string `json:"start_url"`
+		Scope    string `json:"scope"`
+		Display  string `json:"display"`
+		Icons    []struct {
+			Src   string `json:"src"`
+			Sizes string `json:"sizes"`
+			Type  string `json:"type"`
+		} `json:"icons"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.StartURL != "./" || manifest.Scope != "./" || manifest.Display != "standalone" {
+		t.Errorf("manifest = %+v, want a standalone app relative to the base path", manifest)
+	}
+
+	// Every icon is served with the type and size the manifest gives it.
+	for _, icon := range manifest.Icons {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/board/"+icon.Src, nil))
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != icon.Type {
+			t.Errorf("%s = %d %s, want %s", icon.Src, w.Code, w.Header().Get("Content-Type"), icon.Type)
+			continue
+		}
+		if icon.Type != "image/png" {
+			continue
+		}
+		cfg, err := png.DecodeConfig(w.Body)
+		if err != nil {
+			t.Errorf("%s: %v", icon.Src, err)
+		} else if size := fmt.Sprintf("%dx%d", cfg.Width, cfg.Height); size != icon.Sizes {
+			t.Errorf("%s is %dx%d, want %s", icon.Src, cfg.Width, cfg.Height, icon.Sizes)
+		}
+	}
+}
+
+func TestHandleServiceWorker(t *testing.T) {
+	tests := []struct {
+		basePath string
+		version  string
+		want     string
+	}{
+		{"", "1.2.3", `const base = "", cache = "boardcast-1.2.3",`},
+		{"/board", "dev", `const base = "/board", cache = "boardcast-dev",`},
+	}
+	for _, tt := range tests {
+		mux := newPWAServer(t, tt.basePath, tt.version)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.basePath+"/sw.js", nil))
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+			t.Fatalf("sw.js = %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		// Browsers check for a new worker on every visit.
+		if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+			t.Errorf("Cache-Control = %q, want no-cache", got)
+		}
+		// The cache is named after the version so that upgrades replace it.
+		if !strings.HasPrefix(w.Body.String(), tt.want) {
+			t.Errorf("sw.js starts %.80q, want %q", w.Body, tt.want)
+		}
+		if strings.Contains(w.Body.String(), "%!") {
+			t.Errorf("sw.js has formatting errors: %s", w.Body)
+		}
+	}
+}
+
+func TestHandleIcon(t *testing.T) {
+	mux := newPWAServer(t, "", "dev")
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/icons/icon.svg", http.StatusOK},
+		{http.MethodHead, "/icons/icon-192.png", http.StatusOK},
+		{http.MethodGet, "/icons/favicon.ico", http.StatusNotFound},
+		{http.MethodPost, "/icons/icon.svg", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/sw.js", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.want)
+		}
+	}
+}
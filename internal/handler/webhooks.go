@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/webhook"
+)
+
+// HandleWebhooks lists the configured outgoing webhooks and their recent deliveries.
+func (h *Handlers) HandleWebhooks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.webhooks.Hooks())
+}
+
+// HandleWebhookTest sends a ping event to a single webhook and reports the delivery.
+func (h *Handlers) HandleWebhookTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.auth.IsAuthenticated(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	delivery, err := h.webhooks.Test(r.Context(), r.PathValue("id"))
+	if errors.Is(err, webhook.ErrHookNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	status := http.StatusOK
+	if !delivery.Succeeded() {
+		status = http.StatusBadGateway
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(delivery)
+}
+
+// HandleInboundHook writes an inbound webhook payload to its board. Callers
+// authenticate with the hook's token rather than a session.
+func (h *Handlers) HandleInboundHook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = r.Header.Get(webhook.TokenHeader)
+	}
+
+	body, err := io.ReadAll(h.limitBody(w, r))
+	if err != nil {
+		h.bodyError(w, err)
+		return
+	}
+
+	id := r.PathValue("id")
+	hook, message, err := h.inbound.Receive(id, token, r.Header.Get("Content-Type"), body)
+	switch {
+	case errors.Is(err, webhook.ErrHookNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	case errors.Is(err, webhook.ErrInvalidToken):
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	actor := audit.Actor{User: "hook:" + id, RemoteAddr: r.RemoteAddr}
+	h.writeBoardContent(w, actor, "", func(content string) (string, error) {
+		return hook.Apply(content, message), nil
+	})
+}
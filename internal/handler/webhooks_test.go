@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yosebyte/boardcast/internal/webhook"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// denyAll is an auth.Provider rejecting every request.
+type denyAll struct{ allowAll }
+
+func (denyAll) IsAuthenticated(*http.Request) bool { return false }
+
+func TestHandleWebhookTest(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(webhook.EventHeader) != webhook.EventPing {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ok.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	dispatcher := webhook.NewDispatcher([]webhook.Hook{
+		{ID: "ok", URL: ok.URL, Events: []string{webhook.EventSave}},
+		{ID: "failing", URL: failing.URL},
+	}, slog.New(slog.DiscardHandler))
+	defer dispatcher.Close(context.Background())
+
+	tests := []struct {
+		name   string
+		method string
+		id     string
+		denied bool
+		want   int
+	}{
+		{"delivered", http.MethodPost, "ok", false, http.StatusOK},
+		{"receiver failed", http.MethodPost, "failing", false, http.StatusBadGateway},
+		{"unknown hook", http.MethodPost, "missing", false, http.StatusNotFound},
+		{"unauthenticated", http.MethodPost, "ok", true, http.StatusUnauthorized},
+		{"wrong method", http.MethodGet, "ok", false, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Webhooks: dispatcher}
+			if tt.denied {
+				opts.Auth = denyAll{}
+			}
+			_, h := newTestHandlers(t, opts, websocket.Limits{}, "")
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v1/webhooks/{id}/test", h.HandleWebhookTest)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, httptest.NewRequest(tt.method, "/api/v1/webhooks/"+tt.id+"/test", nil))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusOK && tt.want != http.StatusBadGateway {
+				return
+			}
+
+			var delivery webhook.Delivery
+			if err := json.NewDecoder(w.Body).Decode(&delivery); err != nil {
+				t.Fatal(err)
+			}
+			if delivery.Event != webhook.EventPing || delivery.Attempts != 1 {
+				t.Errorf("delivery = %+v, want a single ping attempt", delivery)
+			}
+		})
+	}
+
+	// Tests show up in the delivery log.
+	for _, status := range dispatcher.Hooks() {
+		if len(status.Deliveries) != 1 {
+			t.Errorf("hook %s logged %d deliveries, want 1", status.ID, len(status.Deliveries))
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// spanKind identifies the type of an inline span.
+type spanKind int
+
+const (
+	text spanKind = iota
+	code
+	strong
+	emphasis
+	strike
+	link
+	image
+	lineBreak
+)
+
+// span is a parsed inline element.
+type span struct {
+	kind spanKind
+	// text is the content of text and code spans.
+	text     string
+	href     string
+	title    string
+	children []span
+}
+
+// autolinkPattern matches an autolink such as <https://example.com>.
+var autolinkPattern = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^<>\s]*|[^<>\s@]+@[^<>\s@]+\.[a-zA-Z]+)>`)
+
+// urlPattern matches a bare URL, which GitHub-flavoured Markdown turns into
+// a link.
+var urlPattern = regexp.MustCompile(`^https?://[^\s<]+`)
+
+// parseInline parses the inline source s into spans.
+func parseInline(s string) []span {
+	var spans []span
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			spans = append(spans, span{kind: text, text: buf.String()})
+			buf.Reset()
+		}
+	}
+	add := func(sp span) {
+		flush()
+		spans = append(spans, sp)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			add(span{kind: lineBreak})
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(s) && isPunct(s[i+1]):
+			buf.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			// Two trailing spaces make a hard line break.
+			trimmed := strings.TrimRight(buf.String(), " ")
+			hard := buf.Len()-len(trimmed) >= 2
+			buf.Reset()
+			buf.WriteString(trimmed)
+			if hard {
+				add(span{kind: lineBreak})
+			} else {
+				buf.WriteByte('\n')
+			}
+			i++
+			continue
+		case c == '`':
+			if content, end, ok := codeSpan(s, i); ok {
+				add(span{kind: code, text: content})
+				i = end
+				continue
+			}
+			n := run(s, i)
+			buf.WriteString(s[i : i+n])
+			i += n
+			continue
+		case c == '!' && i+1 < len(s) && s[i+1] == '[':
+			if sp, end, ok := parseLink(s, i+1); ok {
+				sp.kind = image
+				add(sp)
+				i = end
+				continue
+			}
+		case c == '[':
+			if sp, end, ok := parseLink(s, i); ok {
+				add(sp)
+				i = end
+				continue
+			}
+		case c == '<':
+			if m := autolinkPattern.FindStringSubmatch(s[i:]); m != nil {
+				href := m[1]
+				if !strings.Contains(href, ":") {
+					href = "mailto:" + href
+				}
+				add(span{kind: link, href: href, children: []span{{kind: text, text: m[1]}}})
+				i += len(m[0])
+				continue
+			}
+		case c == 'h' && (i == 0 || !isWord(s[i-1])):
+			if m := urlPattern.FindString(s[i:]); m != "" {
+				m = strings.TrimRight(m, ".,:;!?\"')*_~")
+				add(span{kind: link, href: m, children: []span{{kind: text, text: m}}})
+				i += len(m)
+				continue
+			}
+		case c == '*' || c == '_' || c == '~':
+			if sp, end, ok := delimited(s, i); ok {
+				add(sp)
+				i = end
+				continue
+			}
+			n := run(s, i)
+			buf.WriteString(s[i : i+n])
+			i += n
+			continue
+		}
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+	return spans
+}
+
+// codeSpan parses the code span opening at s[i], returning its content and
+// the index after it.
+func codeSpan(s string, i int) (string, int, bool) {
+	n := run(s, i)
+	for j := i + n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := run(s, j)
+		if m == n {
+			content := strings.ReplaceAll(s[i+n:j], "\n", " ")
+			if len(content) > 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.TrimSpace(content) != "" {
+				content = content[1 : len(content)-1]
+			}
+			return content, j + m, true
+		}
+		j += m
+	}
+	return "", 0, false
+}
+
+// parseLink parses the link "[text](href "title")" starting at s[i].
+func parseLink(s string, i int) (span, int, bool) {
+	// Find the bracket closing the text, skipping nested brackets.
+	depth, end := 0, -1
+	for j := i; j < len(s) && end < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				end = j
+			}
+		}
+	}
+	if end < 0 || end+1 >= len(s) || s[end+1] != '(' {
+		return span{}, 0, false
+	}
+
+	// Find the parenthesis closing the destination and title.
+	depth, close := 0, -1
+	for j := end + 1; j < len(s) && close < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				close = j
+			}
+		}
+	}
+	if close < 0 {
+		return span{}, 0, false
+	}
+
+	dest := strings.TrimSpace(s[end+2 : close])
+	var href, title string
+	if strings.HasPrefix(dest, "<") {
+		if k := strings.IndexByte(dest, '>'); k > 0 {
+			href, title = dest[1:k], strings.TrimSpace(dest[k+1:])
+		}
+	} else if k := strings.IndexAny(dest, " \t\n"); k >= 0 {
+		href, title = dest[:k], strings.TrimSpace(dest[k:])
+	} else {
+		href = dest
+	}
+	if title != "" {
+		if len(title) < 2 || !strings.ContainsRune(`"'(`, rune(title[0])) {
+			return span{}, 0, false
+		}
+		title = title[1 : len(title)-1]
+	}
+
+	return span{
+		kind:     link,
+		href:     href,
+		title:    title,
+		children: parseInline(s[i+1 : end]),
+	}, close + 1, true
+}
+
+// delimited parses the emphasis, strong emphasis or strikethrough opening at
+// s[i], such as "*a*", "__a__" or "~~a~~".
+func delimited(s string, i int) (span, int, bool) {
+	c := s[i]
+	n := run(s, i)
+	if n > 3 || (c == '~' && n != 2) {
+		return span{}, 0, false
+	}
+	// The opening run must precede text, and underscores must not sit
+	// inside a word.
+	if i+n >= len(s) || isSpace(s[i+n]) || (c == '_' && i > 0 && isWord(s[i-1])) {
+		return span{}, 0, false
+	}
+
+	for j := i + n; j < len(s); {
+		if s[j] == '`' {
+			if _, end, ok := codeSpan(s, j); ok {
+				j = end
+				continue
+			}
+		}
+		if s[j] == '\\' {
+			j += 2
+			continue
+		}
+		if s[j] != c {
+			j++
+			continue
+		}
+		m := run(s, j)
+		closes := m == n && !isSpace(s[j-1]) &&
+			!(c == '_' && j+m < len(s) && isWord(s[j+m]))
+		if !closes {
+			j += m
+			continue
+		}
+
+		children := parseInline(s[i+n : j])
+		var sp span
+		switch {
+		case c == '~':
+			sp = span{kind: strike, children: children}
+		case n == 1:
+			sp = span{kind: emphasis, children: children}
+		case n == 2:
+			sp = span{kind: strong, children: children}
+		default:
+			sp = span{kind: strong, children: []span{{kind: emphasis, children: children}}}
+		}
+		return sp, j + m, true
+	}
+	return span{}, 0, false
+}
+
+// run returns the length of the run of the byte at s[i].
+func run(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// isPunct reports whether c is ASCII punctuation, which a backslash escapes.
+func isPunct(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsPunct(rune(c)) || strings.IndexByte("$+<=>^`|~", c) >= 0
+}
+
+// isSpace reports whether c is whitespace.
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// isWord reports whether c is part of a word, including any byte of a
+// multi-byte character.
+func isWord(c byte) bool {
+	return c >= utf8.RuneSelf || c == '_' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c))
+}
@@ -0,0 +1,277 @@
+// Package markdown renders board content, written in the GitHub-flavoured
+// Markdown shown by the board preview, as HTML or as plain text for exports.
+// Raw HTML in the source is escaped rather than passed through.
+package markdown
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// blockKind identifies the type of a block.
+type blockKind int
+
+const (
+	paragraph blockKind = iota
+	heading
+	codeBlock
+	quote
+	list
+	table
+	rule
+)
+
+// block is a parsed block of a document.
+type block struct {
+	kind blockKind
+	// text is the inline source of paragraphs and headings, or the content
+	// of code blocks.
+	text  string
+	level int
+	lang  string
+	// children holds the blocks of a quote.
+	children []*block
+	// items holds the list items, each a sequence of blocks.
+	items   []item
+	ordered bool
+	start   int
+	// header, rows and align describe a table.
+	header []string
+	rows   [][]string
+	align  []string
+}
+
+// item is a list item.
+type item struct {
+	blocks []*block
+	// task is set for task list items, checked holds their state.
+	task    bool
+	checked bool
+}
+
+var (
+	fencePattern     = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	headingPattern   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	quotePattern     = regexp.MustCompile(`^ {0,3}> ?`)
+	itemPattern      = regexp.MustCompile(`^( {0,3})([-*+]|[0-9]{1,9}[.)])(?:([ \t]+)(.*))?$`)
+	delimiterPattern = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	taskPattern      = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+)
+
+// parse splits src into blocks.
+func parse(src string) []*block {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\t", "    ")
+	return parseBlocks(strings.Split(src, "\n"))
+}
+
+// parseBlocks parses lines into blocks.
+func parseBlocks(lines []string) []*block {
+	var blocks []*block
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case blank(line):
+			i++
+		case fencePattern.MatchString(line):
+			var b *block
+			b, i = parseFence(lines, i)
+			blocks = append(blocks, b)
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			blocks = append(blocks, &block{kind: heading, level: len(m[1]), text: m[2]})
+			i++
+		case isRule(line):
+			blocks = append(blocks, &block{kind: rule})
+			i++
+		case quotePattern.MatchString(line):
+			var inner []string
+			for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+				inner = append(inner, quotePattern.ReplaceAllString(lines[i], ""))
+			}
+			blocks = append(blocks, &block{kind: quote, children: parseBlocks(inner)})
+		case itemPattern.MatchString(line):
+			var b *block
+			b, i = parseList(lines, i)
+			blocks = append(blocks, b)
+		case indent(line) >= 4:
+			var code []string
+			for ; i < len(lines) && (indent(lines[i]) >= 4 || blank(lines[i])); i++ {
+				code = append(code, strings.TrimPrefix(lines[i], "    "))
+			}
+			for len(code) > 0 && blank(code[len(code)-1]) {
+				code = code[:len(code)-1]
+			}
+			blocks = append(blocks, &block{kind: codeBlock, text: strings.Join(code, "\n")})
+		case i+1 < len(lines) && strings.Contains(line, "|") && delimiterPattern.MatchString(lines[i+1]):
+			var b *block
+			b, i = parseTable(lines, i)
+			blocks = append(blocks, b)
+		default:
+			start := i
+			for i++; i < len(lines) && !blank(lines[i]) && !interrupts(lines[i]); i++ {
+			}
+			text := strings.TrimSpace(strings.Join(lines[start:i], "\n"))
+			blocks = append(blocks, &block{kind: paragraph, text: text})
+		}
+	}
+	return blocks
+}
+
+// interrupts reports whether line starts a block that ends a paragraph.
+func interrupts(line string) bool {
+	return fencePattern.MatchString(line) || headingPattern.MatchString(line) || isRule(line) ||
+		quotePattern.MatchString(line) || itemPattern.MatchString(line)
+}
+
+// parseFence parses the fenced code block starting at lines[i].
+func parseFence(lines []string, i int) (*block, int) {
+	m := fencePattern.FindStringSubmatch(lines[i])
+	open, fence := len(m[1]), m[2]
+	b := &block{kind: codeBlock, lang: m[3]}
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		code = append(code, strings.TrimPrefix(lines[i], strings.Repeat(" ", min(open, indent(lines[i])))))
+	}
+	b.text = strings.Join(code, "\n")
+	return b, i
+}
+
+// parseList parses the list starting at lines[i].
+func parseList(lines []string, i int) (*block, int) {
+	m := itemPattern.FindStringSubmatch(lines[i])
+	marker := m[2]
+	b := &block{kind: list, ordered: !strings.ContainsAny(marker[:1], "-*+")}
+	if b.ordered {
+		b.start, _ = strconv.Atoi(marker[:len(marker)-1])
+	}
+
+	for i < len(lines) {
+		m := itemPattern.FindStringSubmatch(lines[i])
+		if m == nil || !sameList(marker, m[2]) {
+			break
+		}
+		// Continuation lines are indented to the content of the item.
+		width := len(m[1]) + len(m[2]) + len(m[3])
+		if len(m[3]) > 4 || m[4] == "" {
+			width = len(m[1]) + len(m[2]) + 1
+		}
+		body := []string{m[4]}
+
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if blank(line) {
+				body = append(body, "")
+				continue
+			}
+			if indent(line) >= width {
+				body = append(body, line[width:])
+				continue
+			}
+			// A lazy continuation of the item's paragraph.
+			if !blank(body[len(body)-1]) && !interrupts(line) {
+				body = append(body, strings.TrimSpace(line))
+				continue
+			}
+			break
+		}
+		for len(body) > 0 && blank(body[len(body)-1]) {
+			body = body[:len(body)-1]
+		}
+
+		var it item
+		if len(body) == 0 {
+			// An empty item such as a lone "-".
+			b.items = append(b.items, it)
+			continue
+		}
+		if t := taskPattern.FindStringSubmatch(body[0]); t != nil {
+			it.task, it.checked = true, t[1] != " "
+			body[0] = body[0][len(t[0]):]
+		}
+		it.blocks = parseBlocks(body)
+		b.items = append(b.items, it)
+	}
+	return b, i
+}
+
+// sameList reports whether the markers a and b belong to the same list.
+func sameList(a, b string) bool {
+	if strings.ContainsAny(a[:1], "-*+") {
+		return a == b
+	}
+	return a[len(a)-1] == b[len(b)-1]
+}
+
+// parseTable parses the table starting at lines[i], whose delimiter row
+// follows its header.
+func parseTable(lines []string, i int) (*block, int) {
+	b := &block{kind: table, header: cells(lines[i])}
+	for _, c := range cells(lines[i+1]) {
+		switch {
+		case strings.HasPrefix(c, ":") && strings.HasSuffix(c, ":"):
+			b.align = append(b.align, "center")
+		case strings.HasSuffix(c, ":"):
+			b.align = append(b.align, "right")
+		case strings.HasPrefix(c, ":"):
+			b.align = append(b.align, "left")
+		default:
+			b.align = append(b.align, "")
+		}
+	}
+	for i += 2; i < len(lines) && !blank(lines[i]) && strings.Contains(lines[i], "|"); i++ {
+		b.rows = append(b.rows, cells(lines[i]))
+	}
+	return b, i
+}
+
+// cells splits a table row into its cells.
+func cells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// isRule reports whether line is a thematic break such as "---" or "* * *".
+func isRule(line string) bool {
+	if indent(line) > 3 {
+		return false
+	}
+	s := strings.ReplaceAll(strings.TrimSpace(line), " ", "")
+	return len(s) >= 3 && strings.Count(s, s[:1]) == len(s) && strings.Contains("-*_", s[:1])
+}
+
+// blank reports whether line holds only whitespace.
+func blank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indent returns the number of leading spaces of line.
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
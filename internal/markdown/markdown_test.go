@@ -0,0 +1,145 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestToHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "", ""},
+		{"paragraph", "hello\nworld", "<p>hello\nworld</p>\n"},
+		{"heading", "## Title ##", "<h2>Title</h2>\n"},
+		{"emphasis", "**b** *i* ***bi*** ~~s~~", "<p><strong>b</strong> <em>i</em> <strong><em>bi</em></strong> <del>s</del></p>\n"},
+		{"intraword underscore", "snake_case_word", "<p>snake_case_word</p>\n"},
+		{"lone star", "2 * 3", "<p>2 * 3</p>\n"},
+		{"code span", "`a*b*`", "<p><code>a*b*</code></p>\n"},
+		{"escape", `\*a\*`, "<p>*a*</p>\n"},
+		{"raw html", "<b>x</b>", "<p>&lt;b&gt;x&lt;/b&gt;</p>\n"},
+		{"hard break", "a  \nb", "<p>a<br>\nb</p>\n"},
+		{"link", `[x](https://e.com "T")`, `<p><a href="https://e.com" title="T">x</a></p>` + "\n"},
+		{"image", "![alt](/a.png)", `<p><img src="/a.png" alt="alt"></p>` + "\n"},
+		{"autolink", "<https://e.com>", `<p><a href="https://e.com">https://e.com</a></p>` + "\n"},
+		{"bare url", "see https://e.com.", `<p>see <a href="https://e.com">https://e.com</a>.</p>` + "\n"},
+		{"fence", "```go\na<b\n```", "<pre><code class=\"language-go\">a&lt;b\n</code></pre>\n"},
+		{"unclosed fence", "```\ncode", "<pre><code>code\n</code></pre>\n"},
+		{"indented code", "    x", "<pre><code>x\n</code></pre>\n"},
+		{"quote", "> a\n> b", "<blockquote>\n<p>a\nb</p>\n</blockquote>\n"},
+		{"rule", "a\n\n* * *", "<p>a</p>\n<hr>\n"},
+		{"list", "- a\n- b", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n"},
+		{"nested list", "- a\n  - b", "<ul>\n<li>a<ul>\n<li>b</li>\n</ul>\n</li>\n</ul>\n"},
+		{"ordered list", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"empty item", "-", "<ul>\n<li></li>\n</ul>\n"},
+		{"empty star item", "*", "<ul>\n<li></li>\n</ul>\n"},
+		{"empty ordered item", "1.", "<ol>\n<li></li>\n</ol>\n"},
+		{"empty item with space", "- \n", "<ul>\n<li></li>\n</ul>\n"},
+		{"empty item after paragraph", "todo:\n-\n", "<p>todo:</p>\n<ul>\n<li></li>\n</ul>\n"},
+		{"empty item between items", "- a\n-\n- b", "<ul>\n<li>a</li>\n<li></li>\n<li>b</li>\n</ul>\n"},
+		{"task list", "- [x] done\n- [ ] todo",
+			"<ul>\n<li><input type=\"checkbox\" checked disabled> done</li>\n<li><input type=\"checkbox\" disabled> todo</li>\n</ul>\n"},
+		{"table", "| a | b |\n|:--|--:|\n| 1 | 2 \\| 3 |",
+			"<table>\n<thead>\n<tr><th style=\"text-align:left\">a</th><th style=\"text-align:right\">b</th></tr>\n</thead>\n" +
+				"<tbody>\n<tr><td style=\"text-align:left\">1</td><td style=\"text-align:right\">2 | 3</td></tr>\n</tbody>\n</table>\n"},
+		{"table short row", "a|b\n-|-\n1|", "<table>\n<thead>\n<tr><th>a</th><th>b</th></tr>\n</thead>\n<tbody>\n<tr><td>1</td><td></td></tr>\n</tbody>\n</table>\n"},
+		{"table without rows", "a|b\n-|-", "<table>\n<thead>\n<tr><th>a</th><th>b</th></tr>\n</thead>\n</table>\n"},
+		{"javascript link", "[x](javascript:alert(1))", `<p><a href="#">x</a></p>` + "\n"},
+		{"javascript image", "![x](JavaScript:alert(1))", `<p><img src="#" alt="x"></p>` + "\n"},
+		{"quoted href", `[x](https://e.com/?a="b")`, `<p><a href="https://e.com/?a=&#34;b&#34;">x</a></p>` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToHTML(tt.src); got != tt.want {
+				t.Errorf("ToHTML(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToText(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", "", "\n"},
+		{"heading and paragraph", "# T\n\n**b** and `c`", "T\n\nb and c\n"},
+		{"link", "[x](https://e.com)", "x (https://e.com)\n"},
+		{"autolink", "<https://e.com>", "https://e.com\n"},
+		{"image", "![alt](/a.png)", "alt\n"},
+		{"list", "- a\n  - b\n1. c", "• a\n  • b\n\n1. c\n"},
+		{"empty item", "-", "•\n"},
+		{"empty item after paragraph", "todo:\n-\n", "todo:\n\n•\n"},
+		{"task list", "- [x] done\n- [ ] todo", "• [x] done\n• [ ] todo\n"},
+		{"quote", "> a", "    a\n"},
+		{"table", "a|b\n-|-\n1|2", "a\tb\n1\t2\n"},
+		{"rule", "a\n\n---\n\nb", "a\n\nb\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ToText(tt.src); got != tt.want {
+				t.Errorf("ToText(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"", ""},
+		{"-", ""},
+		{"text\n\n## *Sub* title", "Sub title"},
+		{"# [Home](/)", "Home (/)"},
+	}
+	for _, tt := range tests {
+		if got := Title(tt.src); got != tt.want {
+			t.Errorf("Title(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		href string
+		want string
+	}{
+		{"https://e.com/a", "https://e.com/a"},
+		{"http://e.com", "http://e.com"},
+		{"mailto:a@e.com", "mailto:a@e.com"},
+		{"tel:+1234", "tel:+1234"},
+		{"/attachments/a.png", "/attachments/a.png"},
+		{"#top", "#top"},
+		{"javascript:alert(1)", "#"},
+		{"JAVASCRIPT:alert(1)", "#"},
+		{" javascript:alert(1)", "#"},
+		{"java\tscript:alert(1)", "#"},
+		{"vbscript:x", "#"},
+		{"data:text/html,<script>", "#"},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.href); got != tt.want {
+			t.Errorf("safeURL(%q) = %q, want %q", tt.href, got, tt.want)
+		}
+	}
+}
+
+// TestNoPanic renders inputs that once crashed or could crash the parser.
+func TestNoPanic(t *testing.T) {
+	inputs := []string{
+		"-", "*", "+", "1.", "1)", "- \n", "todo:\n-\n", "- [ ]", "- [x]",
+		"-\n  -\n    -", "> -", ">", "```", "~~~~", "|", "|\n|", "a|b\n-|-\n|",
+		"[", "[]", "[](", "![", "`", "**", "~~", "_", "\\", "<", "<>", "h", "http://",
+		"#", "######", "#######", strings.Repeat("- ", 50), strings.Repeat("> ", 50),
+	}
+	for _, src := range inputs {
+		ToHTML(src)
+		ToText(src)
+		Title(src)
+	}
+}
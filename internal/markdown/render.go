@@ -0,0 +1,271 @@
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ToHTML renders src as an HTML fragment.
+func ToHTML(src string) string {
+	var b strings.Builder
+	writeHTML(&b, parse(src), false)
+	return b.String()
+}
+
+// ToText renders src as plain text without Markdown syntax. Links are kept
+// as their text followed by the URL.
+func ToText(src string) string {
+	var b strings.Builder
+	writeText(&b, parse(src), "", false)
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// Title returns the text of the first heading of src, or "" if it has none.
+func Title(src string) string {
+	for _, bl := range parse(src) {
+		if bl.kind == heading {
+			var b strings.Builder
+			writeSpansText(&b, parseInline(bl.text))
+			return strings.TrimSpace(b.String())
+		}
+	}
+	return ""
+}
+
+// writeHTML renders blocks as HTML. Paragraphs of tight list items are
+// written without their <p> element.
+func writeHTML(b *strings.Builder, blocks []*block, tight bool) {
+	for _, bl := range blocks {
+		switch bl.kind {
+		case paragraph:
+			if tight {
+				writeSpansHTML(b, parseInline(bl.text))
+				continue
+			}
+			b.WriteString("<p>")
+			writeSpansHTML(b, parseInline(bl.text))
+			b.WriteString("</p>\n")
+		case heading:
+			level := strconv.Itoa(bl.level)
+			b.WriteString("<h" + level + ">")
+			writeSpansHTML(b, parseInline(bl.text))
+			b.WriteString("</h" + level + ">\n")
+		case codeBlock:
+			b.WriteString("<pre><code")
+			if bl.lang != "" {
+				b.WriteString(` class="language-` + html.EscapeString(bl.lang) + `"`)
+			}
+			b.WriteString(">")
+			b.WriteString(html.EscapeString(bl.text))
+			if bl.text != "" {
+				b.WriteString("\n")
+			}
+			b.WriteString("</code></pre>\n")
+		case quote:
+			b.WriteString("<blockquote>\n")
+			writeHTML(b, bl.children, false)
+			b.WriteString("</blockquote>\n")
+		case rule:
+			b.WriteString("<hr>\n")
+		case list:
+			tag := "ul"
+			if bl.ordered {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag)
+			if bl.ordered && bl.start != 1 {
+				b.WriteString(` start="` + strconv.Itoa(bl.start) + `"`)
+			}
+			b.WriteString(">\n")
+			for _, it := range bl.items {
+				b.WriteString("<li>")
+				if it.task {
+					if it.checked {
+						b.WriteString(`<input type="checkbox" checked disabled> `)
+					} else {
+						b.WriteString(`<input type="checkbox" disabled> `)
+					}
+				}
+				writeHTML(b, it.blocks, true)
+				b.WriteString("</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		case table:
+			b.WriteString("<table>\n<thead>\n")
+			writeRowHTML(b, "th", bl.header, bl.align)
+			b.WriteString("</thead>\n")
+			if len(bl.rows) > 0 {
+				b.WriteString("<tbody>\n")
+				for _, row := range bl.rows {
+					writeRowHTML(b, "td", row, bl.align)
+				}
+				b.WriteString("</tbody>\n")
+			}
+			b.WriteString("</table>\n")
+		}
+	}
+}
+
+// writeRowHTML renders a table row with one cell per column.
+func writeRowHTML(b *strings.Builder, tag string, row, align []string) {
+	b.WriteString("<tr>")
+	for i, a := range align {
+		b.WriteString("<" + tag)
+		if a != "" {
+			b.WriteString(` style="text-align:` + a + `"`)
+		}
+		b.WriteString(">")
+		if i < len(row) {
+			writeSpansHTML(b, parseInline(row[i]))
+		}
+		b.WriteString("</" + tag + ">")
+	}
+	b.WriteString("</tr>\n")
+}
+
+// writeSpansHTML renders inline spans as HTML.
+func writeSpansHTML(b *strings.Builder, spans []span) {
+	for _, sp := range spans {
+		switch sp.kind {
+		case text:
+			b.WriteString(html.EscapeString(sp.text))
+		case code:
+			b.WriteString("<code>" + html.EscapeString(sp.text) + "</code>")
+		case strong:
+			b.WriteString("<strong>")
+			writeSpansHTML(b, sp.children)
+			b.WriteString("</strong>")
+		case emphasis:
+			b.WriteString("<em>")
+			writeSpansHTML(b, sp.children)
+			b.WriteString("</em>")
+		case strike:
+			b.WriteString("<del>")
+			writeSpansHTML(b, sp.children)
+			b.WriteString("</del>")
+		case link:
+			b.WriteString(`<a href="` + html.EscapeString(safeURL(sp.href)) + `"`)
+			if sp.title != "" {
+				b.WriteString(` title="` + html.EscapeString(sp.title) + `"`)
+			}
+			b.WriteString(">")
+			writeSpansHTML(b, sp.children)
+			b.WriteString("</a>")
+		case image:
+			var alt strings.Builder
+			writeSpansText(&alt, sp.children)
+			b.WriteString(`<img src="` + html.EscapeString(safeURL(sp.href)) + `" alt="` + html.EscapeString(alt.String()) + `"`)
+			if sp.title != "" {
+				b.WriteString(` title="` + html.EscapeString(sp.title) + `"`)
+			}
+			b.WriteString(">")
+		case lineBreak:
+			b.WriteString("<br>\n")
+		}
+	}
+}
+
+// safeURL returns href unless its scheme could run script, such as
+// javascript:, in which case it returns "#".
+func safeURL(href string) string {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return "#"
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto", "tel":
+		return href
+	default:
+		return "#"
+	}
+}
+
+// writeText renders blocks as plain text, starting lines with prefix. Blocks
+// of tight list items are not separated by blank lines.
+func writeText(b *strings.Builder, blocks []*block, prefix string, tight bool) {
+	for i, bl := range blocks {
+		if i > 0 && !tight && bl.kind != rule {
+			b.WriteString("\n")
+		}
+		switch bl.kind {
+		case paragraph, heading:
+			var t strings.Builder
+			writeSpansText(&t, parseInline(bl.text))
+			writeLines(b, t.String(), prefix)
+		case codeBlock:
+			writeLines(b, bl.text, prefix)
+		case quote:
+			writeText(b, bl.children, prefix+"    ", false)
+		case rule:
+			// The blank line before the next block is enough.
+		case list:
+			for n, it := range bl.items {
+				marker := "• "
+				if bl.ordered {
+					marker = strconv.Itoa(bl.start+n) + ". "
+				}
+				if it.task {
+					if it.checked {
+						marker += "[x] "
+					} else {
+						marker += "[ ] "
+					}
+				}
+				inner := prefix + strings.Repeat(" ", len([]rune(marker)))
+				var t strings.Builder
+				writeText(&t, it.blocks, inner, true)
+				if t.Len() == 0 {
+					b.WriteString(prefix + strings.TrimRight(marker, " ") + "\n")
+					continue
+				}
+				b.WriteString(prefix + marker + strings.TrimPrefix(t.String(), inner))
+			}
+		case table:
+			writeLines(b, cellsText(bl.header), prefix)
+			for _, row := range bl.rows {
+				writeLines(b, cellsText(row), prefix)
+			}
+		}
+	}
+}
+
+// writeLines writes each line of s after prefix.
+func writeLines(b *strings.Builder, s, prefix string) {
+	for line := range strings.SplitSeq(s, "\n") {
+		b.WriteString(prefix + line + "\n")
+	}
+}
+
+// cellsText renders the cells of a table row separated by tabs.
+func cellsText(row []string) string {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		var t strings.Builder
+		writeSpansText(&t, parseInline(cell))
+		cells[i] = t.String()
+	}
+	return strings.Join(cells, "\t")
+}
+
+// writeSpansText renders inline spans as plain text.
+func writeSpansText(b *strings.Builder, spans []span) {
+	for _, sp := range spans {
+		switch sp.kind {
+		case text, code:
+			b.WriteString(sp.text)
+		case strong, emphasis, strike, image:
+			writeSpansText(b, sp.children)
+		case link:
+			var t strings.Builder
+			writeSpansText(&t, sp.children)
+			b.WriteString(t.String())
+			if href := strings.TrimPrefix(sp.href, "mailto:"); href != t.String() {
+				b.WriteString(" (" + sp.href + ")")
+			}
+		case lineBreak:
+			b.WriteString("\n")
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Package merge combines concurrent edits of a board with a line-based
+// three-way merge.
+package merge
+
+import "strings"
+
+// maxCells bounds the size of the table used to match lines. Regions with
+// more changed lines are treated as changed as a whole.
+const maxCells = 1 << 22
+
+// Merge applies the changes made from base to ours and from base to theirs.
+// It reports false when both changed the same lines; the result then holds
+// both versions of those lines between conflict markers.
+func Merge(base, ours, theirs, oursLabel, theirsLabel string) (string, bool) {
+	// Compare last lines with their line break so that appending to a board
+	// without a final newline does not change its last line.
+	unterminated := !strings.HasSuffix(ours, "\n") && !strings.HasSuffix(theirs, "\n")
+	o, a, b := split(terminate(base)), split(terminate(ours)), split(terminate(theirs))
+	toA, toB := match(o, a), match(o, b)
+
+	var (
+		out   strings.Builder
+		clean = true
+	)
+	resolve := func(o, a, b []string) {
+		switch {
+		case equal(a, o):
+			writeLines(&out, b)
+		case equal(b, o), equal(a, b):
+			writeLines(&out, a)
+		default:
+			clean = false
+			writeConflict(&out, a, b, oursLabel, theirsLabel)
+		}
+	}
+
+	io, ia, ib := 0, 0, 0
+	for {
+		// Find the next base line kept by both sides.
+		j := io
+		for j < len(o) && (toA[j] < 0 || toB[j] < 0) {
+			j++
+		}
+		if j == len(o) {
+			resolve(o[io:], a[ia:], b[ib:])
+			break
+		}
+		ja, jb := toA[j], toB[j]
+		if j > io || ja > ia || jb > ib {
+			resolve(o[io:j], a[ia:ja], b[ib:jb])
+		}
+		out.WriteString(o[j])
+		io, ia, ib = j+1, ja+1, jb+1
+	}
+
+	result := out.String()
+	if unterminated && clean {
+		result = strings.TrimSuffix(result, "\n")
+	}
+	return result, clean
+}
+
+// Conflict joins two whole versions between conflict markers.
+func Conflict(ours, theirs, oursLabel, theirsLabel string) string {
+	var out strings.Builder
+	writeConflict(&out, split(ours), split(theirs), oursLabel, theirsLabel)
+	return out.String()
+}
+
+// writeConflict writes both versions of a region between conflict markers.
+func writeConflict(out *strings.Builder, a, b []string, oursLabel, theirsLabel string) {
+	out.WriteString("<<<<<<< " + oursLabel + "\n")
+	writeLines(out, a)
+	out.WriteString("=======\n")
+	writeLines(out, b)
+	out.WriteString(">>>>>>> " + theirsLabel + "\n")
+}
+
+// terminate ends non-empty s with a line break.
+func terminate(s string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		return s + "\n"
+	}
+	return s
+}
+
+// split splits s into lines, each keeping its line break.
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// writeLines writes lines, ending the last with a line break if it lacks one
+// so that a following marker starts on its own line.
+func writeLines(b *strings.Builder, lines []string) {
+	for _, line := range lines {
+		b.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+}
+
+// equal reports whether two slices of lines are identical.
+func equal(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns, for each line of o, the index of the line of a it is kept
+// as in a longest common subsequence, or -1 if it was removed.
+func match(o, a []string) []int {
+	m := make([]int, len(o))
+	for i := range m {
+		m[i] = -1
+	}
+
+	// Lines shared at both ends need no table.
+	prefix := 0
+	for prefix < len(o) && prefix < len(a) && o[prefix] == a[prefix] {
+		m[prefix] = prefix
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(o)-prefix && suffix < len(a)-prefix && o[len(o)-1-suffix] == a[len(a)-1-suffix] {
+		m[len(o)-1-suffix] = len(a) - 1 - suffix
+		suffix++
+	}
+
+	x, y := o[prefix:len(o)-suffix], a[prefix:len(a)-suffix]
+	if len(x) == 0 || len(y) == 0 || len(x)*len(y) > maxCells {
+		return m
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	cols := len(y) + 1
+	lcs := make([]int32, (len(x)+1)*cols)
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i*cols+j] = lcs[(i+1)*cols+j+1] + 1
+			} else {
+				lcs[i*cols+j] = max(lcs[(i+1)*cols+j], lcs[i*cols+j+1])
+			}
+		}
+	}
+	for i, j := 0, 0; i < len(x) && j < len(y); {
+		switch {
+		case x[i] == y[j]:
+			m[prefix+i] = prefix + j
+			i++
+			j++
+		case lcs[(i+1)*cols+j] >= lcs[i*cols+j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return m
+}
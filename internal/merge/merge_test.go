@@ -0,0 +1,54 @@
+package merge
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		ours   string
+		theirs string
+		want   string
+		clean  bool
+	}{
+		{"unchanged", "a\nb\n", "a\nb\n", "a\nb\n", "a\nb\n", true},
+		{"ours only", "a\nb\n", "a\nB\n", "a\nb\n", "a\nB\n", true},
+		{"theirs only", "a\nb\n", "a\nb\n", "A\nb\n", "A\nb\n", true},
+		{"separate lines", "a\nb\nc\n", "A\nb\nc\n", "a\nb\nC\n", "A\nb\nC\n", true},
+		{"same change", "a\nb\n", "a\nX\n", "a\nX\n", "a\nX\n", true},
+		{"both append", "a\n", "a\nb\n", "a\nc\n", "a\n<<<<<<< ours\nb\n=======\nc\n>>>>>>> theirs\n", false},
+		{"insert and delete elsewhere", "a\nb\nc\n", "a\nx\nb\nc\n", "a\nb\n", "a\nx\nb\n", true},
+		{"same line", "a\nb\nc\n", "a\nB1\nc\n", "a\nB2\nc\n", "a\n<<<<<<< ours\nB1\n=======\nB2\n>>>>>>> theirs\nc\n", false},
+		{"delete and edit", "a\nb\nc\n", "a\nc\n", "a\nB\nc\n", "a\n<<<<<<< ours\n=======\nB\n>>>>>>> theirs\nc\n", false},
+		{"empty base", "", "a\n", "", "a\n", true},
+		{"unterminated append", "a", "a\nb", "x\na", "x\na\nb", true},
+		{"unterminated kept when ours ends with a line break", "a", "a\nb\n", "x\na", "x\na\nb\n", true},
+		{"crlf lines", "a\r\nb\r\nc\r\n", "A\r\nb\r\nc\r\n", "a\r\nb\r\nC\r\n", "A\r\nb\r\nC\r\n", true},
+		{"adjacent lines", "a\nb\n", "A\nb\n", "a\nB\n", "<<<<<<< ours\nA\nb\n=======\na\nB\n>>>>>>> theirs\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clean := Merge(tt.base, tt.ours, tt.theirs, "ours", "theirs")
+			if got != tt.want || clean != tt.clean {
+				t.Errorf("Merge(%q, %q, %q) = %q, %v, want %q, %v", tt.base, tt.ours, tt.theirs, got, clean, tt.want, tt.clean)
+			}
+		})
+	}
+}
+
+func TestConflict(t *testing.T) {
+	tests := []struct {
+		ours   string
+		theirs string
+		want   string
+	}{
+		{"a\n", "b\n", "<<<<<<< local\na\n=======\nb\n>>>>>>> peer\n"},
+		{"a", "b", "<<<<<<< local\na\n=======\nb\n>>>>>>> peer\n"},
+		{"", "b\nc", "<<<<<<< local\n=======\nb\nc\n>>>>>>> peer\n"},
+	}
+	for _, tt := range tests {
+		if got := Conflict(tt.ours, tt.theirs, "local", "peer"); got != tt.want {
+			t.Errorf("Conflict(%q, %q) = %q, want %q", tt.ours, tt.theirs, got, tt.want)
+		}
+	}
+}
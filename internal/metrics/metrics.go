@@ -0,0 +1,161 @@
+// Package metrics provides Prometheus-compatible instrumentation for the boardcast application.
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics holds every metric exported by a boardcast server.
+type Metrics struct {
+	registry *Registry
+
+	Clients             *GaugeVec
+	MessagesReceived    *CounterVec
+	MessagesBroadcast   *CounterVec
+	BytesSent           *CounterVec
+	BroadcastsDropped   *CounterVec
+	MessagesRejected    *CounterVec
+	MessagesThrottled   *CounterVec
+	BroadcastsCoalesced *CounterVec
+	OfflineSyncs        *CounterVec
+	ReplicaConnected    *GaugeVec
+	ReplicaConflicts    *CounterVec
+	BoardWipes          *CounterVec
+	Logins              *CounterVec
+	Snapshots           *CounterVec
+	SnapshotDuration    *HistogramVec
+	HTTPRequests        *HistogramVec
+}
+
+// New creates and registers all boardcast metrics.
+func New() *Metrics {
+	r := NewRegistry()
+	return &Metrics{
+		registry: r,
+		Clients: r.NewGaugeVec("boardcast_websocket_clients",
+			"Number of connected WebSocket clients.", "board"),
+		MessagesReceived: r.NewCounterVec("boardcast_websocket_messages_received_total",
+			"WebSocket messages received from clients.", "board"),
+		MessagesBroadcast: r.NewCounterVec("boardcast_websocket_messages_broadcast_total",
+			"Messages broadcast to WebSocket clients.", "board"),
+		BytesSent: r.NewCounterVec("boardcast_websocket_bytes_sent_total",
+			"Payload bytes written to WebSocket clients.", "board"),
+		BroadcastsDropped: r.NewCounterVec("boardcast_websocket_broadcasts_dropped_total",
+			"Broadcasts dropped because the broadcast channel was full.", "board"),
+		MessagesRejected: r.NewCounterVec("boardcast_websocket_messages_rejected_total",
+			"WebSocket messages rejected by reason.", "board", "reason"),
+		MessagesThrottled: r.NewCounterVec("boardcast_websocket_messages_throttled_total",
+			"WebSocket updates refused by rate limits, by scope.", "board", "scope"),
+		BroadcastsCoalesced: r.NewCounterVec("boardcast_websocket_broadcasts_coalesced_total",
+			"Queued updates superseded by a later update before being broadcast.", "board"),
+		OfflineSyncs: r.NewCounterVec("boardcast_websocket_offline_syncs_total",
+			"Edits made offline by clients and synced on reconnect, by outcome.", "board", "outcome"),
+		ReplicaConnected: r.NewGaugeVec("boardcast_replica_connected",
+			"Whether the replica is connected to the server it replicates from.", "board"),
+		ReplicaConflicts: r.NewCounterVec("boardcast_replica_conflicts_total",
+			"Replication conflicts where both servers edited while disconnected.", "board"),
+		BoardWipes: r.NewCounterVec("boardcast_board_wipes_total",
+			"Board contents wiped because they expired or were read, by reason.", "board", "reason"),
+		Logins: r.NewCounterVec("boardcast_auth_logins_total",
+			"Login attempts by result.", "result"),
+		Snapshots: r.NewCounterVec("boardcast_snapshot_operations_total",
+			"Snapshot save and restore operations by result.", "operation", "result"),
+		SnapshotDuration: r.NewHistogramVec("boardcast_snapshot_duration_seconds",
+			"Latency of snapshot save and restore operations.", DefaultBuckets, "operation"),
+		HTTPRequests: r.NewHistogramVec("boardcast_http_request_duration_seconds",
+			"Latency of HTTP requests by route, method and status.", DefaultBuckets, "route", "method", "status"),
+	}
+}
+
+// ObserveSnapshot records the outcome and latency of a snapshot operation started at start.
+func (m *Metrics) ObserveSnapshot(operation string, start time.Time, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.Snapshots.Inc(operation, result)
+	m.SnapshotDuration.Observe(time.Since(start).Seconds(), operation)
+}
+
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.registry.WriteTo(w)
+	})
+}
+
+// Middleware records request latency labelled by the matched route with basePath removed.
+func (m *Metrics) Middleware(basePath string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := NewStatusRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		route := strings.TrimPrefix(r.Pattern, basePath)
+		if route == "" {
+			route = "unmatched"
+		}
+		m.HTTPRequests.Observe(time.Since(start).Seconds(), route, r.Method, strconv.Itoa(rec.Status()))
+	})
+}
+
+// StatusRecorder wraps a ResponseWriter to capture the response status.
+// It supports hijacking so WebSocket upgrades pass through.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// NewStatusRecorder wraps w.
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+// Status returns the status written, defaulting to 200.
+func (s *StatusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+// WriteHeader records the status before forwarding it.
+func (s *StatusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Write records an implicit 200 status before forwarding the data.
+func (s *StatusRecorder) Write(p []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(p)
+}
+
+// Hijack takes over the connection, recording a 101 status.
+func (s *StatusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(s.ResponseWriter).Hijack()
+	if err == nil && s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Flush forwards to the underlying writer when supported.
+func (s *StatusRecorder) Flush() {
+	http.NewResponseController(s.ResponseWriter).Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter for http.ResponseController.
+func (s *StatusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
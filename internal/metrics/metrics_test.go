@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/board/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	mux.Handle("/board/metrics", m.Handler())
+	handler := m.Middleware("/board", mux)
+
+	for _, path := range []string{"/board/items/1", "/board/items/2", "/elsewhere"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/board/metrics", nil))
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the Prometheus text format", got)
+	}
+
+	body, _ := io.ReadAll(w.Body)
+	// Routes are labelled by pattern without the base path, so requests
+	// for different items share a series.
+	for _, want := range []string{
+		"# TYPE boardcast_http_request_duration_seconds histogram\n",
+		`boardcast_http_request_duration_seconds_count{route="/items/{id}",method="GET",status="418"} 2` + "\n",
+		`boardcast_http_request_duration_seconds_count{route="unmatched",method="GET",status="404"} 1` + "\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestObserveSnapshot(t *testing.T) {
+	m := New()
+	m.ObserveSnapshot("save", time.Now(), nil)
+	m.ObserveSnapshot("save", time.Now(), errors.New("disk full"))
+
+	var b strings.Builder
+	m.registry.WriteTo(&b)
+	for _, want := range []string{
+		`boardcast_snapshot_operations_total{operation="save",result="success"} 1` + "\n",
+		`boardcast_snapshot_operations_total{operation="save",result="failure"} 1` + "\n",
+		`boardcast_snapshot_duration_seconds_count{operation="save"} 2` + "\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+}
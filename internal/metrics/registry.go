@@ -0,0 +1,297 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry holds metric families and renders them in the Prometheus text format.
+type Registry struct {
+	mu       sync.Mutex
+	families []family
+}
+
+// family is a named metric that can write its samples.
+type family interface {
+	write(w *bufio.Writer)
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// WriteTo writes every registered metric in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, f := range families {
+		f.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// register adds a family to the registry.
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.families = append(r.families, f)
+}
+
+// vec stores one value per combination of label values.
+type vec[T any] struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+	mu     sync.Mutex
+	series map[string]*series[T]
+}
+
+// series is a single labelled time series.
+type series[T any] struct {
+	labels []string
+	value  T
+}
+
+// newVec creates a vector with the given metadata.
+func newVec[T any](name, help, kind string, labels []string) *vec[T] {
+	return &vec[T]{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		labels: labels,
+		series: make(map[string]*series[T]),
+	}
+}
+
+// with runs fn on the series for the label values, creating it with init when missing.
+func (v *vec[T]) with(values []string, init func() T, fn func(*T)) {
+	if len(values) != len(v.labels) {
+		panic("metrics: wrong number of label values for " + v.name)
+	}
+
+	key := strings.Join(values, "\xff")
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.series[key]
+	if !ok {
+		s = &series[T]{labels: slices.Clone(values), value: init()}
+		v.series[key] = s
+	}
+	fn(&s.value)
+}
+
+// sorted returns a snapshot of all series ordered by label values.
+func (v *vec[T]) sorted(clone func(T) T) []series[T] {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	out := make([]series[T], 0, len(keys))
+	for _, key := range keys {
+		s := v.series[key]
+		out = append(out, series[T]{labels: s.labels, value: clone(s.value)})
+	}
+	return out
+}
+
+// writeHeader writes the HELP and TYPE lines.
+func (v *vec[T]) writeHeader(w *bufio.Writer) {
+	w.WriteString("# HELP " + v.name + " " + escapeHelp(v.help) + "\n")
+	w.WriteString("# TYPE " + v.name + " " + v.kind + "\n")
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels.
+type CounterVec struct {
+	*vec[float64]
+}
+
+// NewCounterVec registers a counter with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec[float64](name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// Inc increments the counter for the label values by one.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add increases the counter for the label values by delta.
+func (c *CounterVec) Add(delta float64, values ...string) {
+	c.with(values, zero, func(v *float64) { *v += delta })
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeScalars(w, c.vec)
+}
+
+// GaugeVec is a value that can go up and down, partitioned by labels.
+type GaugeVec struct {
+	*vec[float64]
+}
+
+// NewGaugeVec registers a gauge with the given label names.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec[float64](name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge for the label values.
+func (g *GaugeVec) Set(value float64, values ...string) {
+	g.with(values, zero, func(v *float64) { *v = value })
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	writeScalars(w, g.vec)
+}
+
+// histogram holds cumulative bucket counts for one series.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// HistogramVec samples observations into buckets, partitioned by labels.
+type HistogramVec struct {
+	*vec[histogram]
+	buckets []float64
+}
+
+// DefaultBuckets are latency buckets in seconds suited to HTTP and storage operations.
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// NewHistogramVec registers a histogram with the given upper bounds and label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{newVec[histogram](name, help, "histogram", labels), slices.Sorted(slices.Values(buckets))}
+	r.register(h)
+	return h
+}
+
+// Observe records a value for the label values.
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	init := func() histogram { return histogram{counts: make([]uint64, len(h.buckets))} }
+	h.with(values, init, func(hist *histogram) {
+		for i, upper := range h.buckets {
+			if value <= upper {
+				hist.counts[i]++
+			}
+		}
+		hist.sum += value
+		hist.count++
+	})
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	all := h.sorted(func(hist histogram) histogram {
+		hist.counts = slices.Clone(hist.counts)
+		return hist
+	})
+	if len(all) == 0 {
+		return
+	}
+
+	bucketLabels := slices.Concat(h.labels, []string{"le"})
+	h.writeHeader(w)
+	for _, s := range all {
+		for i, upper := range h.buckets {
+			labels := formatLabels(bucketLabels, slices.Concat(s.labels, []string{formatFloat(upper)}))
+			writeSample(w, h.name+"_bucket", labels, float64(s.value.counts[i]))
+		}
+		labels := formatLabels(bucketLabels, slices.Concat(s.labels, []string{"+Inf"}))
+		writeSample(w, h.name+"_bucket", labels, float64(s.value.count))
+
+		labels = formatLabels(h.labels, s.labels)
+		writeSample(w, h.name+"_sum", labels, s.value.sum)
+		writeSample(w, h.name+"_count", labels, float64(s.value.count))
+	}
+}
+
+// writeScalars writes a counter or gauge family.
+func writeScalars(w *bufio.Writer, v *vec[float64]) {
+	all := v.sorted(func(f float64) float64 { return f })
+	if len(all) == 0 {
+		return
+	}
+
+	v.writeHeader(w)
+	for _, s := range all {
+		writeSample(w, v.name, formatLabels(v.labels, s.labels), s.value)
+	}
+}
+
+// writeSample writes a single sample line.
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name + labels + " " + formatFloat(value) + "\n")
+}
+
+// formatLabels renders label pairs as {a="x",b="y"}.
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(name + `="` + escapeLabel(values[i]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// formatFloat renders a sample value.
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func zero() float64 { return 0 }
+
+// countingWriter counts bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
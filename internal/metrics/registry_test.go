@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	clients := r.NewGaugeVec("test_clients", "Connected clients.", "board")
+	messages := r.NewCounterVec("test_messages_total", "Messages by reason.\nSecond line with a \\.", "board", "reason")
+	r.NewCounterVec("test_unused_total", "Never incremented.")
+	latency := r.NewHistogramVec("test_duration_seconds", "Latency.", []float64{1, 0.5}, "route")
+
+	clients.Set(3, "default")
+	clients.Set(1, "default")
+	messages.Inc("default", "too_large")
+	messages.Add(2.5, "default", "too_large")
+	messages.Inc("b\"o\\a\nrd", "utf8")
+	latency.Observe(0.25, "/ws")
+	latency.Observe(0.75, "/ws")
+	latency.Observe(2, "/ws")
+	clients.Set(math.Inf(1), "other")
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP test_clients Connected clients.
+# TYPE test_clients gauge
+test_clients{board="default"} 1
+test_clients{board="other"} +Inf
+# HELP test_messages_total Messages by reason.\nSecond line with a \\.
+# TYPE test_messages_total counter
+test_messages_total{board="b\"o\\a\nrd",reason="utf8"} 1
+test_messages_total{board="default",reason="too_large"} 3.5
+# HELP test_duration_seconds Latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/ws",le="0.5"} 1
+test_duration_seconds_bucket{route="/ws",le="1"} 2
+test_duration_seconds_bucket{route="/ws",le="+Inf"} 3
+test_duration_seconds_sum{route="/ws"} 3
+test_duration_seconds_count{route="/ws"} 3
+`
+	if got := b.String(); got != want {
+		t.Errorf("WriteTo wrote:\n%s\nwant:\n%s", got, want)
+	}
+	if n != int64(len(want)) {
+		t.Errorf("WriteTo = %d, want %d", n, len(want))
+	}
+}
+
+func TestWrongLabelCount(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inc with a missing label value did not panic")
+		}
+	}()
+	NewRegistry().NewCounterVec("test_total", "Test.", "board").Inc()
+}
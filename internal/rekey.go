@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/server"
+)
+
+// storageKeys returns the keys encrypting data at rest, read from the file at
+// path or parsed from text, or nil if neither is given.
+func storageKeys(path, text string) (*server.Keyring, error) {
+	switch {
+	case path != "":
+		return server.ReadKeyring(path)
+	case text != "":
+		return server.ParseKeyring(text)
+	default:
+		return nil, nil
+	}
+}
+
+// Rekey runs the rekey command, which seals all stored snapshots and history
+// with the primary storage key. Older keys are only needed until it finishes.
+// The server should be stopped while it runs.
+func Rekey(args []string) error {
+	fs := flag.NewFlagSet("rekey", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: boardcast rekey [flags]")
+		fs.PrintDefaults()
+	}
+	var (
+		dataDir  = fs.String("data-dir", ".", "Directory for snapshots and attachments")
+		store    = fs.String("store", "file", "Snapshot storage: file (in the data directory) or redis (at -redis-url)")
+		redisURL = fs.String("redis-url", "", "Redis-protocol server holding the snapshots, e.g. redis://localhost:6379/0")
+		keyFile  = fs.String("storage-key-file", "", "File of id:hexkey lines whose first key is used (or set BOARDCAST_STORAGE_KEY)")
+	)
+	fs.Parse(args)
+
+	keys, err := storageKeys(*keyFile, os.Getenv("BOARDCAST_STORAGE_KEY"))
+	if err != nil {
+		return fmt.Errorf("invalid storage key: %w", err)
+	}
+	if keys == nil {
+		return errors.New("no storage key: use -storage-key-file or BOARDCAST_STORAGE_KEY")
+	}
+
+	var s storage.Encrypter
+	switch *store {
+	case "file":
+		s = storage.NewFileStore(*dataDir)
+	case "redis":
+		if *redisURL == "" {
+			return errors.New("redis requires -redis-url")
+		}
+		rs, err := storage.NewRedisStore(*redisURL)
+		if err != nil {
+			return err
+		}
+		defer rs.Close()
+		s = rs
+	default:
+		return fmt.Errorf("invalid store: %s (must be file or redis)", *store)
+	}
+
+	s.SetKeyring(keys)
+	n, err := s.Rekey()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Re-encrypted %d items with key %s\n", n, keys.Primary())
+	return nil
+}
@@ -0,0 +1,716 @@
+// Package replica keeps the board of one boardcast server in sync with the
+// board of another, tolerating long disconnections. Edits made on both sides
+// while disconnected are kept as a conflict instead of one overwriting the other.
+package replica
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	gorilla "github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/merge"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+const (
+	dialTimeout    = 10 * time.Second
+	readTimeout    = 90 * time.Second
+	maxRetryDelay  = 30 * time.Second
+	historyDelay   = 10 * time.Second
+	maxConflicts   = 100
+	requestTimeout = 30 * time.Second
+)
+
+// errPeerChanged is returned when the peer's board changed between reading
+// and writing it.
+var errPeerChanged = errors.New("peer content changed")
+
+// Options configures a Replicator.
+type Options struct {
+	// Peer is the base URL of the server to replicate from, including its
+	// base path, e.g. "https://office.example.com/board".
+	Peer string
+	// Token is the API token accepted by the peer.
+	Token string
+	Hub   *websocket.Hub
+	// StatePath is the file recording what both servers last agreed on, so
+	// that edits made while disconnected can be told apart after a restart.
+	StatePath string
+	Audit     audit.Log
+	// OnEvent, when set, is called for conflicts.
+	OnEvent func(audit.Entry)
+	Metrics *metrics.Metrics
+	Logger  *slog.Logger
+}
+
+// Conflict records both servers editing the board while disconnected. Each
+// side's content is kept as a version in the local history.
+type Conflict struct {
+	Time           time.Time `json:"time"`
+	LocalRevision  uint64    `json:"local_revision"`
+	RemoteRevision uint64    `json:"remote_revision"`
+	LocalVersion   string    `json:"local_version,omitempty"`
+	RemoteVersion  string    `json:"remote_version,omitempty"`
+}
+
+// Status describes the replication state.
+type Status struct {
+	Peer           string     `json:"peer"`
+	Connected      bool       `json:"connected"`
+	SyncedAt       time.Time  `json:"synced_at"`
+	RemoteRevision uint64     `json:"remote_revision"`
+	LocalRevision  uint64     `json:"local_revision"`
+	Conflicts      []Conflict `json:"conflicts"`
+}
+
+// Replicator mirrors the default board of a peer server into a local hub and
+// pushes local edits back.
+type Replicator struct {
+	hub     *websocket.Hub
+	peer    *url.URL
+	name    string
+	token   string
+	actor   audit.Actor
+	client  *http.Client
+	audit   audit.Log
+	onEvent func(audit.Entry)
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+	state   *stateFile
+
+	mu        sync.Mutex
+	connected bool
+	// synced is the content last exchanged with the peer; changes equal to
+	// it are echoes and are not sent again.
+	synced     string
+	hasPending bool
+	push       chan struct{}
+	// base is the content both servers last agreed on, if they agreed since
+	// the process started, and peerTag the ETag of the peer's content last
+	// seen, which local edits are sent against.
+	base    string
+	hasBase bool
+	peerTag string
+
+	// syncMu serializes applying changes from the peer with sending local
+	// edits to it.
+	syncMu sync.Mutex
+
+	historyMu    sync.Mutex
+	historyTimer *time.Timer
+
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+// New creates a replicator for opts. Call Start to begin replicating.
+func New(opts Options) (*Replicator, error) {
+	peer, err := url.Parse(strings.TrimSuffix(opts.Peer, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer URL: %w", err)
+	}
+	if peer.Scheme != "http" && peer.Scheme != "https" || peer.Host == "" {
+		return nil, fmt.Errorf("invalid peer URL %q: use http:// or https://", opts.Peer)
+	}
+
+	state, err := loadState(opts.StatePath, peer.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replication state: %w", err)
+	}
+
+	if opts.Audit == nil {
+		opts.Audit = audit.Discard
+	}
+	logger := opts.Logger.With("peer", peer.Host)
+	return &Replicator{
+		hub:     opts.Hub,
+		peer:    peer,
+		name:    peer.Host,
+		token:   opts.Token,
+		actor:   audit.Actor{User: "replica:" + peer.Host},
+		client:  &http.Client{Timeout: requestTimeout},
+		audit:   opts.Audit,
+		onEvent: opts.OnEvent,
+		metrics: opts.Metrics,
+		logger:  logger,
+		state:   state,
+		push:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Start watches the local board and connects to the peer in the background.
+func (r *Replicator) Start() {
+	r.hub.Watch(r.changed)
+	r.wg.Add(1)
+	go r.run()
+}
+
+// Close disconnects from the peer and saves the replication state.
+func (r *Replicator) Close() error {
+	r.once.Do(func() { close(r.stop) })
+	r.wg.Wait()
+
+	r.historyMu.Lock()
+	if r.historyTimer != nil {
+		r.historyTimer.Stop()
+	}
+	r.historyMu.Unlock()
+	return r.state.flush()
+}
+
+// Status returns the current replication state.
+func (r *Replicator) Status() Status {
+	r.mu.Lock()
+	connected := r.connected
+	r.mu.Unlock()
+
+	s := r.state.get()
+	conflicts := append([]Conflict{}, s.Conflicts...)
+	return Status{
+		Peer:           r.peer.String(),
+		Connected:      connected,
+		SyncedAt:       s.SyncedAt,
+		RemoteRevision: s.RemoteRevision,
+		LocalRevision:  s.LocalRevision,
+		Conflicts:      conflicts,
+	}
+}
+
+// run keeps a session with the peer open, reconnecting with backoff.
+func (r *Replicator) run() {
+	defer r.wg.Done()
+
+	delay := time.Second
+	for {
+		start := time.Now()
+		err := r.session()
+
+		select {
+		case <-r.stop:
+			return
+		default:
+		}
+
+		if time.Since(start) > maxRetryDelay {
+			delay = time.Second
+		}
+		r.logger.Warn("Replication disconnected, reconnecting", "error", err, "delay", delay)
+
+		select {
+		case <-time.After(delay):
+			delay = min(delay*2, maxRetryDelay)
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// session connects to the peer, reconciles both boards and then mirrors
+// changes in both directions until the connection fails.
+func (r *Replicator) session() error {
+	header := http.Header{}
+	if r.token != "" {
+		header.Set("Authorization", "Bearer "+r.token)
+	}
+	dialer := gorilla.Dialer{
+		HandshakeTimeout: dialTimeout,
+		Subprotocols:     []string{websocket.ProtocolV1},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	conn, resp, err := dialer.DialContext(ctx, r.endpoint("ws", "/ws"), header)
+	cancel()
+	if err != nil {
+		if resp != nil {
+			return fmt.Errorf("%w: %s", err, resp.Status)
+		}
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		// Closing the connection unblocks reads on shutdown.
+		select {
+		case <-r.stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	if conn.Subprotocol() != websocket.ProtocolV1 {
+		return errors.New("peer does not support " + websocket.ProtocolV1)
+	}
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		return conn.WriteControl(gorilla.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	frame, err := readFrame(conn)
+	if err != nil {
+		return err
+	}
+	if err := r.reconcile(frame.Content, frame.Revision); err != nil {
+		return err
+	}
+
+	r.setConnected(true)
+	defer r.setConnected(false)
+	r.logger.Info("Replication connected", "revision", frame.Revision)
+	// Edits made while reconciling were not queued.
+	r.changed(r.hub.GetState())
+
+	go r.pushLoop(conn, done)
+	r.scheduleHistory(0)
+
+	for {
+		frame, err := readFrame(conn)
+		if err != nil {
+			return err
+		}
+		switch frame.Type {
+		case websocket.FrameContent:
+			if err := r.receive(frame.Content, frame.Revision); err != nil {
+				return err
+			}
+			r.scheduleHistory(historyDelay)
+		case websocket.FrameError:
+			r.logger.Warn("Peer reported an error", "code", frame.Code, "message", frame.Message)
+		}
+	}
+}
+
+// readFrame reads the next ProtocolV1 frame from conn.
+func readFrame(conn *gorilla.Conn) (websocket.Frame, error) {
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	var frame websocket.Frame
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		return frame, err
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return frame, fmt.Errorf("invalid frame from peer: %w", err)
+	}
+	return frame, nil
+}
+
+// setConnected records whether a session is established.
+func (r *Replicator) setConnected(connected bool) {
+	r.mu.Lock()
+	r.connected = connected
+	r.hasPending = false
+	r.mu.Unlock()
+
+	value := 0.0
+	if connected {
+		value = 1
+	}
+	r.metrics.ReplicaConnected.Set(value, websocket.DefaultBoard)
+}
+
+// reconcile brings both boards in line after connecting or after both changed.
+// Revisions restart with each server process, so content digests against the
+// last agreed content decide which side changed; ETags make the push
+// conditional on the peer not changing meanwhile.
+func (r *Replicator) reconcile(remote string, remoteRevision uint64) error {
+	local, localRevision := r.hub.GetState()
+	base := r.state.get().Base
+
+	switch {
+	case local == remote:
+		r.agree(remote, remoteRevision, localRevision)
+		return nil
+	case websocket.Digest(local) == base:
+		r.logger.Info("Adopting changes made on peer while disconnected", "revision", remoteRevision)
+		return r.adopt(remote, remoteRevision)
+	case websocket.Digest(remote) == base:
+		r.logger.Info("Sending changes made while disconnected to peer", "revision", localRevision)
+		revision, err := r.send(local, websocket.ETag(remote, remoteRevision))
+		if err != nil {
+			return err
+		}
+		r.agree(local, revision, localRevision)
+		return nil
+	default:
+		merged, clean := r.merge(local, remote)
+		if clean && r.hub.Limits().Check(merged) == nil {
+			r.logger.Info("Merging changes made on both servers", "local_revision", localRevision, "remote_revision", remoteRevision)
+			return r.resolve(merged, remote, remoteRevision)
+		}
+		return r.conflict(local, localRevision, remote, remoteRevision, merged)
+	}
+}
+
+// merge combines the changes both servers made since they last agreed. Unless
+// that content is known, the whole versions conflict.
+func (r *Replicator) merge(local, remote string) (string, bool) {
+	r.mu.Lock()
+	base, known := r.base, r.hasBase && websocket.Digest(r.base) == r.state.get().Base
+	r.mu.Unlock()
+
+	if !known {
+		return merge.Conflict(local, remote, "local", r.name), false
+	}
+	return merge.Merge(base, local, remote, "local", r.name)
+}
+
+// resolve replaces the content on both servers with merged, provided the
+// peer still holds remote at remoteRevision.
+func (r *Replicator) resolve(merged, remote string, remoteRevision uint64) error {
+	r.mu.Lock()
+	r.synced = merged
+	r.mu.Unlock()
+
+	newLocal, err := r.hub.Mirror(r.actor, merged)
+	if err != nil {
+		return err
+	}
+	newRemote, err := r.send(merged, websocket.ETag(remote, remoteRevision))
+	if err != nil {
+		return err
+	}
+	r.agree(merged, newRemote, newLocal)
+	return nil
+}
+
+// adopt replaces the local content with content from the peer.
+func (r *Replicator) adopt(content string, remoteRevision uint64) error {
+	r.mu.Lock()
+	r.synced = content
+	r.mu.Unlock()
+
+	localRevision, err := r.hub.Mirror(r.actor, content)
+	if err != nil {
+		return err
+	}
+	r.agree(content, remoteRevision, localRevision)
+	return nil
+}
+
+// receive mirrors a change broadcast by the peer, reconciling it with local
+// edits not yet sent.
+func (r *Replicator) receive(content string, remoteRevision uint64) error {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	r.mu.Lock()
+	echo := content == r.synced
+	r.peerTag = websocket.ETag(content, remoteRevision)
+	r.mu.Unlock()
+
+	if echo || content == r.hub.GetContent() {
+		_, localRevision := r.hub.GetState()
+		r.agree(content, remoteRevision, localRevision)
+		return nil
+	}
+	return r.reconcile(content, remoteRevision)
+}
+
+// conflict keeps both sides' content in the history and replaces the board
+// on both servers with merged, which holds both versions of the conflicting
+// lines between conflict markers, so that someone resolves them by hand.
+func (r *Replicator) conflict(local string, localRevision uint64, remote string, remoteRevision uint64, merged string) error {
+	now := time.Now().UTC()
+	c := Conflict{Time: now, LocalRevision: localRevision, RemoteRevision: remoteRevision}
+	c.LocalVersion = r.keep(storage.Version{Revision: localRevision, Time: now, User: r.actor.User, Content: local})
+	c.RemoteVersion = r.keep(storage.Version{Revision: remoteRevision, Time: now.Add(time.Nanosecond), User: r.actor.User, Origin: r.name, Content: remote})
+
+	if err := r.hub.Limits().Check(merged); err != nil {
+		// Both versions are in the history; take the peer's rather than
+		// exceeding the board limit.
+		merged = remote
+	}
+
+	r.logger.Warn("Replication conflict: both servers edited the board while disconnected",
+		"local_revision", localRevision, "remote_revision", remoteRevision,
+		"local_version", c.LocalVersion, "remote_version", c.RemoteVersion)
+	r.metrics.ReplicaConflicts.Inc(websocket.DefaultBoard)
+	entry := audit.Entry{
+		Time:     now,
+		Action:   audit.ActionConflict,
+		Actor:    r.actor,
+		Board:    websocket.DefaultBoard,
+		Revision: localRevision,
+		Size:     len(merged),
+	}
+	if err := r.audit.Record(entry); err != nil {
+		r.logger.Error("Failed to write audit log", "action", audit.ActionConflict, "error", err)
+	}
+	if r.onEvent != nil {
+		r.onEvent(entry)
+	}
+	r.state.update(func(s *state) {
+		s.Conflicts = append(s.Conflicts, c)
+		if len(s.Conflicts) > maxConflicts {
+			s.Conflicts = s.Conflicts[len(s.Conflicts)-maxConflicts:]
+		}
+	})
+
+	return r.resolve(merged, remote, remoteRevision)
+}
+
+// keep adds v to the local history under a new ID and returns the ID, or ""
+// if the history is unavailable.
+func (r *Replicator) keep(v storage.Version) string {
+	v.ID = storage.NewVersionID(v.Time)
+	if _, err := r.hub.ImportVersion(v); err != nil {
+		r.logger.Warn("Failed to keep conflicting version", "error", err)
+		return ""
+	}
+	return v.ID
+}
+
+// agree records content as what both servers hold at the given revisions.
+func (r *Replicator) agree(content string, remoteRevision, localRevision uint64) {
+	r.mu.Lock()
+	r.synced = content
+	r.base, r.hasBase = content, true
+	r.peerTag = websocket.ETag(content, remoteRevision)
+	r.mu.Unlock()
+
+	r.state.update(func(s *state) {
+		s.Base = websocket.Digest(content)
+		s.RemoteRevision = remoteRevision
+		s.LocalRevision = localRevision
+		s.SyncedAt = time.Now().UTC()
+	})
+}
+
+// changed is called by the hub for every broadcast and queues local edits
+// for sending to the peer.
+func (r *Replicator) changed(content string, revision uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.connected || content == r.synced {
+		return
+	}
+	r.hasPending = true
+	select {
+	case r.push <- struct{}{}:
+	default:
+	}
+}
+
+// pushLoop sends queued local edits to the peer until done is closed. Edits
+// are sent against the peer content last seen: when the peer changed
+// meanwhile its change arrives as a frame and is reconciled with the edit. A
+// failed push closes conn so that the next session reconciles both boards.
+func (r *Replicator) pushLoop(conn *gorilla.Conn, done chan struct{}) {
+	for {
+		select {
+		case <-r.push:
+		case <-done:
+			return
+		}
+		if !r.pushPending(conn) {
+			return
+		}
+	}
+}
+
+// pushPending sends the local content if an edit is queued, and reports
+// whether the session can continue. The content is read again because a
+// change from the peer may have been merged into it since the edit.
+func (r *Replicator) pushPending(conn *gorilla.Conn) bool {
+	r.syncMu.Lock()
+	defer r.syncMu.Unlock()
+
+	content := r.hub.GetContent()
+	r.mu.Lock()
+	ok := r.hasPending && content != r.synced
+	match := r.peerTag
+	r.hasPending = false
+	if ok {
+		r.synced = content
+	}
+	r.mu.Unlock()
+	if !ok {
+		return true
+	}
+
+	revision, err := r.send(content, match)
+	if errors.Is(err, errPeerChanged) {
+		r.logger.Info("Peer changed before an edit was sent, reconciling")
+		return true
+	} else if err != nil {
+		r.logger.Warn("Failed to send edit to peer", "error", err)
+		conn.Close()
+		return false
+	}
+
+	r.mu.Lock()
+	current := r.synced == content
+	r.mu.Unlock()
+	if current {
+		_, localRevision := r.hub.GetState()
+		r.agree(content, revision, localRevision)
+	}
+	return true
+}
+
+// send replaces the peer's content and returns its new revision. The peer
+// only accepts the write while its content matches the If-Match value match.
+func (r *Replicator) send(content, match string) (uint64, error) {
+	req, err := http.NewRequest(http.MethodPut, r.endpoint("http", "/api/v1/boards/"+websocket.DefaultBoard+"/content"), strings.NewReader(content))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("If-Match", match)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := r.do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+	case http.StatusPreconditionFailed:
+		return 0, errPeerChanged
+	default:
+		return 0, fmt.Errorf("peer rejected update: %s", resp.Status)
+	}
+
+	revision, ok := websocket.ParseETag(resp.Header.Get("ETag"))
+	if !ok {
+		return 0, fmt.Errorf("peer returned invalid ETag %q", resp.Header.Get("ETag"))
+	}
+	return revision, nil
+}
+
+// scheduleHistory mirrors the peer's history after delay, postponing any
+// run already scheduled.
+func (r *Replicator) scheduleHistory(delay time.Duration) {
+	r.historyMu.Lock()
+	defer r.historyMu.Unlock()
+
+	if r.historyTimer == nil {
+		r.historyTimer = time.AfterFunc(delay, r.mirrorHistory)
+		return
+	}
+	r.historyTimer.Reset(delay)
+}
+
+// mirrorHistory copies versions recorded by the peer into the local history.
+func (r *Replicator) mirrorHistory() {
+	select {
+	case <-r.stop:
+		return
+	default:
+	}
+
+	var remote []storage.Version
+	if err := r.getJSON("/api/v1/boards/"+websocket.DefaultBoard+"/history", &remote); err != nil {
+		r.logger.Warn("Failed to list peer history", "error", err)
+		return
+	}
+	local, err := r.hub.Versions()
+	if err != nil {
+		r.logger.Debug("Local store keeps no history, skipping", "error", err)
+		return
+	}
+
+	known := make(map[string]bool, len(local))
+	for _, v := range local {
+		known[v.ID] = true
+	}
+	if len(remote) > storage.HistoryLimit {
+		remote = remote[len(remote)-storage.HistoryLimit:]
+	}
+
+	imported := 0
+	for _, v := range remote {
+		if known[v.ID] || !storage.ValidVersionID(v.ID) {
+			continue
+		}
+		if err := r.getJSON("/api/v1/boards/"+websocket.DefaultBoard+"/history/"+v.ID, &v); err != nil {
+			r.logger.Warn("Failed to fetch peer version", "version", v.ID, "error", err)
+			return
+		}
+		if r.duplicate(v, local) {
+			continue
+		}
+		if v.Origin == "" {
+			v.Origin = r.name
+		}
+		if ok, err := r.hub.ImportVersion(v); err != nil {
+			r.logger.Warn("Failed to import peer version", "version", v.ID, "error", err)
+			return
+		} else if ok {
+			imported++
+		}
+	}
+	if imported > 0 {
+		r.logger.Info("Mirrored peer history", "versions", imported)
+	}
+}
+
+// echoWindow bounds how far apart a local version and the peer's record of
+// the same edit may be.
+const echoWindow = time.Minute
+
+// duplicate reports whether v is the peer's record of an edit sent from here,
+// which the local history already holds.
+func (r *Replicator) duplicate(v storage.Version, local []storage.Version) bool {
+	for _, l := range local {
+		if l.Origin != "" || l.Size != len(v.Content) || l.Time.Sub(v.Time).Abs() > echoWindow {
+			continue
+		}
+		if own, err := r.hub.Version(l.ID); err == nil && own.Content == v.Content {
+			return true
+		}
+	}
+	return false
+}
+
+// getJSON decodes the response to a GET request for path on the peer.
+func (r *Replicator) getJSON(path string, v any) error {
+	req, err := http.NewRequest(http.MethodGet, r.endpoint("http", path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// do sends an authenticated request to the peer.
+func (r *Replicator) do(req *http.Request) (*http.Response, error) {
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	return r.client.Do(req)
+}
+
+// endpoint returns the URL of path on the peer, using a WebSocket scheme when
+// scheme is "ws".
+func (r *Replicator) endpoint(scheme, path string) string {
+	u := *r.peer
+	if scheme == "ws" {
+		u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	}
+	u.Path += path
+	return u.String()
+}
@@ -0,0 +1,108 @@
+package replica_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/replica"
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/websocket"
+	"github.com/yosebyte/boardcast/server"
+)
+
+// edit applies a line edit to the current content of hub.
+func edit(t *testing.T, hub *websocket.Hub, old, new string) {
+	t.Helper()
+	_, err := hub.Update(audit.Actor{}, nil, func(content string) (string, error) {
+		return strings.Replace(content, old, new, 1), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// request sends an API request to the peer at url, returning the response body.
+func request(t *testing.T, method, url, body string) string {
+	t.Helper()
+	req, err := http.NewRequest(method, url+"/api/v1/boards/default/content", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer tk")
+	req.Header.Set("If-Match", "*")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode >= 300 {
+		t.Fatalf("%s failed: %s %s %v", method, resp.Status, data, err)
+	}
+	return string(data)
+}
+
+// TestConcurrentEdits checks that edits made on both servers at the same time
+// are merged instead of one overwriting the other.
+func TestConcurrentEdits(t *testing.T) {
+	const base = "a\nb\nc\n"
+	peer, err := server.New(server.WithPassword("pw"), server.WithToken("tk"), server.WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(peer)
+	t.Cleanup(func() {
+		srv.Close()
+		peer.Shutdown(context.Background())
+	})
+	request(t, http.MethodPut, srv.URL, base)
+
+	hub := websocket.NewHub(websocket.Options{
+		Store:   storage.NewMemoryStore(),
+		Logger:  slog.New(slog.DiscardHandler),
+		Metrics: metrics.New(),
+		Audit:   audit.Discard,
+	})
+	hub.Start()
+	t.Cleanup(hub.Stop)
+	r, err := replica.New(replica.Options{
+		Peer:      srv.URL,
+		Token:     "tk",
+		Hub:       hub,
+		StatePath: filepath.Join(t.TempDir(), "replica.json"),
+		Metrics:   metrics.New(),
+		Logger:    slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Start()
+	t.Cleanup(func() { r.Close() })
+
+	waitFor(t, func() bool { return r.Status().Connected && hub.GetContent() == base })
+	edit(t, hub, "c", "C")
+	request(t, http.MethodPatch, srv.URL, `{"op":"replace-range","text":"A","start":0,"end":1}`)
+
+	const want = "A\nb\nC\n"
+	waitFor(t, func() bool { return hub.GetContent() == want && request(t, http.MethodGet, srv.URL, "") == want })
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,108 @@
+package replica
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// saveDelay batches state changes made in quick succession into one write.
+const saveDelay = time.Second
+
+// state is what both servers last agreed on, persisted across restarts.
+type state struct {
+	Peer string `json:"peer"`
+	// Base is the SHA-256 of the content both servers last held.
+	Base           string     `json:"base"`
+	RemoteRevision uint64     `json:"remote_revision"`
+	LocalRevision  uint64     `json:"local_revision"`
+	SyncedAt       time.Time  `json:"synced_at"`
+	Conflicts      []Conflict `json:"conflicts,omitempty"`
+}
+
+// stateFile holds the state and writes it to path shortly after each change.
+// An empty path keeps the state in memory only.
+type stateFile struct {
+	path  string
+	mu    sync.Mutex
+	state state
+	dirty bool
+	timer *time.Timer
+}
+
+// loadState reads the state for peer from path. State recorded for another
+// peer is discarded. Without a state both servers are assumed to have last
+// agreed on an empty board.
+func loadState(path, peer string) (*stateFile, error) {
+	f := &stateFile{path: path}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &f.state); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if f.state.Peer != peer {
+		f.state = state{Peer: peer, Base: websocket.Digest("")}
+	}
+	return f, nil
+}
+
+// get returns a copy of the state.
+func (f *stateFile) get() state {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.state
+}
+
+// update applies fn to the state and schedules a write.
+func (f *stateFile) update(fn func(*state)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fn(&f.state)
+	if f.path == "" {
+		return
+	}
+	f.dirty = true
+	if f.timer == nil {
+		f.timer = time.AfterFunc(saveDelay, func() { f.flush() })
+	} else {
+		f.timer.Reset(saveDelay)
+	}
+}
+
+// flush writes the state if it changed since the last write.
+func (f *stateFile) flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(f.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+	f.dirty = false
+	return nil
+}
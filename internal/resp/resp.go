@@ -0,0 +1,295 @@
+// Package resp implements a minimal client for servers speaking the Redis
+// serialization protocol (RESP2), such as Redis, Valkey or KeyDB.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error is an error reply sent by the server.
+type Error string
+
+// Error returns the server's error message.
+func (e Error) Error() string {
+	return string(e)
+}
+
+// ErrNil is returned by Client.Get when the key does not exist.
+var ErrNil = errors.New("resp: nil reply")
+
+// Options holds the settings parsed from a redis:// URL.
+type Options struct {
+	Addr     string
+	Username string
+	Password string
+	DB       int
+}
+
+// ParseURL parses a URL of the form redis://[[user]:password@]host[:port][/db].
+func ParseURL(rawURL string) (Options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Options{}, err
+	}
+	if u.Scheme != "redis" {
+		return Options{}, fmt.Errorf("unsupported scheme %q: use redis://", u.Scheme)
+	}
+
+	opts := Options{Addr: u.Host}
+	if u.Port() == "" {
+		opts.Addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		opts.Username = u.User.Username()
+		opts.Password, _ = u.User.Password()
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if opts.DB, err = strconv.Atoi(db); err != nil {
+			return Options{}, fmt.Errorf("invalid database %q", db)
+		}
+	}
+	return opts, nil
+}
+
+// Conn is a single connection to a RESP server. Send may be called while
+// another goroutine sends or receives, such as to ping a subscribed
+// connection; other uses are not safe for concurrent use.
+type Conn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	wmu  sync.Mutex
+	w    *bufio.Writer
+}
+
+// Dial connects to the server described by opts, authenticating and
+// selecting the database as needed.
+func Dial(ctx context.Context, opts Options) (*Conn, error) {
+	var d net.Dialer
+	nc, err := d.DialContext(ctx, "tcp", opts.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if opts.Password != "" {
+		args := []string{"AUTH", opts.Password}
+		if opts.Username != "" {
+			args = []string{"AUTH", opts.Username, opts.Password}
+		}
+		if _, err := c.Do(args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if opts.DB != 0 {
+		if _, err := c.Do("SELECT", strconv.Itoa(opts.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Do sends a command and returns its reply. Error replies are returned as Error.
+func (c *Conn) Do(args ...string) (any, error) {
+	if err := c.Send(args...); err != nil {
+		return nil, err
+	}
+	reply, err := c.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Send writes a command without waiting for its reply.
+func (c *Conn) Send(args ...string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	return c.w.Flush()
+}
+
+// Receive reads the next reply: a string, Error, int64, []byte, nil or []any.
+func (c *Conn) Receive() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("resp: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return Error(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, data); err != nil {
+			return nil, err
+		}
+		return data[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.Receive(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("resp: unknown reply type %q", kind)
+	}
+}
+
+// SetDeadline sets the read and write deadline of the connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// readOnly holds the commands that Client.Do retries even when the server may
+// have received them, as running them twice changes nothing.
+var readOnly = map[string]bool{
+	"GET":    true,
+	"LRANGE": true,
+	"PING":   true,
+	"SCAN":   true,
+	"TYPE":   true,
+}
+
+// Client issues commands over a shared connection, reconnecting after errors.
+// It is safe for concurrent use; commands are serialized.
+type Client struct {
+	opts    Options
+	timeout time.Duration
+	mu      sync.Mutex
+	conn    *Conn
+}
+
+// NewClient returns a client for the server at rawURL. No connection is made
+// until the first command.
+func NewClient(rawURL string) (*Client, error) {
+	opts, err := ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{opts: opts, timeout: 5 * time.Second}, nil
+}
+
+// Options returns the connection settings of the client.
+func (c *Client) Options() Options {
+	return c.opts
+}
+
+// Do sends a command and returns its reply, dialing a connection if needed.
+// A command failing on a reused connection, which the server may have closed
+// while idle, is retried once on a new connection if it was not sent or is
+// read-only: a command such as RPUSH may have run before the connection
+// failed, and running it again would repeat its effect.
+func (c *Client) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	reused := c.conn != nil
+	reply, sent, err := c.do(args)
+	var serverErr Error
+	if err != nil && reused && !errors.As(err, &serverErr) && (!sent || readOnly[strings.ToUpper(args[0])]) {
+		reply, _, err = c.do(args)
+	}
+	return reply, err
+}
+
+// do runs a single attempt of a command and reports whether the command was
+// written to the connection.
+func (c *Client) do(args []string) (reply any, sent bool, err error) {
+	if c.conn == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+		conn, err := Dial(ctx, c.opts)
+		cancel()
+		if err != nil {
+			return nil, false, err
+		}
+		c.conn = conn
+	}
+
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	if err = c.conn.Send(args...); err == nil {
+		sent = true
+		reply, err = c.conn.Receive()
+	}
+	if err != nil {
+		// The connection state is unknown after a network error.
+		c.conn.Close()
+		c.conn = nil
+		return nil, sent, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, true, e
+	}
+	return reply, true, nil
+}
+
+// Get returns the value of key, or ErrNil if it does not exist.
+func (c *Client) Get(key string) ([]byte, error) {
+	reply, err := c.Do("GET", key)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, ErrNil
+	}
+	return value, nil
+}
+
+// Set stores value under key.
+func (c *Client) Set(key string, value []byte) error {
+	_, err := c.Do("SET", key, string(value))
+	return err
+}
+
+// Close closes the client's connection.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,217 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testConn returns a connection reading input and writing to out.
+func testConn(input string, out *bytes.Buffer) *Conn {
+	return &Conn{r: bufio.NewReader(strings.NewReader(input)), w: bufio.NewWriter(out)}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no arguments", []string{"PING"}, "*1\r\n$4\r\nPING\r\n"},
+		{"arguments", []string{"SET", "k", "v"}, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n"},
+		{"empty argument", []string{"GET", ""}, "*2\r\n$3\r\nGET\r\n$0\r\n\r\n"},
+		{"binary argument", []string{"SET", "k", "a\r\nb"}, "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$4\r\na\r\nb\r\n"},
+		{"multibyte argument", []string{"ECHO", "é"}, "*2\r\n$4\r\nECHO\r\n$2\r\né\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := testConn("", &out).Send(tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("Send() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceive(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    any
+		wantErr bool
+	}{
+		{"simple string", "+OK\r\n", "OK", false},
+		{"error", "-ERR unknown\r\n", Error("ERR unknown"), false},
+		{"integer", ":-42\r\n", int64(-42), false},
+		{"bulk string", "$5\r\na\r\nbc\r\n", []byte("a\r\nbc"), false},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, false},
+		{"nil bulk string", "$-1\r\n", nil, false},
+		{"array", "*3\r\n$1\r\na\r\n:1\r\n*1\r\n+x\r\n", []any{[]byte("a"), int64(1), []any{"x"}}, false},
+		{"empty array", "*0\r\n", []any{}, false},
+		{"nil array", "*-1\r\n", nil, false},
+		{"missing carriage return", "+OK\n", nil, true},
+		{"unknown type", "?x\r\n", nil, true},
+		{"invalid integer", ":x\r\n", nil, true},
+		{"invalid length", "$x\r\n", nil, true},
+		{"truncated bulk string", "$5\r\nab", nil, true},
+		{"truncated array", "*2\r\n+a\r\n", nil, true},
+		{"empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := testConn(tt.input, nil).Receive()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Receive() error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Receive() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSendConcurrent checks that commands sent from several goroutines, as
+// the backplane pings a subscribed connection, are not interleaved.
+func TestSendConcurrent(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	c := &Conn{conn: client, r: bufio.NewReader(client), w: bufio.NewWriterSize(client, 16)}
+	s := &Conn{conn: server, r: bufio.NewReader(server), w: bufio.NewWriter(server)}
+
+	const senders, commands = 4, 50
+	var wg sync.WaitGroup
+	for range senders {
+		wg.Go(func() {
+			for range commands {
+				c.Send("PUBLISH", "channel", strings.Repeat("x", 100))
+			}
+		})
+	}
+	for i := range senders * commands {
+		cmd, err := s.Receive()
+		if err != nil {
+			t.Fatalf("command %d: %v", i, err)
+		}
+		if args, ok := cmd.([]any); !ok || len(args) != 3 {
+			t.Fatalf("command %d = %q, want PUBLISH", i, cmd)
+		}
+	}
+	wg.Wait()
+}
+
+func TestDoError(t *testing.T) {
+	_, err := testConn("-WRONGTYPE bad\r\n", &bytes.Buffer{}).Do("GET", "k")
+	if err != Error("WRONGTYPE bad") {
+		t.Errorf("Do() error = %v, want the server's error", err)
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		want    Options
+		wantErr bool
+	}{
+		{"redis://localhost", Options{Addr: "localhost:6379"}, false},
+		{"redis://:pw@host:6380/2", Options{Addr: "host:6380", Password: "pw", DB: 2}, false},
+		{"redis://user:pw@host/", Options{Addr: "host:6379", Username: "user", Password: "pw"}, false},
+		{"rediss://host", Options{}, true},
+		{"redis://host/db", Options{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			got, err := ParseURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURL() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseURL() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// idleServer is a RESP server that answers one command per connection with
+// +OK and then closes the connection, as a server does with idle clients.
+type idleServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	commands []string
+}
+
+func newIdleServer(t *testing.T) *idleServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &idleServer{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c := &Conn{conn: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+			if cmd, err := c.Receive(); err == nil {
+				name, _ := cmd.([]any)[0].([]byte)
+				s.mu.Lock()
+				s.commands = append(s.commands, string(name))
+				s.mu.Unlock()
+				c.w.WriteString("+OK\r\n")
+				c.w.Flush()
+			}
+			c.Close()
+		}
+	}()
+	return s
+}
+
+func (s *idleServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.commands
+}
+
+func TestClientRetry(t *testing.T) {
+	tests := []struct {
+		command string
+		wantErr bool
+		want    []string
+	}{
+		{"GET", false, []string{"PING", "GET"}},
+		{"LRANGE", false, []string{"PING", "LRANGE"}},
+		// The server may have run a write before the connection failed.
+		{"RPUSH", true, []string{"PING"}},
+		{"PUBLISH", true, []string{"PING"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			s := newIdleServer(t)
+			c, err := NewClient("redis://" + s.ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			if _, err := c.Do("PING"); err != nil {
+				t.Fatal(err)
+			}
+			// The server has closed the connection, which the client reuses.
+			if _, err := c.Do(tt.command, "k"); (err != nil) != tt.wantErr {
+				t.Fatalf("Do(%s) error = %v, want error %v", tt.command, err, tt.wantErr)
+			}
+			if got := s.received(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("server received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
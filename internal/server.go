@@ -3,106 +3,197 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
-	"github.com/yosebyte/boardcast/internal/auth"
 	"github.com/yosebyte/boardcast/internal/config"
-	"github.com/yosebyte/boardcast/internal/handler"
-	"github.com/yosebyte/boardcast/internal/websocket"
+	"github.com/yosebyte/boardcast/server"
 )
 
 // Server represents the main application server.
 type Server struct {
-	config   *config.Config
-	auth     *auth.Manager
-	wsHub    *websocket.Hub
-	handlers *handler.Handlers
-	server   *http.Server
+	config *config.Config
+	app    *server.Server
+	server *http.Server
+	logger *slog.Logger
+	// closers release shared resources once the server has stopped.
+	closers []io.Closer
 }
 
 // NewServer creates a new server instance with the given configuration.
 func NewServer(cfg *config.Config) (*Server, error) {
-	// Initialize authentication manager
-	authManager, err := auth.NewManager(cfg.Password)
+	logger := cfg.NewLogger()
+	slog.SetDefault(logger)
+
+	opts := []server.Option{
+		server.WithPassword(cfg.Password),
+		server.WithToken(cfg.Token),
+		server.WithAttachments(filepath.Join(cfg.DataDir, "attachments"), cfg.MaxAttachment),
+		server.WithMaxMessageSize(cfg.MaxMessage),
+		server.WithMaxBoardSize(cfg.MaxBoard),
+		server.WithRateLimits(cfg.RateLimit, cfg.UserRateLimit, cfg.RateBurst),
+		server.WithBroadcastTick(cfg.BroadcastTick),
+		server.WithVersion(cfg.Version),
+		server.WithBasePath(cfg.BasePath),
+		server.WithLogger(logger),
+		server.WithSessionKey(cfg.SessionKey),
+	}
+
+	var closers []io.Closer
+	if cfg.Store == "redis" {
+		store, err := server.NewRedisStore(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create redis store: %w", err)
+		}
+		closers = append(closers, store.(io.Closer))
+		opts = append(opts, server.WithStore(store))
+	} else {
+		opts = append(opts, server.WithStore(server.NewFileStore(cfg.DataDir)))
+	}
+
+	keys, err := storageKeys(cfg.KeyFile, cfg.StorageKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create auth manager: %w", err)
+		return nil, fmt.Errorf("invalid storage key: %w", err)
+	}
+	if keys != nil {
+		opts = append(opts, server.WithStorageKeys(keys))
+	}
+
+	if cfg.RedisURL != "" {
+		bp, err := server.NewRedisBackplane(cfg.RedisURL, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backplane: %w", err)
+		}
+		closers = append(closers, bp)
+		opts = append(opts, server.WithBackplane(bp))
+	}
+
+	if cfg.E2E {
+		opts = append(opts, server.WithEndToEndEncryption())
+	}
+	if cfg.BoardTTL > 0 || cfg.BurnAfterRead {
+		opts = append(opts, server.WithExpiry(cfg.BoardTTL, cfg.BurnAfterRead))
+	}
+
+	if cfg.ReplicateFrom != "" {
+		opts = append(opts, server.WithReplication(cfg.ReplicateFrom, cfg.ReplicaToken, filepath.Join(cfg.DataDir, "replica-state.json")))
+	}
+
+	if cfg.Compression {
+		opts = append(opts, server.WithCompression(cfg.CompressLevel, cfg.CompressMin))
+	} else {
+		opts = append(opts, server.WithoutCompression())
 	}
 
-	// Initialize WebSocket hub
-	wsHub := websocket.NewHub()
+	if cfg.AuditLog != "" {
+		auditLog, err := server.NewFileAuditLog(cfg.AuditLog)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		opts = append(opts, server.WithAuditLog(auditLog))
+	}
 
-	// Initialize handlers
-	handlers := handler.New(authManager, wsHub, cfg.Version)
+	if cfg.Webhooks != "" {
+		hooks, err := server.LoadWebhooks(cfg.Webhooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load webhooks: %w", err)
+		}
+		opts = append(opts, server.WithWebhooks(hooks))
+	}
+
+	if cfg.InboundHooks != "" {
+		hooks, err := server.LoadInboundWebhooks(cfg.InboundHooks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load inbound webhooks: %w", err)
+		}
+		opts = append(opts, server.WithInboundWebhooks(hooks))
+	}
+
+	// Create the boardcast handler
+	app, err := server.New(opts...)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create HTTP server
-	server := &http.Server{
+	httpServer := &http.Server{
 		Addr:         cfg.GetAddr(),
+		Handler:      app,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 30 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		ErrorLog:     slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
 	}
 
-	s := &Server{
-		config:   cfg,
-		auth:     authManager,
-		wsHub:    wsHub,
-		handlers: handlers,
-		server:   server,
-	}
-
-	// Register routes
-	s.registerRoutes()
-
-	return s, nil
+	return &Server{
+		config:  cfg,
+		app:     app,
+		server:  httpServer,
+		logger:  logger,
+		closers: closers,
+	}, nil
 }
 
-// Start starts the WebSocket hub and HTTP server with graceful shutdown.
+// Start starts the HTTP server and blocks until it is shut down gracefully.
 func (s *Server) Start() error {
-	// Start WebSocket hub
-	s.wsHub.Start()
-
 	// Channel to listen for interrupt signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
 	// Start server in a goroutine
 	go func() {
-		log.Printf("Server started: %s | Password: %s", s.server.Addr, s.config.Password)
+		s.logger.Info("Server started", "addr", s.server.Addr, "password", s.config.Password, "version", s.config.Version)
 		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			s.logger.Error("Server failed to start", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	// Wait for interrupt signal
 	<-stop
-	log.Println("Shutting down server...")
+	s.logger.Info("Shutting down server")
+
+	// Fail readiness first so load balancers stop routing traffic
+	s.app.Drain()
+	time.Sleep(s.config.ShutdownDelay)
 
 	// Create context with timeout for graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	err := s.shutdown(ctx)
+	s.logger.Info("Server stopped")
+	return err
+}
+
+// shutdown stops the application and the HTTP server and releases shared
+// resources. Every step runs even if an earlier one fails.
+func (s *Server) shutdown(ctx context.Context) error {
+	var errs []error
+
+	// Persist content and close WebSocket clients, which the HTTP server does not track
+	if err := s.app.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("hub shutdown failed: %w", err))
+	}
+
 	// Shutdown server gracefully
 	if err := s.server.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown failed: %w", err)
+		errs = append(errs, fmt.Errorf("server shutdown failed: %w", err))
 	}
 
-	log.Println("Server stopped")
-	return nil
-}
+	for _, closer := range s.closers {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close: %w", err))
+		}
+	}
 
-// registerRoutes sets up all HTTP routes.
-func (s *Server) registerRoutes() {
-	http.HandleFunc("/", s.handlers.ServeWhiteboard)
-	http.HandleFunc("/auth", s.handlers.HandleAuth)
-	http.HandleFunc("/logout", s.handlers.HandleLogout)
-	http.HandleFunc("/ws", s.handlers.HandleWebSocket)
-	http.HandleFunc("/content", s.handlers.HandleContent)
-	http.HandleFunc("/save", s.handlers.HandleSave)
-	http.HandleFunc("/restore", s.handlers.HandleRestore)
+	return errors.Join(errs...)
 }
@@ -0,0 +1,68 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/server"
+)
+
+var errSave = errors.New("disk full")
+
+// failingStore is a store that cannot save snapshots.
+type failingStore struct{ storage.Store }
+
+func (failingStore) SaveSnapshot(string, []byte) error { return errSave }
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+func TestShutdownRunsEveryStep(t *testing.T) {
+	app, err := server.New(
+		server.WithPassword("pw"),
+		server.WithToken("tk"),
+		server.WithStore(failingStore{storage.NewMemoryStore()}),
+		server.WithLogger(slog.New(slog.DiscardHandler)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave unsaved content so that shutting the app down fails.
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/boards/default/content", strings.NewReader(`{"op":"append","text":"unsaved"}`))
+	req.Header.Set("Authorization", "Bearer tk")
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("PATCH = %d: %s", w.Code, w.Body)
+	}
+
+	closed := 0
+	errClose := errors.New("connection reset")
+	s := &Server{
+		app:    app,
+		server: &http.Server{},
+		logger: slog.New(slog.DiscardHandler),
+		closers: []io.Closer{
+			closerFunc(func() error { closed++; return errClose }),
+			closerFunc(func() error { closed++; return nil }),
+		},
+	}
+
+	err = s.shutdown(context.Background())
+	if !errors.Is(err, errSave) || !errors.Is(err, errClose) {
+		t.Errorf("shutdown = %v, want both failures", err)
+	}
+	if closed != 2 {
+		t.Errorf("closed %d resources, want 2", closed)
+	}
+}
@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/yosebyte/boardcast/internal/resp"
+)
+
+// sealedMagic starts data sealed with a Keyring. It is followed by the length
+// of the key ID, the key ID, the nonce and the ciphertext.
+const sealedMagic = "\x00bce1"
+
+// plainMagic starts data written without a Keyring that would otherwise
+// begin like sealed data, or like data marked this way. It is removed when
+// the data is read.
+const plainMagic = "\x00bcp1"
+
+// Keyring errors.
+var (
+	ErrUnknownKey = errors.New("data is encrypted with an unknown key")
+	ErrNoKeyring  = errors.New("data is encrypted but no storage key is configured")
+	ErrDecrypt    = errors.New("failed to decrypt data")
+)
+
+// IsKeyError reports whether err means stored data cannot be decrypted with
+// the configured keys.
+func IsKeyError(err error) bool {
+	return errors.Is(err, ErrUnknownKey) || errors.Is(err, ErrNoKeyring) || errors.Is(err, ErrDecrypt)
+}
+
+// keyIDPattern matches key IDs.
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+
+// Keyring holds the AES-256-GCM keys encrypting snapshots and history at
+// rest. Data is sealed with the primary key and records its key ID, so older
+// keys can be kept to open data sealed before a rotation.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseKeyring parses keys given one per line as "id:hexkey", where each key
+// is 32 hex-encoded bytes. The first key is the primary key; blank lines and
+// lines starting with # are ignored. Keys may also be separated by commas.
+func ParseKeyring(text string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	scanner := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(text, ",", "\n")))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(line, ":")
+		if !ok || !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid storage key %q: must be id:hexkey", id)
+		}
+		if _, dup := k.keys[id]; dup {
+			return nil, fmt.Errorf("duplicate storage key ID %q", id)
+		}
+		key, err := hex.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid storage key %q: must be 32 hex-encoded bytes", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		k.keys[id] = aead
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if k.primary == "" {
+		return nil, errors.New("no storage key given")
+	}
+	return k, nil
+}
+
+// ReadKeyring parses the keys in the file at path.
+func ReadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyring(string(data))
+}
+
+// Primary returns the ID of the key sealing new data.
+func (k *Keyring) Primary() string {
+	if k == nil {
+		return ""
+	}
+	return k.primary
+}
+
+// seal encrypts data with the primary key. A nil keyring leaves data as is,
+// except that content looking like sealed data is marked as plain text.
+func (k *Keyring) seal(data []byte) []byte {
+	if k == nil {
+		if bytes.HasPrefix(data, []byte(sealedMagic)) || bytes.HasPrefix(data, []byte(plainMagic)) {
+			return append([]byte(plainMagic), data...)
+		}
+		return data
+	}
+	aead := k.keys[k.primary]
+	header := sealedHeader(k.primary)
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	out := make([]byte, 0, len(header)+len(nonce)+len(data)+aead.Overhead())
+	out = append(append(out, header...), nonce...)
+	// The header is authenticated so that the key ID cannot be swapped.
+	return aead.Seal(out, nonce, data, header)
+}
+
+// open decrypts data sealed by seal. Data that was never sealed, such as files
+// written before encryption was enabled, is returned as is.
+func (k *Keyring) open(data []byte) ([]byte, error) {
+	if plain, ok := bytes.CutPrefix(data, []byte(plainMagic)); ok {
+		return plain, nil
+	}
+	id, ok := sealedKeyID(data)
+	if !ok {
+		return data, nil
+	}
+	if k == nil {
+		return nil, ErrNoKeyring
+	}
+	aead, found := k.keys[id]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, id)
+	}
+
+	header := sealedHeader(id)
+	rest := data[len(header):]
+	if len(rest) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	plain, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], header)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plain, nil
+}
+
+// reseal returns data sealed with the primary key and reports whether it had
+// to be rewritten.
+func (k *Keyring) reseal(data []byte) ([]byte, bool, error) {
+	if id, ok := sealedKeyID(data); ok && id == k.primary {
+		return data, false, nil
+	}
+	plain, err := k.open(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return k.seal(plain), true, nil
+}
+
+// sealedHeader returns the header of data sealed with key id.
+func sealedHeader(id string) []byte {
+	return append(append([]byte(sealedMagic), byte(len(id))), id...)
+}
+
+// sealedKeyID returns the key ID in the header of sealed data.
+func sealedKeyID(data []byte) (string, bool) {
+	if !bytes.HasPrefix(data, []byte(sealedMagic)) || len(data) <= len(sealedMagic) {
+		return "", false
+	}
+	n := int(data[len(sealedMagic)])
+	start := len(sealedMagic) + 1
+	if n == 0 || len(data) < start+n {
+		return "", false
+	}
+	return string(data[start : start+n]), true
+}
+
+// Encrypter is implemented by stores that can encrypt data at rest.
+type Encrypter interface {
+	// SetKeyring makes the store seal snapshots and history with keys.
+	SetKeyring(keys *Keyring)
+	// Rekey seals all stored snapshots and history with the primary key,
+	// returning how many items were rewritten.
+	Rekey() (int, error)
+}
+
+// Encrypt makes store seal its data with keys, failing if it cannot.
+func Encrypt(store Store, keys *Keyring) error {
+	encrypter, ok := store.(Encrypter)
+	if !ok {
+		return fmt.Errorf("%T does not support encryption at rest", store)
+	}
+	encrypter.SetKeyring(keys)
+	return nil
+}
+
+// errRekeyWithoutKey is returned when rekeying a store without a keyring.
+var errRekeyWithoutKey = errors.New("rekeying requires a storage key")
+
+// SetKeyring makes the store seal the files it writes with keys. It must be
+// called before the store is used.
+func (s *FileStore) SetKeyring(keys *Keyring) {
+	s.keys = keys
+}
+
+// Rekey rewrites the snapshot files and version files not yet sealed with the
+// primary key. The server should not be running against the same directory.
+func (s *FileStore) Rekey() (int, error) {
+	if s.keys == nil {
+		return 0, errRekeyWithoutKey
+	}
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	snapshots, err := filepath.Glob(filepath.Join(s.dir, "boardcast*.txt"))
+	if err != nil {
+		return 0, err
+	}
+	versions, err := filepath.Glob(filepath.Join(s.dir, "history", "*", "*.txt"))
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, path := range append(snapshots, versions...) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return n, err
+		}
+		sealed, changed, err := s.keys.reseal(data)
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", path, err)
+		}
+		if !changed {
+			continue
+		}
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, sealed, 0644); err != nil {
+			return n, err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// SetKeyring makes the store seal the values it writes with keys. It must be
+// called before the store is used.
+func (s *RedisStore) SetKeyring(keys *Keyring) {
+	s.keys = keys
+}
+
+// Rekey rewrites the snapshots and version contents not yet sealed with the
+// primary key.
+func (s *RedisStore) Rekey() (int, error) {
+	if s.keys == nil {
+		return 0, errRekeyWithoutKey
+	}
+
+	n := 0
+	for _, pattern := range []string{redisKeyPrefix + "*", redisHistoryPrefix + "*"} {
+		keys, err := s.scan(pattern)
+		if err != nil {
+			return n, err
+		}
+		for _, key := range keys {
+			// History lists hold metadata only.
+			if kind, err := s.client.Do("TYPE", key); err != nil {
+				return n, err
+			} else if kind != "string" {
+				continue
+			}
+
+			data, err := s.client.Get(key)
+			if errors.Is(err, resp.ErrNil) {
+				continue
+			} else if err != nil {
+				return n, err
+			}
+			sealed, changed, err := s.keys.reseal(data)
+			if err != nil {
+				return n, fmt.Errorf("%s: %w", key, err)
+			}
+			if !changed {
+				continue
+			}
+			if err := s.client.Set(key, sealed); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// scan returns the keys matching pattern.
+func (s *RedisStore) scan(pattern string) ([]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := s.client.Do("SCAN", cursor, "MATCH", pattern, "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		parts, _ := reply.([]any)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply: %v", reply)
+		}
+		next, _ := parts[0].([]byte)
+		items, _ := parts[1].([]any)
+		for _, item := range items {
+			if key, ok := item.([]byte); ok {
+				keys = append(keys, string(key))
+			}
+		}
+		if cursor = string(next); cursor == "0" || cursor == "" {
+			return keys, nil
+		}
+	}
+}
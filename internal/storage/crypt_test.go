@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKeyring parses the keys "id:hexkey" with each key made of one repeated
+// byte, such as "k1:11".
+func testKeyring(t *testing.T, keys ...string) *Keyring {
+	t.Helper()
+	var lines []string
+	for _, key := range keys {
+		id, b, _ := strings.Cut(key, ":")
+		lines = append(lines, id+":"+strings.Repeat(b, 32))
+	}
+	k, err := ParseKeyring(strings.Join(lines, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestParseKeyring(t *testing.T) {
+	key := strings.Repeat("ab", 32)
+	tests := []struct {
+		name    string
+		text    string
+		primary string
+		wantErr bool
+	}{
+		{"one key", "k1:" + key, "k1", false},
+		{"first key is primary", "# keys\nk2:" + key + "\n\nk1:" + key + "\n", "k2", false},
+		{"comma separated", "k2:" + key + ",k1:" + key, "k2", false},
+		{"empty", "# none\n", "", true},
+		{"missing id", key, "", true},
+		{"invalid id", "k/1:" + key, "", true},
+		{"short key", "k1:abcd", "", true},
+		{"not hex", "k1:" + strings.Repeat("zz", 32), "", true},
+		{"duplicate id", "k1:" + key + "\nk1:" + key, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k, err := ParseKeyring(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyring() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := k.Primary(); got != tt.primary {
+				t.Errorf("Primary() = %q, want %q", got, tt.primary)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	k1 := testKeyring(t, "k1:11")
+	k2 := testKeyring(t, "k2:22", "k1:11")
+
+	// Board content is free text, so it can start like sealed data.
+	fake := sealedMagic + "\x02k9" + strings.Repeat("x", 40)
+	inputs := []string{"", "hello", sealedMagic, fake, plainMagic + "hello", "\x00bce", "\x00"}
+
+	tests := []struct {
+		name  string
+		write *Keyring
+		read  *Keyring
+	}{
+		{"no keyring", nil, nil},
+		{"keyring", k1, k1},
+		{"older key", k1, k2},
+		{"plain data read with a keyring", nil, k1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, in := range inputs {
+				sealed := tt.write.seal([]byte(in))
+				if tt.write != nil && bytes.Contains(sealed, []byte("hello")) {
+					t.Errorf("seal(%q) = %q, holds the plain text", in, sealed)
+				}
+				got, err := tt.read.open(sealed)
+				if err != nil {
+					t.Errorf("open(seal(%q)) error = %v", in, err)
+				} else if string(got) != in {
+					t.Errorf("open(seal(%q)) = %q", in, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	k1 := testKeyring(t, "k1:11")
+	k2 := testKeyring(t, "k2:22")
+	sealed := k1.seal([]byte("secret"))
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+	// Swapping the key ID in the header fails authentication.
+	swapped := append(sealedHeader("k2"), sealed[len(sealedHeader("k1")):]...)
+
+	tests := []struct {
+		name string
+		keys *Keyring
+		data []byte
+		want error
+	}{
+		{"no keyring", nil, sealed, ErrNoKeyring},
+		{"unknown key", k2, sealed, ErrUnknownKey},
+		{"tampered", k1, tampered, ErrDecrypt},
+		{"truncated", k1, sealed[:len(sealedHeader("k1"))+4], ErrDecrypt},
+		{"swapped key ID", testKeyring(t, "k1:11", "k2:11"), swapped, ErrDecrypt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.keys.open(tt.data)
+			if !errors.Is(err, tt.want) || !IsKeyError(err) {
+				t.Errorf("open() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileStoreRekey(t *testing.T) {
+	dir := t.TempDir()
+	fake := sealedMagic + "\x02k9" + strings.Repeat("x", 40)
+
+	// Data written before encryption was enabled, then with k1.
+	plain := NewFileStore(dir)
+	if err := plain.SaveSnapshot("default", []byte(fake)); err != nil {
+		t.Fatal(err)
+	}
+	if err := plain.AppendVersion("default", Version{ID: "1", Content: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := plain.LoadSnapshot("default"); err != nil || string(got) != fake {
+		t.Fatalf("LoadSnapshot() = %q, %v, want %q", got, err, fake)
+	}
+	old := NewFileStore(dir)
+	old.SetKeyring(testKeyring(t, "k1:11"))
+	if err := old.AppendVersion("default", Version{ID: "2", Content: "second"}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewFileStore(dir)
+	store.SetKeyring(testKeyring(t, "k2:22", "k1:11"))
+	for i, want := range []int{3, 0} {
+		n, err := store.Rekey()
+		if err != nil || n != want {
+			t.Fatalf("Rekey() #%d = %d, %v, want %d", i+1, n, err, want)
+		}
+	}
+
+	rotated := NewFileStore(dir)
+	rotated.SetKeyring(testKeyring(t, "k2:22"))
+	if got, err := rotated.LoadSnapshot("default"); err != nil || string(got) != fake {
+		t.Errorf("LoadSnapshot() = %q, %v, want %q", got, err, fake)
+	}
+	for id, want := range map[string]string{"1": "first", "2": "second"} {
+		if v, err := rotated.LoadVersion("default", id); err != nil || v.Content != want {
+			t.Errorf("LoadVersion(%s) = %q, %v, want %q", id, v.Content, err, want)
+		}
+	}
+	if _, err := NewFileStore(dir).LoadSnapshot("default"); !errors.Is(err, ErrNoKeyring) {
+		t.Errorf("LoadSnapshot() without keys error = %v, want %v", err, ErrNoKeyring)
+	}
+}
@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/resp"
+)
+
+// Expiry controls when the content of a board is wiped.
+type Expiry struct {
+	// TTL wipes the content this long after the last edit. Zero keeps it.
+	TTL time.Duration `json:"ttl"`
+	// BurnAfterReading wipes the content once someone other than its
+	// author has viewed it.
+	BurnAfterReading bool `json:"burn_after_reading"`
+}
+
+// Settings is implemented by stores that keep the expiry of boards.
+type Settings interface {
+	// SaveExpiry stores the expiry of board.
+	SaveExpiry(board string, e Expiry) error
+	// LoadExpiry returns the expiry of board or ErrNotFound if none was saved.
+	LoadExpiry(board string) (Expiry, error)
+}
+
+// SettingsOf returns the settings of store, or nil if it keeps none.
+func SettingsOf(store Store) Settings {
+	settings, _ := store.(Settings)
+	return settings
+}
+
+// Purger is implemented by stores that can delete everything they keep for
+// a board.
+type Purger interface {
+	// Purge deletes the snapshot and history of board.
+	Purge(board string) error
+}
+
+// Purge deletes the snapshot and history of board if store supports it.
+func Purge(store Store, board string) error {
+	if purger, ok := store.(Purger); ok {
+		return purger.Purge(board)
+	}
+	return nil
+}
+
+// SaveExpiry writes the expiry of board to its settings file.
+func (s *FileStore) SaveExpiry(board string, e Expiry) error {
+	path := s.settingsPath(board)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadExpiry reads the expiry of board from its settings file.
+func (s *FileStore) LoadExpiry(board string) (Expiry, error) {
+	data, err := os.ReadFile(s.settingsPath(board))
+	if errors.Is(err, os.ErrNotExist) {
+		return Expiry{}, ErrNotFound
+	} else if err != nil {
+		return Expiry{}, err
+	}
+	var e Expiry
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+// Purge removes the snapshot file and history directory of board.
+func (s *FileStore) Purge(board string) error {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	if err := os.Remove(s.path(board)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(s.historyDir(board))
+}
+
+// settingsPath returns the settings file of board.
+func (s *FileStore) settingsPath(board string) string {
+	return filepath.Join(s.dir, "settings", board+".json")
+}
+
+// SaveExpiry stores the expiry of board in memory.
+func (s *MemoryStore) SaveExpiry(board string, e Expiry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expiry == nil {
+		s.expiry = make(map[string]Expiry)
+	}
+	s.expiry[board] = e
+	return nil
+}
+
+// LoadExpiry returns the expiry of board stored in memory.
+func (s *MemoryStore) LoadExpiry(board string) (Expiry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.expiry[board]
+	if !ok {
+		return Expiry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+// Purge drops the snapshot and history of board.
+func (s *MemoryStore) Purge(board string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.snapshots, board)
+	delete(s.history, board)
+	return nil
+}
+
+// redisExpiryPrefix namespaces expiry keys.
+const redisExpiryPrefix = "boardcast:expiry:"
+
+// SaveExpiry stores the expiry of board under its key.
+func (s *RedisStore) SaveExpiry(board string, e Expiry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(redisExpiryPrefix+board, data)
+}
+
+// LoadExpiry returns the expiry stored under the board's key.
+func (s *RedisStore) LoadExpiry(board string) (Expiry, error) {
+	data, err := s.client.Get(redisExpiryPrefix + board)
+	if errors.Is(err, resp.ErrNil) {
+		return Expiry{}, ErrNotFound
+	} else if err != nil {
+		return Expiry{}, err
+	}
+	var e Expiry
+	err = json.Unmarshal(data, &e)
+	return e, err
+}
+
+// Purge deletes the snapshot, the history list and the version contents of
+// board.
+func (s *RedisStore) Purge(board string) error {
+	listKey := redisHistoryPrefix + board
+	versions, err := s.Versions(board)
+	if err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if _, err := s.client.Do("DEL", listKey+":"+v.ID); err != nil {
+			return err
+		}
+	}
+	_, err = s.client.Do("DEL", redisKeyPrefix+board, listKey)
+	return err
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// HistoryLimit is the number of versions kept per board.
+const HistoryLimit = 1000
+
+// ErrVersionNotFound is returned when a board has no version with the requested ID.
+var ErrVersionNotFound = errors.New("version not found")
+
+// Version is a past state of a board. Content is omitted when listing.
+type Version struct {
+	ID       string    `json:"id"`
+	Revision uint64    `json:"revision"`
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	// Origin names the peer a replicated version came from.
+	Origin  string `json:"origin,omitempty"`
+	Size    int    `json:"size"`
+	Content string `json:"content,omitempty"`
+}
+
+// NewVersionID returns a version ID that sorts chronologically.
+func NewVersionID(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+// versionIDPattern matches IDs accepted from clients and peers.
+var versionIDPattern = regexp.MustCompile(`^[0-9]{1,20}$`)
+
+// ValidVersionID reports whether id is well formed.
+func ValidVersionID(id string) bool {
+	return versionIDPattern.MatchString(id)
+}
+
+// History is implemented by stores that keep past versions of boards.
+type History interface {
+	// AppendVersion adds v, dropping the oldest versions beyond HistoryLimit.
+	AppendVersion(board string, v Version) error
+	// Versions lists the versions of board without content, oldest first.
+	Versions(board string) ([]Version, error)
+	// LoadVersion returns a version of board including its content.
+	LoadVersion(board, id string) (Version, error)
+}
+
+// HistoryOf returns the history of store, or nil if it keeps none.
+func HistoryOf(store Store) History {
+	history, _ := store.(History)
+	return history
+}
+
+// AppendVersion adds a version to the file history of board.
+func (s *FileStore) AppendVersion(board string, v Version) error {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	if !ValidVersionID(v.ID) {
+		return ErrVersionNotFound
+	}
+
+	dir := s.historyDir(board)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	content := v.Content
+	v.Content = ""
+	if err := os.WriteFile(filepath.Join(dir, v.ID+".txt"), s.keys.seal([]byte(content)), 0644); err != nil {
+		return err
+	}
+
+	versions, err := s.readIndex(board)
+	if err != nil {
+		return err
+	}
+	versions = append(versions, v)
+	if len(versions) <= HistoryLimit {
+		return appendJSONLine(filepath.Join(dir, "index.jsonl"), v)
+	}
+
+	for _, old := range versions[:len(versions)-HistoryLimit] {
+		os.Remove(filepath.Join(dir, old.ID+".txt"))
+	}
+	return s.writeIndex(board, versions[len(versions)-HistoryLimit:])
+}
+
+// Versions lists the file history of board.
+func (s *FileStore) Versions(board string) ([]Version, error) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	return s.readIndex(board)
+}
+
+// LoadVersion reads a version from the file history of board.
+func (s *FileStore) LoadVersion(board, id string) (Version, error) {
+	versions, err := s.Versions(board)
+	if err != nil {
+		return Version{}, err
+	}
+
+	i := slices.IndexFunc(versions, func(v Version) bool { return v.ID == id })
+	if i < 0 {
+		return Version{}, ErrVersionNotFound
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.historyDir(board), id+".txt"))
+	if errors.Is(err, os.ErrNotExist) {
+		return Version{}, ErrVersionNotFound
+	} else if err != nil {
+		return Version{}, err
+	}
+	content, err := s.keys.open(data)
+	if err != nil {
+		return Version{}, err
+	}
+
+	v := versions[i]
+	v.Content = string(content)
+	return v, nil
+}
+
+// historyDir returns the directory holding the history of board.
+func (s *FileStore) historyDir(board string) string {
+	return filepath.Join(s.dir, "history", board)
+}
+
+// readIndex reads the version index of board.
+func (s *FileStore) readIndex(board string) ([]Version, error) {
+	f, err := os.Open(filepath.Join(s.historyDir(board), "index.jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var versions []Version
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var v Version
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	return versions, scanner.Err()
+}
+
+// writeIndex atomically replaces the version index of board.
+func (s *FileStore) writeIndex(board string, versions []Version) error {
+	path := filepath.Join(s.historyDir(board), "index.jsonl")
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	for _, v := range versions {
+		if err := enc.Encode(v); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// appendJSONLine appends v to the JSON lines file at path.
+func appendJSONLine(path string, v any) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(v); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AppendVersion adds a version to the in-memory history of board.
+func (s *MemoryStore) AppendVersion(board string, v Version) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.history == nil {
+		s.history = make(map[string][]Version)
+	}
+	versions := append(s.history[board], v)
+	if len(versions) > HistoryLimit {
+		versions = slices.Clone(versions[len(versions)-HistoryLimit:])
+	}
+	s.history[board] = versions
+	return nil
+}
+
+// Versions lists the in-memory history of board.
+func (s *MemoryStore) Versions(board string) ([]Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	versions := make([]Version, 0, len(s.history[board]))
+	for _, v := range s.history[board] {
+		v.Content = ""
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// LoadVersion returns a version from the in-memory history of board.
+func (s *MemoryStore) LoadVersion(board, id string) (Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, v := range s.history[board] {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return Version{}, ErrVersionNotFound
+}
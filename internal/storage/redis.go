@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/yosebyte/boardcast/internal/resp"
+)
+
+// redisKeyPrefix namespaces snapshot keys.
+const redisKeyPrefix = "boardcast:snapshot:"
+
+// redisCheckKey is written by Check and expires on its own.
+const redisCheckKey = "boardcast:check"
+
+// RedisStore keeps snapshots in a Redis-protocol server so that several
+// instances share them.
+type RedisStore struct {
+	client *resp.Client
+	keys   *Keyring
+	check  checkCache
+}
+
+// NewRedisStore creates a store for the server at rawURL, a redis:// URL.
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	client, err := resp.NewClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisStore{client: client}, nil
+}
+
+// SaveSnapshot stores content under the board's key.
+func (s *RedisStore) SaveSnapshot(board string, content []byte) error {
+	return s.client.Set(redisKeyPrefix+board, s.keys.seal(content))
+}
+
+// LoadSnapshot returns the content stored under the board's key.
+func (s *RedisStore) LoadSnapshot(board string) ([]byte, error) {
+	data, err := s.client.Get(redisKeyPrefix + board)
+	if errors.Is(err, resp.ErrNil) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.keys.open(data)
+}
+
+// Check verifies the server is reachable and accepts writes, which a replica
+// or a server out of memory refuses.
+func (s *RedisStore) Check() error {
+	return s.check.run(s.probe)
+}
+
+// probe writes a short-lived key. The PING first replaces a connection the
+// server closed, as a write failing on it is not retried.
+func (s *RedisStore) probe() error {
+	if _, err := s.client.Do("PING"); err != nil {
+		return err
+	}
+	_, err := s.client.Do("SET", redisCheckKey, "1", "EX", "60")
+	return err
+}
+
+// Close closes the connection to the server.
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// redisHistoryPrefix namespaces history keys: a list of version metadata per
+// board and one key per version's content.
+const redisHistoryPrefix = "boardcast:history:"
+
+// AppendVersion pushes a version onto the board's history list.
+func (s *RedisStore) AppendVersion(board string, v Version) error {
+	if !ValidVersionID(v.ID) {
+		return ErrVersionNotFound
+	}
+
+	content := v.Content
+	v.Content = ""
+	meta, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	listKey := redisHistoryPrefix + board
+	if err := s.client.Set(listKey+":"+v.ID, s.keys.seal([]byte(content))); err != nil {
+		return err
+	}
+	if _, err := s.client.Do("RPUSH", listKey, string(meta)); err != nil {
+		return err
+	}
+
+	// Drop content of versions beyond the limit before trimming the list.
+	reply, err := s.client.Do("LRANGE", listKey, "0", strconv.Itoa(-HistoryLimit-1))
+	if err != nil {
+		return err
+	}
+	for _, old := range decodeVersions(reply) {
+		s.client.Do("DEL", listKey+":"+old.ID)
+	}
+	_, err = s.client.Do("LTRIM", listKey, strconv.Itoa(-HistoryLimit), "-1")
+	return err
+}
+
+// Versions lists the board's history.
+func (s *RedisStore) Versions(board string) ([]Version, error) {
+	reply, err := s.client.Do("LRANGE", redisHistoryPrefix+board, "0", "-1")
+	if err != nil {
+		return nil, err
+	}
+	return decodeVersions(reply), nil
+}
+
+// LoadVersion returns a version from the board's history.
+func (s *RedisStore) LoadVersion(board, id string) (Version, error) {
+	versions, err := s.Versions(board)
+	if err != nil {
+		return Version{}, err
+	}
+
+	i := slices.IndexFunc(versions, func(v Version) bool { return v.ID == id })
+	if i < 0 {
+		return Version{}, ErrVersionNotFound
+	}
+
+	data, err := s.client.Get(redisHistoryPrefix + board + ":" + id)
+	if errors.Is(err, resp.ErrNil) {
+		return Version{}, ErrVersionNotFound
+	} else if err != nil {
+		return Version{}, err
+	}
+	content, err := s.keys.open(data)
+	if err != nil {
+		return Version{}, err
+	}
+
+	v := versions[i]
+	v.Content = string(content)
+	return v, nil
+}
+
+// decodeVersions parses an LRANGE reply of version metadata.
+func decodeVersions(reply any) []Version {
+	items, _ := reply.([]any)
+	versions := make([]Version, 0, len(items))
+	for _, item := range items {
+		data, _ := item.([]byte)
+		var v Version
+		if err := json.Unmarshal(data, &v); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
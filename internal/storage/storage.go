@@ -0,0 +1,150 @@
+// Package storage provides persistence backends for board snapshots, history
+// and settings.
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a board has no saved snapshot.
+var ErrNotFound = errors.New("snapshot not found")
+
+// Store persists board snapshots.
+type Store interface {
+	// SaveSnapshot stores content as the latest snapshot of board.
+	SaveSnapshot(board string, content []byte) error
+	// LoadSnapshot returns the latest snapshot of board or ErrNotFound.
+	LoadSnapshot(board string) ([]byte, error)
+}
+
+// Checker is implemented by stores that can verify they are able to persist data.
+type Checker interface {
+	// Check returns an error if the store cannot currently be written to.
+	Check() error
+}
+
+// Check verifies store is writable when it implements Checker.
+func Check(store Store) error {
+	if checker, ok := store.(Checker); ok {
+		return checker.Check()
+	}
+	return nil
+}
+
+// checkInterval is how long the result of a store check is reused, so that
+// frequent readiness probes do not each write to the store.
+const checkInterval = 5 * time.Second
+
+// checkCache remembers the last result of a store check.
+type checkCache struct {
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+// run returns the result of probe, calling it at most once per checkInterval.
+func (c *checkCache) run(probe func() error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.at.IsZero() || time.Since(c.at) >= checkInterval {
+		c.err = probe()
+		c.at = time.Now()
+	}
+	return c.err
+}
+
+// FileStore keeps one snapshot file per board in a directory.
+type FileStore struct {
+	dir       string
+	historyMu sync.Mutex
+	keys      *Keyring
+	check     checkCache
+}
+
+// NewFileStore creates a file store rooted at dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// SaveSnapshot writes content to the board's snapshot file.
+func (s *FileStore) SaveSnapshot(board string, content []byte) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(board), s.keys.seal(content), 0644)
+}
+
+// LoadSnapshot reads the board's snapshot file.
+func (s *FileStore) LoadSnapshot(board string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(board))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return s.keys.open(data)
+}
+
+// Check verifies the directory exists and accepts new files.
+func (s *FileStore) Check() error {
+	return s.check.run(s.probe)
+}
+
+// probe writes and removes a file in the directory.
+func (s *FileStore) probe() error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, ".boardcast-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// path returns the snapshot file for board. The default board keeps the
+// historical boardcast.txt name.
+func (s *FileStore) path(board string) string {
+	if board == "default" {
+		return filepath.Join(s.dir, "boardcast.txt")
+	}
+	return filepath.Join(s.dir, "boardcast-"+board+".txt")
+}
+
+// MemoryStore keeps snapshots in memory, mainly for embedding and tests.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	snapshots map[string][]byte
+	history   map[string][]Version
+	expiry    map[string]Expiry
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{snapshots: make(map[string][]byte)}
+}
+
+// SaveSnapshot stores a copy of content.
+func (s *MemoryStore) SaveSnapshot(board string, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[board] = append([]byte(nil), content...)
+	return nil
+}
+
+// LoadSnapshot returns a copy of the stored content.
+func (s *MemoryStore) LoadSnapshot(board string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.snapshots[board]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), content...), nil
+}
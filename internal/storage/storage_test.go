@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreCheck(t *testing.T) {
+	// A file in place of the directory makes the store unwritable.
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(dir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewFileStore(dir)
+	if err := Check(s); err == nil {
+		t.Fatal("Check() succeeded on an unwritable directory")
+	}
+
+	if err := os.Remove(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(s); err == nil {
+		t.Error("Check() probed again before the result expired")
+	}
+
+	// Rewind the clock instead of sleeping.
+	s.check.at = s.check.at.Add(-checkInterval)
+	if err := Check(s); err != nil {
+		t.Errorf("Check() error = %v after the directory became writable", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Check() left %d files behind, %v", len(entries), err)
+	}
+}
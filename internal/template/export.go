@@ -0,0 +1,40 @@
+package template
+
+// ExportHTML is the standalone page of an HTML export. It is formatted with
+// the escaped title and the rendered board content.
+const ExportHTML = `<!DOCTYPE html>
+<html>
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width,initial-scale=1">
+	<title>%s</title>
+	<style>
+		:root { color-scheme: light dark; }
+		body { max-width: 46rem; margin: 2rem auto; padding: 0 1rem; font: 16px/1.6 -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, "Helvetica Neue", Arial, sans-serif; color: #1f2328; background: #fff; }
+		h1, h2, h3, h4, h5, h6 { line-height: 1.25; margin: 1.5em 0 .5em; }
+		h1, h2 { padding-bottom: .3em; border-bottom: 1px solid #d0d7de; }
+		a { color: #2859c5; }
+		code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, Consolas, monospace; font-size: .9em; }
+		code { padding: .15em .35em; border-radius: 4px; background: #eff1f3; }
+		pre { padding: 1em; overflow: auto; border-radius: 6px; background: #f6f8fa; }
+		pre code { padding: 0; background: none; }
+		blockquote { margin: 0; padding: 0 1em; color: #59636e; border-left: .25em solid #d0d7de; }
+		table { border-collapse: collapse; }
+		th, td { padding: .4em .8em; border: 1px solid #d0d7de; }
+		img { max-width: 100%%; }
+		hr { border: 0; border-top: 1px solid #d0d7de; }
+		li > input[type=checkbox] { margin-right: .3em; }
+		@media (prefers-color-scheme: dark) {
+			body { color: #e6edf3; background: #0d1117; }
+			a { color: #6d9cff; }
+			code { background: #262c36; }
+			pre { background: #161b22; }
+			blockquote { color: #9198a1; border-color: #3d444d; }
+			h1, h2, th, td, hr { border-color: #3d444d; }
+		}
+	</style>
+</head>
+<body>
+%s</body>
+</html>
+`
@@ -0,0 +1,93 @@
+package template
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sync"
+)
+
+// Icon colors, matching the logo.
+var (
+	iconBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	iconBoard      = color.RGBA{0x8f, 0xbf, 0xfa, 0xff}
+	iconWaves      = color.RGBA{0x28, 0x59, 0xc5, 0xff}
+)
+
+// iconSamples is the number of samples per pixel along each axis.
+const iconSamples = 4
+
+// icons holds the PNG icons by name, rendered once on first use.
+var icons = sync.OnceValue(func() map[string][]byte {
+	return map[string][]byte{
+		"icon-192.png":     renderIcon(192, 0.8),
+		"icon-512.png":     renderIcon(512, 0.8),
+		"maskable-512.png": renderIcon(512, 0.6),
+	}
+})
+
+// IconPNG returns the PNG icon with the given name, if there is one.
+func IconPNG(name string) ([]byte, bool) {
+	icon, ok := icons()[name]
+	return icon, ok
+}
+
+// renderIcon draws the logo on a white square of size pixels, the logo
+// spanning scale of its width. Maskable icons use a smaller scale to keep the
+// logo within the safe zone.
+func renderIcon(size int, scale float64) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	unit := float64(size) * scale / 14
+	offset := float64(size) * (1 - scale) / 2
+
+	for y := range size {
+		for x := range size {
+			var r, g, b float64
+			for sy := range iconSamples {
+				for sx := range iconSamples {
+					px := (float64(x) + (float64(sx)+0.5)/iconSamples - offset) / unit
+					py := (float64(y) + (float64(sy)+0.5)/iconSamples - offset) / unit
+					c := logoColor(px, py)
+					r, g, b = r+float64(c.R), g+float64(c.G), b+float64(c.B)
+				}
+			}
+			n := float64(iconSamples * iconSamples)
+			img.SetRGBA(x, y, color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 0xff})
+		}
+	}
+
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// logoColor returns the color of the logo at (x, y) in its 14x14 view box:
+// a board whose lower left corner is cut out around two broadcast waves.
+func logoColor(x, y float64) color.RGBA {
+	// The waves are quarter rings with round ends around the lower left corner.
+	const cx, cy = 0.768, 11.762
+	dx, dy := x-cx, cy-y
+	d := math.Hypot(dx, dy)
+	for _, radius := range []float64{4.283, 1.606} {
+		if dx >= 0 && dy >= 0 && math.Abs(d-radius) <= 0.75 {
+			return iconWaves
+		}
+		if math.Hypot(x-cx, y-(cy-radius)) <= 0.75 || math.Hypot(x-(cx+radius), y-cy) <= 0.75 {
+			return iconWaves
+		}
+	}
+
+	const left, top, right, bottom, corner = 0.017, 1.4, 13.98, 12.6, 1.92
+	if x < left || x > right || y < top || y > bottom || d <= 6.283 {
+		return iconBackground
+	}
+	// Round the remaining corners.
+	qx := math.Max(math.Max(left+corner-x, x-(right-corner)), 0)
+	qy := math.Max(math.Max(top+corner-y, y-(bottom-corner)), 0)
+	if qx > 0 && qy > 0 && math.Hypot(qx, qy) > corner {
+		return iconBackground
+	}
+	return iconBoard
+}
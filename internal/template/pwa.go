@@ -0,0 +1,88 @@
+package template
+
+// ManifestJSON is the web app manifest. Its URLs are relative to the
+// manifest, which is served next to the whiteboard page.
+const ManifestJSON = `{
+	"name": "BoardCast",
+	"short_name": "BoardCast",
+	"description": "Shared real-time whiteboard",
+	"start_url": "./",
+	"scope": "./",
+	"display": "standalone",
+	"background_color": "#f5f5f5",
+	"theme_color": "#2859c5",
+	"icons": [
+		{"src": "icons/icon.svg", "sizes": "any", "type": "image/svg+xml"},
+		{"src": "icons/icon-192.png", "sizes": "192x192", "type": "image/png"},
+		{"src": "icons/icon-512.png", "sizes": "512x512", "type": "image/png"},
+		{"src": "icons/maskable-512.png", "sizes": "512x512", "type": "image/png", "purpose": "maskable"}
+	]
+}
+`
+
+// IconSVG is the application icon, the logo on a white rounded square.
+const IconSVG = `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 14 14">
+	<rect x="-1" y="-1" width="16" height="16" rx="3" fill="#fff"/>
+	<g transform="translate(1.4 1.4) scale(.8)">
+		<path fill="#8fbffa" d="M.58 1.961A1.92 1.92 0 0 1 1.937 1.4H12.06a1.92 1.92 0 0 1 1.92 1.92v7.362a1.92 1.92 0 0 1-1.92 1.92H6.995A6.283 6.283 0 0 0 .017 5.524V3.32c0-.51.202-.998.563-1.358Z"/>
+		<path fill="#2859c5" d="M.768 6.73a.75.75 0 1 0 0 1.5a3.533 3.533 0 0 1 3.533 3.532a.75.75 0 0 0 1.5 0A5.033 5.033 0 0 0 .768 6.73m0 2.676a.75.75 0 0 0 0 1.5a.856.856 0 0 1 .856.856a.75.75 0 1 0 1.5 0A2.356 2.356 0 0 0 .768 9.406"/>
+	</g>
+</svg>
+`
+
+// ServiceWorkerJS is the service worker making the whiteboard installable
+// and usable offline. It is formatted with the quoted base path and the
+// quoted cache name.
+//
+// The page is fetched from the network first and falls back to a cached
+// copy, so it always reflects the current release when online. The cached
+// copy is fetched without credentials and so holds no board content: the page
+// keeps the last synced content in IndexedDB and shows it when the server
+// cannot be reached. Icons, the manifest and the markdown libraries are served
+// from the cache.
+const ServiceWorkerJS = `const base = %s, cache = %s,
+	page = base + '/',
+	assets = [
+		base + '/manifest.webmanifest',
+		base + '/icons/icon.svg',
+		base + '/icons/icon-192.png',
+		'https://cdn.jsdelivr.net/npm/marked/marked.min.js',
+		'https://cdn.jsdelivr.net/npm/dompurify@3.0.6/dist/purify.min.js'
+	],
+	shell = () => fetch(page, {credentials: 'omit'})
+		.then(res => res.ok && caches.open(cache).then(c => c.put(page, res)));
+
+self.addEventListener('install', e => e.waitUntil(
+	caches.open(cache)
+		.then(c => Promise.all([shell(), ...assets.map(u => c.add(u))].map(p => p.catch(() => {}))))
+		.then(() => self.skipWaiting())
+));
+
+self.addEventListener('activate', e => e.waitUntil(
+	caches.keys()
+		.then(keys => Promise.all(keys.filter(k => k.startsWith('boardcast-') && k !== cache).map(k => caches.delete(k))))
+		.then(() => self.clients.claim())
+));
+
+self.addEventListener('fetch', e => {
+	const r = e.request, url = new URL(r.url);
+	if (r.method !== 'GET') return;
+	if (r.mode === 'navigate' && url.origin === location.origin && url.pathname === page) {
+		e.respondWith(fetch(r).then(res => {
+			e.waitUntil(shell().catch(() => {}));
+			return res;
+		}, () => caches.match(page).then(hit => hit || Response.error())));
+		return;
+	}
+	const key = url.origin === location.origin ? url.pathname : url.href;
+	if (assets.includes(key)) {
+		e.respondWith(caches.match(key).then(hit => hit || fetch(r).then(res => {
+			if (res.ok) {
+				const copy = res.clone();
+				e.waitUntil(caches.open(cache).then(c => c.put(key, copy)));
+			}
+			return res;
+		})));
+	}
+});
+`
@@ -2,11 +2,17 @@
 package template
 
 // WhiteboardHTML contains the complete HTML template for the whiteboard interface.
+// It is formatted with the quoted initial content, the quoted base path,
+// whether the board is end-to-end encrypted and the version.
 const WhiteboardHTML = `<!DOCTYPE html>
 <html>
 <head>
 	<title>BoardCast</title>
 	<meta name="viewport" content="width=device-width,initial-scale=1">
+	<meta name="theme-color" content="#2859c5">
+	<link rel="manifest" href="manifest.webmanifest">
+	<link rel="icon" href="icons/icon.svg" type="image/svg+xml">
+	<link rel="apple-touch-icon" href="icons/icon-192.png">
 	<style>
 		*{box-sizing:border-box}
 		body{margin:0;padding:10px;height:100vh;display:flex;flex-direction:column;font-family:system-ui,sans-serif;background:#f5f5f5;transition:all .5s;overflow:hidden}
@@ -27,6 +33,11 @@ const WhiteboardHTML = `<!DOCTYPE html>
 		#whiteboard{padding:20px;font-size:16px;line-height:1.5;resize:none;font-family:inherit;display:none}
 		#whiteboard:focus{outline:none;border-color:#8fbffa;box-shadow:0 0 0 2px rgba(143,191,250,.2)}
 		.placeholder{display:flex;align-items:center;justify-content:center;color:#999}
+		.notice{position:fixed;top:12px;left:50%%;transform:translateX(-50%%);padding:6px 12px;border-radius:4px;background:rgba(255,193,7,.9);color:#333;font-size:13px;display:none;z-index:10}
+		.conflict{display:none;align-items:center;gap:8px;flex-wrap:wrap;margin-bottom:10px;padding:8px 12px;border-radius:4px;background:rgba(255,107,129,.2);border:1px solid rgba(255,107,129,.6);font-size:13px}
+		.conflict button{padding:4px 10px;border:1px solid #ddd;border-radius:4px;background:#f0f0f0;cursor:pointer;font-size:13px}
+		body.dark .conflict{color:#e0e0e0}
+		body.dark .conflict button{background:#3d3d3d;border-color:#555;color:#e0e0e0}
 		body.dark{background:#1a1a1a}
 		body.dark #whiteboard,body.dark .placeholder{background:#2d2d2d;border-color:#444;color:#e0e0e0}
 		body.dark .placeholder{color:#999}
@@ -61,7 +72,7 @@ body.dark .preview-wrapper{--preview-bg:#111;--preview-color:#f5f5f5;--placehold
   <script src="https://cdn.jsdelivr.net/npm/dompurify@3.0.6/dist/purify.min.js"></script>
 </head>
 <body>
-  <script>const initialContent = %s;</script>
+  <script>const initialContent = %s, basePath = %s, e2e = %s;</script>
 	<div class="header">
 		<div class="logo">
 			<svg xmlns="http://www.w3.org/2000/svg" width="32" height="32" viewBox="0 0 14 14">
@@ -94,6 +105,13 @@ body.dark .preview-wrapper{--preview-bg:#111;--preview-color:#f5f5f5;--placehold
 			</button>
 		</div>
 	</div>
+	<div class="notice" id="notice"></div>
+	<div class="conflict" id="conflict">
+		<span>Your offline edits overlap changes made meanwhile.</span>
+		<button id="keepMine">Keep mine</button>
+		<button id="keepServer">Use server version</button>
+		<button id="mergeHand">Merge by hand</button>
+	</div>
 	<div class="placeholder" id="placeholder">Enter password to access BoardCast</div>
 	<div class="editor-container">
     <textarea id="whiteboard" placeholder="Start typing markdown here..."></textarea>
@@ -110,11 +128,25 @@ body.dark .preview-wrapper{--preview-bg:#111;--preview-color:#f5f5f5;--placehold
 			h=document.getElementById('placeholder'),
 			t=document.getElementById('themeBtn'),
 			sb=document.getElementById('saveBtn'),
-			rb=document.getElementById('restoreBtn');
+			rb=document.getElementById('restoreBtn'),
+			n=document.getElementById('notice'),
+			cf=document.getElementById('conflict');
 		
-		let s=null,auth=false,updating=false,timer=null;
+		// rev and synced are the revision and content last received from the
+		// server, and sd the digest of that content as the server holds it.
+		// dirty marks edits the server has not received, which are kept in
+		// IndexedDB with the base they started from and sent as a sync frame
+		// once connected.
+		let s=null,auth=false,updating=false,timer=null,retry=null,
+			rev=0,synced='',sd,base={rev:0,content:''},dirty=false,pending=null,conflict=null,fresh=false,db=null,kt=null;
+		// On end-to-end encrypted boards ck is the key derived from the
+		// passphrase in the URL fragment, which browsers never send. Content is
+		// encrypted before it leaves the page and decrypted as it arrives; sq and
+		// rq keep sent and received frames in order while that happens.
+		let ck=null,sq=Promise.resolve(),rq=Promise.resolve();
 		
 		const status=st=>p.className='status-'+st,
+			notice=m=>{n.textContent=m||'';n.style.display=m?'block':'none'},
 			icons={
 				connect:'M12 2C6.48 2 2 6.48 2 12s4.48 10 10 10 10-4.48 10-10S17.52 2 12 2zm-2 15l-5-5 1.41-1.41L10 14.17l7.59-7.59L19 8l-9 9z',
 				disconnect:'M12 2C6.47 2 2 6.47 2 12s4.47 10 10 10 10-4.47 10-10S17.53 2 12 2zm5 13.59L15.59 17 12 13.41 8.41 17 7 15.59 10.59 12 7 8.41 8.41 7 12 10.59 15.59 7 17 8.41 13.41 12 17 15.59z',
@@ -134,29 +166,48 @@ body.dark .preview-wrapper{--preview-bg:#111;--preview-color:#f5f5f5;--placehold
 			connect=()=>{
 				if(!auth)return;
 				status('connecting');
-				s=new WebSocket((location.protocol==='https:'?'wss:':'ws:')+'//'+location.host+'/ws');
-				s.onopen=()=>{status('connected');timer&&(clearTimeout(timer),timer=null);fetch('/content',{credentials:'include'}).then(r=>r.text()).then(c=>w.value=c).catch(()=>{})};
-				s.onmessage=e=>{updating||(w.value=e.data,w.setSelectionRange(w.value.length,w.value.length))};
-				s.onclose=()=>{status('disconnected');auth&&!timer&&(timer=setTimeout(()=>{timer=null;connect()},3000))};
-				s.onerror=()=>status('disconnected');
-				w.oninput=()=>{s?.readyState===1&&(updating=true,s.send(w.value),setTimeout(()=>updating=false,50))}
+				s=new WebSocket((location.protocol==='https:'?'wss:':'ws:')+'//'+location.host+basePath+'/ws','boardcast.v1');
+				s.onopen=()=>{status('connected');notice('');timer&&(clearTimeout(timer),timer=null);pending=null;fresh=true};
+				s.onmessage=e=>{
+					const ws=s;
+					rq=rq.then(()=>unwrap(JSON.parse(e.data))).then(f=>ws===s&&receive(f),()=>{notice('Cannot decrypt the board, check the passphrase');setTimeout(()=>notice(''),5000)})
+				};
+				s.onclose=e=>{status('disconnected');e.code===1012&&notice((e.reason||'Server restarting')+', reconnecting...');auth&&!timer&&(timer=setTimeout(()=>{timer=null;connect()},3000))};
+				s.onerror=()=>status('disconnected')
+			},
+			
+			receive=f=>{
+				if(f.type==='error'&&f.code==='rate_limited'){clearTimeout(retry);retry=setTimeout(()=>pending?send(pending):w.oninput(),1000);return}
+				if(f.type==='error'){notice(f.message+(f.limit?' (limit '+f.limit+' bytes)':''));setTimeout(()=>notice(''),5000);return}
+				if(f.type==='conflict'){pending=null;conflict=f;f.merged||(f.merged='<<<<<<< offline edits\n'+nl(w.value)+'=======\n'+nl(f.content)+'>>>>>>> server\n');rev=f.revision||0;synced=f.content;sd=f.digest;cf.style.display='flex';return}
+				if(f.type==='synced'){
+					rev=f.revision||0;synced=f.content;sd=f.digest;
+					// Edits typed while syncing apply on top of the synced result.
+					if(pending&&w.value!==pending.content){base={rev,content:pending.content,digest:sd};return sync()}
+					pending=null;dirty=false;base={rev,content:synced,digest:sd};w.value=synced;keep();updatePreview();
+					notice('Offline edits synced');setTimeout(()=>notice(''),3000);return
+				}
+				if(dirty&&!pending&&!conflict)sync();
+				// Revisions restart with the server, so only older frames on one connection are stale.
+				if(!fresh&&(f.revision||0)<rev)return;
+				fresh=false;rev=f.revision||0;synced=f.content;sd=f.digest;
+				if(f.reason){notice(f.reason==='burned'?'Board content was burned after reading':'Board content expired');setTimeout(()=>notice(''),5000)}
+				if(!dirty){base={rev,content:synced,digest:sd};updating||(w.value=synced,w.setSelectionRange(w.value.length,w.value.length));keep()}
 			},
 			
-			authenticate=()=>fetch('/auth',{
+			authenticate=()=>fetch(basePath+'/auth',{
 				method:'POST',headers:{'Content-Type':'application/json'},credentials:'include',
 				body:JSON.stringify({password:p.value})
 			}).then(r=>r.ok?r.text():Promise.reject()).then(()=>{
 				auth=true;p.disabled=true;p.value='';w.style.display='block';h.style.display='none';
 				a.querySelector('path').setAttribute('d',icons.disconnect);
-				fetch('/content',{credentials:'include'}).then(r=>r.text()).then(c=>{
-					w.value=c;
-					updatePreview(); // 认证完成后立即更新markdown预览
-				});
-				connect();updateButtons()
+				fetch(basePath+'/content',{credentials:'include'}).then(r=>r.text()).then(c=>unlock().then(()=>unseal(c))).then(restore).then(connect,locked); // 认证完成后立即更新markdown预览
+				updateButtons()
 			}).catch(()=>{p.value='';updateButtons()}),
 			
-			disconnect=()=>fetch('/logout',{method:'POST',credentials:'include'}).finally(()=>{
+			disconnect=()=>fetch(basePath+'/logout',{method:'POST',credentials:'include'}).finally(()=>{
 				timer&&(clearTimeout(timer),timer=null);s?.close();auth=false;p.value='';p.disabled=false;
+				clearTimeout(kt);dirty=false;pending=null;conflict=null;cf.style.display='none';drafts('readwrite',o=>o.delete(key));
 				w.style.display='none';h.style.display='flex';w.value='';
 				a.querySelector('path').setAttribute('d',icons.connect);status('disconnected');updateButtons();
 				// 退出认证后清空markdown预览区
@@ -164,28 +215,142 @@ body.dark .preview-wrapper{--preview-bg:#111;--preview-color:#f5f5f5;--placehold
 				if(inner) inner.innerHTML = '<div class="preview-placeholder">预览区：暂无内容 — 开始输入 Markdown 或粘贴文本以查看渲染结果。</div>';
 			}),
 			
-			init=()=>fetch('/content',{credentials:'include'}).then(r=>{
+			init=()=>fetch(basePath+'/content',{credentials:'include'}).then(r=>{
 				if(r.ok)return r.text();throw new Error('Not authenticated')
-			}).then(c=>{
+			}).then(c=>unlock().then(()=>unseal(c)).then(c=>{
 				auth=true;p.disabled=true;p.value='';w.style.display='block';h.style.display='none';
-				a.querySelector('path').setAttribute('d',icons.disconnect);w.value=c;connect();updateButtons();
-				updatePreview(); // 初始化时也更新markdown预览
-			}).catch(()=>{status('disconnected');updateButtons()}),
+				a.querySelector('path').setAttribute('d',icons.disconnect);updateButtons();
+				return restore(c).then(connect); // 初始化时也更新markdown预览
+			},locked)).catch(e=>{
+				if(e instanceof TypeError)return offline();
+				auth&&shown(false);status('disconnected');updateButtons()
+			}),
 			
-			snap=(u)=>auth&&fetch(u,{method:'POST',credentials:'include'}).catch(()=>{});
+			// offline shows the content kept in IndexedDB when the server cannot
+			// be reached and retries until it can.
+			offline=()=>drafts('readonly',o=>o.get(key)).then(d=>d&&unlock().then(()=>opened(d)).catch(()=>null)).then(d=>{
+				status('disconnected');setTimeout(init,5000);
+				if(!d||auth){updateButtons();return}
+				shown(true);w.value=d.content;dirty=!!d.dirty;base={rev:d.base,content:d.baseContent,digest:d.baseDigest};synced=dirty?d.baseContent:d.content;
+				updatePreview();notice('Offline: showing the last synced content');setTimeout(()=>notice(''),3000)
+			}),
+			shown=on=>{
+				auth=on;p.disabled=on;p.value='';w.style.display=on?'block':'none';h.style.display=on?'none':'flex';
+				a.querySelector('path').setAttribute('d',on?icons.disconnect:icons.connect);updateButtons()
+			},
+			
+			snap=(u)=>auth&&fetch(u,{method:'POST',credentials:'include'}).catch(()=>{}),
+
+			key=location.host+basePath,
+			drafts=(mode,fn)=>(db||(db=new Promise((ok,ko)=>{
+				const r=indexedDB.open('boardcast',1);
+				r.onupgradeneeded=()=>r.result.createObjectStore('drafts');r.onsuccess=()=>ok(r.result);r.onerror=()=>ko(r.error)
+			}))).then(d=>new Promise((ok,ko)=>{
+				const tx=d.transaction('drafts',mode),r=fn(tx.objectStore('drafts'));
+				tx.oncomplete=()=>ok(r.result);tx.onerror=()=>ko(tx.error)
+			})).catch(()=>null),
+			keep=()=>{clearTimeout(kt);kt=setTimeout(()=>Promise.all([seal(w.value),seal(base.content)]).then(([c,b])=>drafts('readwrite',o=>o.put({content:c,dirty,base:base.rev,baseContent:b,baseDigest:base.digest},key))),300)},
+			// Edits made while the page was offline are newer than the stored draft.
+			restore=c=>(dirty?Promise.resolve({dirty,content:w.value,base:base.rev,baseContent:base.content,baseDigest:base.digest}):drafts('readonly',o=>o.get(key)).then(opened)).then(d=>{
+				synced=c;
+				if(d&&d.dirty&&d.content!==c){w.value=d.content;base={rev:d.base,content:d.baseContent,digest:d.baseDigest};dirty=true;notice('Restored edits made offline');setTimeout(()=>notice(''),3000)}
+				else{w.value=c;base={rev:0,content:c};dirty=false}
+				updatePreview()
+			}),
+			send=f=>{
+				if(s?.readyState!==1)return false;
+				// Encrypted content differs on every send, so offline edits are
+				// synced against the digest of the base as the server holds it
+				// instead of its content, and the digest of each update sent is
+				// kept for that.
+				const ws=s;
+				sq=sq.then(()=>seal(f.content)).then(c=>{
+					ws.send(JSON.stringify(e2e?{...f,content:c,base_content:undefined}:f));
+					return f.type==='update'&&hash(c).then(d=>{if(synced===f.content){sd=d;base.content===f.content&&(base.digest=d)}})
+				}).catch(()=>{});
+				return true
+			},
+			sync=()=>{pending={type:'sync',base:base.rev,base_content:base.content,base_digest:base.digest,content:w.value};send(pending)},
+			resolve=c=>{
+				// Whatever is chosen is based on the server content shown in the conflict.
+				cf.style.display='none';conflict=null;w.value=c;base={rev,content:synced,digest:sd};
+				dirty=c!==synced&&!send({type:'update',content:c});dirty||(synced=c,base={rev,content:c});keep();updatePreview()
+			},
+
+			nl=t=>t&&!t.endsWith('\n')?t+'\n':t,
+			b64=u=>{let r='';for(let i=0;i<u.length;i+=32768)r+=String.fromCharCode.apply(null,u.subarray(i,i+32768));return btoa(r)},
+			unlock=()=>{
+				if(!e2e||ck)return Promise.resolve();
+				let pass=new URLSearchParams(location.hash.slice(1)).get('key');
+				if(!pass&&crypto.subtle&&(pass=prompt('Passphrase of this end-to-end encrypted board')))history.replaceState(null,'','#key='+encodeURIComponent(pass));
+				if(!pass||!crypto.subtle)return Promise.reject(new Error('locked'));
+				const te=new TextEncoder();
+				return crypto.subtle.importKey('raw',te.encode(pass),'PBKDF2',false,['deriveKey'])
+					.then(k=>crypto.subtle.deriveKey({name:'PBKDF2',salt:te.encode('boardcast:default'),iterations:310000,hash:'SHA-256'},k,{name:'AES-GCM',length:256},false,['encrypt','decrypt']))
+					.then(k=>{ck=k})
+			},
+			seal=t=>{
+				if(!e2e||!t)return Promise.resolve(t);
+				const iv=crypto.getRandomValues(new Uint8Array(12));
+				return crypto.subtle.encrypt({name:'AES-GCM',iv},ck,new TextEncoder().encode(t)).then(c=>{
+					const u=new Uint8Array(12+c.byteLength);u.set(iv);u.set(new Uint8Array(c),12);return 'e2e1:'+b64(u)
+				})
+			},
+			unseal=t=>{
+				if(!e2e||!t)return Promise.resolve(t);
+				if(!t.startsWith('e2e1:'))return Promise.reject(new Error('not encrypted'));
+				const u=Uint8Array.from(atob(t.slice(5)),c=>c.charCodeAt(0));
+				return crypto.subtle.decrypt({name:'AES-GCM',iv:u.subarray(0,12)},ck,u.subarray(12)).then(b=>new TextDecoder().decode(b))
+			},
+			// hash is the hex SHA-256 digest of content of encrypted boards.
+			hash=t=>e2e?crypto.subtle.digest('SHA-256',new TextEncoder().encode(t)).then(b=>Array.from(new Uint8Array(b),x=>x.toString(16).padStart(2,'0')).join('')):Promise.resolve(),
+			unwrap=f=>Promise.all([unseal(f.content||''),unseal(f.merged||''),hash(f.content||'')]).then(([c,m,d])=>Object.assign(f,{content:c,merged:m,digest:d})),
+			opened=d=>d&&Promise.all([unseal(d.content),unseal(d.baseContent)]).then(([content,baseContent])=>({...d,content,baseContent}),()=>null),
+			locked=()=>{
+				// A wrong passphrase is asked for again on reload.
+				ck=null;history.replaceState(null,'',location.pathname+location.search);shown(false);status('disconnected');
+				h.textContent=crypto.subtle?'This board is end-to-end encrypted: reload to enter its passphrase':'End-to-end encrypted boards require HTTPS'
+			},
+
+			upload=f=>{
+				const fd=new FormData();fd.append('file',f,f.name||'pasted');
+				return fetch(basePath+'/api/v1/attachments',{method:'POST',credentials:'include',body:fd})
+					.then(r=>r.ok?r.json():r.text().then(m=>Promise.reject(m)))
+			},
+			attach=files=>{
+				// Attachments would be stored unencrypted.
+				if(!auth||e2e||!files.length)return false;
+				const st=w.selectionStart,en=w.selectionEnd;
+				Promise.all([...files].map(upload)).then(res=>{
+					w.setRangeText(res.map(a=>a.markdown).join('\n'),st,en,'end');w.dispatchEvent(new Event('input'))
+				}).catch(m=>{notice(String(m).trim()||'Upload failed');setTimeout(()=>notice(''),3000)});
+				return true
+			};
 		
 		load();
 		t.onclick=()=>{document.body.classList.toggle('dark');icon();save()};
 		a.onclick=()=>auth?disconnect():authenticate();
-		sb.onclick=()=>snap('/save');
-		rb.onclick=()=>snap('/restore');
+		sb.onclick=()=>snap(basePath+'/save');
+		rb.onclick=()=>snap(basePath+'/restore');
+		document.getElementById('keepMine').onclick=()=>resolve(w.value);
+		document.getElementById('keepServer').onclick=()=>conflict&&resolve(conflict.content);
+		document.getElementById('mergeHand').onclick=()=>conflict&&resolve(conflict.merged);
+		w.oninput=()=>{
+			if(!dirty&&send({type:'update',content:w.value})){updating=true;synced=w.value;base={rev,content:synced};setTimeout(()=>updating=false,50)}
+			else if(!dirty){dirty=true;notice('Offline: edits are kept on this device and synced on reconnect');setTimeout(()=>notice(''),3000)}
+			keep()
+		};
 		p.addEventListener('keypress',e=>e.key==='Enter'&&a.click());
 		p.addEventListener('input',updateButtons);
+		w.addEventListener('paste',e=>attach(e.clipboardData.files)&&e.preventDefault());
+		w.addEventListener('dragover',e=>e.dataTransfer.types.includes('Files')&&e.preventDefault());
+		w.addEventListener('drop',e=>attach(e.dataTransfer.files)&&e.preventDefault());
+		'serviceWorker' in navigator&&navigator.serviceWorker.register(basePath+'/sw.js').catch(()=>{});
 		init()
 	</script>
   <script>
     function updatePreview() {
-      var raw = document.getElementById('whiteboard').value || (typeof initialContent !== 'undefined' ? initialContent : '');
+      var raw = document.getElementById('whiteboard').value || (typeof initialContent !== 'undefined' && !e2e ? initialContent : '');
       var html = raw.trim() ? DOMPurify.sanitize(marked.parse(raw)) : '';
       var inner = document.getElementById('preview-inner');
       if(raw.trim()==='') {
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// Inbound hook modes.
+const (
+	ModeAppend  = "append"
+	ModeSection = "section"
+)
+
+// TokenHeader carries an inbound hook's token when it is not given in the
+// token query parameter.
+const TokenHeader = "X-Boardcast-Token"
+
+var (
+	// ErrInvalidToken is returned when an inbound request has the wrong token.
+	ErrInvalidToken = errors.New("invalid webhook token")
+	// ErrEmptyMessage is returned when a payload renders to no text.
+	ErrEmptyMessage = errors.New("webhook payload rendered an empty message")
+)
+
+// Templates for common payload shapes, selectable by name in InboundHook.Template.
+var builtinTemplates = map[string]string{
+	"slack": `{{.text}}`,
+	"alertmanager": `{{range .alerts}}[{{upper .status}}] {{.labels.alertname}}` +
+		`{{with .annotations.summary}}: {{.}}{{end}}` + "\n" + `{{end}}`,
+	"github-workflow": `{{with .workflow_run}}{{$.repository.full_name}}: {{.name}} #{{.run_number}} ` +
+		`{{or .conclusion .status}} on {{.head_branch}}{{end}}`,
+	"gitlab-pipeline": `{{with .object_attributes}}{{$.project.path_with_namespace}}: pipeline #{{.id}} ` +
+		`{{.status}} on {{.ref}}{{end}}`,
+}
+
+// templateFuncs are available to inbound hook templates.
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// InboundHook is the configuration of one inbound webhook URL. Mode is
+// "append" (the default) or "section", which replaces the named section of
+// the board. Template is either a built-in name or a text/template applied
+// to the decoded JSON payload; plain text bodies are exposed as .text.
+type InboundHook struct {
+	ID       string `json:"id"`
+	Board    string `json:"board"`
+	Token    string `json:"token"`
+	Mode     string `json:"mode"`
+	Section  string `json:"section"`
+	Template string `json:"template"`
+}
+
+// LoadInboundConfig reads a JSON array of inbound hooks from path and validates it.
+func LoadInboundConfig(path string) ([]InboundHook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []InboundHook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("invalid inbound webhook config: %w", err)
+	}
+
+	if _, err := NewInbound(hooks); err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// Inbound authenticates inbound hook requests and renders their payloads.
+type Inbound struct {
+	hooks map[string]*inboundHook
+}
+
+// inboundHook is an inbound hook with its compiled template.
+type inboundHook struct {
+	InboundHook
+	tmpl *template.Template
+}
+
+// NewInbound validates hooks and compiles their templates.
+func NewInbound(hooks []InboundHook) (*Inbound, error) {
+	in := &Inbound{hooks: make(map[string]*inboundHook)}
+
+	for _, h := range hooks {
+		if h.ID == "" || in.hooks[h.ID] != nil {
+			return nil, fmt.Errorf("inbound webhook IDs must be unique and non-empty: %q", h.ID)
+		}
+		if h.Token == "" {
+			return nil, fmt.Errorf("inbound webhook %s: token is required", h.ID)
+		}
+		if h.Board != "" && h.Board != websocket.DefaultBoard {
+			return nil, fmt.Errorf("inbound webhook %s: unknown board %q", h.ID, h.Board)
+		}
+
+		switch h.Mode {
+		case "":
+			h.Mode = ModeAppend
+		case ModeAppend:
+		case ModeSection:
+			if strings.TrimSpace(h.Section) == "" || strings.Contains(h.Section, "\n") {
+				return nil, fmt.Errorf("inbound webhook %s: section mode needs a single-line section name", h.ID)
+			}
+		default:
+			return nil, fmt.Errorf("inbound webhook %s: unknown mode %q", h.ID, h.Mode)
+		}
+
+		state := &inboundHook{InboundHook: h}
+		if h.Template != "" {
+			text, ok := builtinTemplates[h.Template]
+			if !ok {
+				text = h.Template
+			}
+			tmpl, err := template.New(h.ID).Funcs(templateFuncs).Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("inbound webhook %s: invalid template: %w", h.ID, err)
+			}
+			state.tmpl = tmpl
+		}
+		in.hooks[h.ID] = state
+	}
+
+	return in, nil
+}
+
+// Receive checks token against the hook with the given ID and renders body
+// into the message to write to the board.
+func (in *Inbound) Receive(id, token, contentType string, body []byte) (InboundHook, string, error) {
+	if in == nil || in.hooks[id] == nil {
+		return InboundHook{}, "", ErrHookNotFound
+	}
+
+	h := in.hooks[id]
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+		return InboundHook{}, "", ErrInvalidToken
+	}
+
+	message, err := h.render(contentType, body)
+	if err != nil {
+		return InboundHook{}, "", err
+	}
+	return h.InboundHook, message, nil
+}
+
+// render turns a JSON or plain text payload into a message.
+func (h *inboundHook) render(contentType string, body []byte) (string, error) {
+	var data any
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/json" {
+		if err := json.Unmarshal(body, &data); err != nil {
+			return "", fmt.Errorf("invalid JSON payload: %w", err)
+		}
+	} else {
+		data = map[string]any{"text": string(body)}
+	}
+
+	var message string
+	if h.tmpl != nil {
+		var buf bytes.Buffer
+		if err := h.tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("failed to render template: %w", err)
+		}
+		message = buf.String()
+	} else {
+		message = defaultMessage(data, body)
+	}
+
+	message = strings.TrimRight(message, "\r\n")
+	if strings.TrimSpace(message) == "" {
+		return "", ErrEmptyMessage
+	}
+	return message, nil
+}
+
+// defaultMessage picks the first well-known text field of a JSON object,
+// falling back to the raw payload.
+func defaultMessage(data any, body []byte) string {
+	if fields, ok := data.(map[string]any); ok {
+		for _, key := range []string{"text", "message", "content", "summary"} {
+			if text, ok := fields[key].(string); ok {
+				return text
+			}
+		}
+	}
+	return string(bytes.TrimSpace(body))
+}
+
+// Apply returns content with message written according to the hook's mode.
+// A section whose end marker was deleted gets a new one after the message,
+// keeping everything that followed its header.
+func (h InboundHook) Apply(content, message string) string {
+	if h.Mode != ModeSection {
+		return appendLine(content, message)
+	}
+
+	begin, end := "--- "+h.Section+" ---", "--- end "+h.Section+" ---"
+	start := lineIndex(content, begin, 0)
+	if start < 0 {
+		return appendLine(content, begin+"\n"+message+"\n"+end)
+	}
+	bodyStart := start + len(begin)
+	if stop := lineIndex(content, end, bodyStart); stop >= 0 {
+		return content[:bodyStart] + "\n" + message + "\n" + content[stop:]
+	}
+	rest := content[bodyStart:]
+	if rest == "" {
+		rest = "\n"
+	}
+	return content[:bodyStart] + "\n" + message + "\n" + end + rest
+}
+
+// appendLine appends text on its own line, terminated by a newline.
+func appendLine(content, text string) string {
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + text + "\n"
+}
+
+// lineIndex returns the offset of the first line of content equal to line,
+// starting the search at from, or -1 if there is none.
+func lineIndex(content, line string, from int) int {
+	for offset := from; offset <= len(content); {
+		i := strings.Index(content[offset:], line)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		atStart := i == 0 || content[i-1] == '\n'
+		after := i + len(line)
+		atEnd := after == len(content) || content[after] == '\n' || content[after] == '\r'
+		if atStart && atEnd {
+			return i
+		}
+		offset = i + 1
+	}
+	return -1
+}
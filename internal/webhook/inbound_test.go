@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewInbound(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    InboundHook
+		wantErr bool
+	}{
+		{"append", InboundHook{ID: "ci", Token: "t"}, false},
+		{"default board", InboundHook{ID: "ci", Token: "t", Board: "default"}, false},
+		{"section", InboundHook{ID: "ci", Token: "t", Mode: ModeSection, Section: "Status"}, false},
+		{"builtin template", InboundHook{ID: "ci", Token: "t", Template: "slack"}, false},
+		{"missing ID", InboundHook{Token: "t"}, true},
+		{"missing token", InboundHook{ID: "ci"}, true},
+		{"unknown board", InboundHook{ID: "ci", Token: "t", Board: "ops"}, true},
+		{"unknown mode", InboundHook{ID: "ci", Token: "t", Mode: "prepend"}, true},
+		{"missing section", InboundHook{ID: "ci", Token: "t", Mode: ModeSection}, true},
+		{"multiline section", InboundHook{ID: "ci", Token: "t", Mode: ModeSection, Section: "a\nb"}, true},
+		{"invalid template", InboundHook{ID: "ci", Token: "t", Template: "{{.text"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewInbound([]InboundHook{tt.hook})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewInbound() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewInbound([]InboundHook{{ID: "ci", Token: "a"}, {ID: "ci", Token: "b"}}); err == nil {
+		t.Error("NewInbound() accepted duplicate IDs")
+	}
+}
+
+func TestReceive(t *testing.T) {
+	in, err := NewInbound([]InboundHook{
+		{ID: "plain", Token: "t"},
+		{ID: "alerts", Token: "t", Template: "alertmanager"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		id, token   string
+		contentType string
+		body        string
+		want        string
+		wantErr     error
+	}{
+		{"plain text", "plain", "t", "text/plain", "deployed\n", "deployed", nil},
+		{"json message field", "plain", "t", "application/json", `{"message":"done"}`, "done", nil},
+		{"json without text field", "plain", "t", "application/json; charset=utf-8", ` {"n":1} `, `{"n":1}`, nil},
+		{"template", "alerts", "t", "application/json",
+			`{"alerts":[{"status":"firing","labels":{"alertname":"Disk"},"annotations":{"summary":"full"}}]}`,
+			"[FIRING] Disk: full", nil},
+		{"empty message", "plain", "t", "text/plain", " \n", "", ErrEmptyMessage},
+		{"wrong token", "plain", "x", "text/plain", "hi", "", ErrInvalidToken},
+		{"unknown hook", "other", "t", "text/plain", "hi", "", ErrHookNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, got, err := in.Receive(tt.id, tt.token, tt.contentType, []byte(tt.body))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Receive() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Receive() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	section := InboundHook{Mode: ModeSection, Section: "CI"}
+	tests := []struct {
+		name    string
+		hook    InboundHook
+		content string
+		want    string
+	}{
+		{"append to empty", InboundHook{}, "", "msg\n"},
+		{"append after unterminated line", InboundHook{}, "a", "a\nmsg\n"},
+		{"append", InboundHook{Mode: ModeAppend}, "a\n", "a\nmsg\n"},
+		{"new section", section, "a\n", "a\n--- CI ---\nmsg\n--- end CI ---\n"},
+		{"replace section", section,
+			"a\n--- CI ---\nold\nlines\n--- end CI ---\nb\n",
+			"a\n--- CI ---\nmsg\n--- end CI ---\nb\n"},
+		{"replace empty section", section,
+			"--- CI ---\n--- end CI ---\n",
+			"--- CI ---\nmsg\n--- end CI ---\n"},
+		{"replace crlf section", section,
+			"a\r\n--- CI ---\r\nold\r\n--- end CI ---\r\nb\r\n",
+			"a\r\n--- CI ---\nmsg\n--- end CI ---\r\nb\r\n"},
+		{"marker inside a line", section,
+			"see --- CI --- below\n",
+			"see --- CI --- below\n--- CI ---\nmsg\n--- end CI ---\n"},
+		{"only first section", section,
+			"--- CI ---\nold\n--- end CI ---\n--- CI ---\nkept\n--- end CI ---\n",
+			"--- CI ---\nmsg\n--- end CI ---\n--- CI ---\nkept\n--- end CI ---\n"},
+		// Without its end marker, the message gets a new one and whatever
+		// followed the header is kept rather than a second section appended.
+		{"missing end marker", section,
+			"a\n--- CI ---\nold\nlines\n\n# Notes\nkeep me\n",
+			"a\n--- CI ---\nmsg\n--- end CI ---\nold\nlines\n\n# Notes\nkeep me\n"},
+		{"missing end marker at end", section,
+			"a\n--- CI ---",
+			"a\n--- CI ---\nmsg\n--- end CI ---\n"},
+		{"end marker before begin", section,
+			"--- end CI ---\n--- CI ---\nold\n",
+			"--- end CI ---\n--- CI ---\nmsg\n--- end CI ---\nold\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hook.Apply(tt.content, "msg"); got != tt.want {
+				t.Errorf("Apply() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
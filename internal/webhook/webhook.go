@@ -0,0 +1,409 @@
+// Package webhook delivers signed notifications about board events to external URLs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+)
+
+// Events that hooks can subscribe to. Edits are debounced per client.
+const (
+	EventEdit     = audit.ActionEdit
+	EventSave     = audit.ActionSave
+	EventRestore  = audit.ActionRestore
+	EventJoin     = audit.ActionJoin
+	EventLeave    = audit.ActionLeave
+	EventWipe     = audit.ActionWipe
+	EventConflict = audit.ActionConflict
+	EventPing     = "ping"
+	EventAll      = "*"
+)
+
+// Headers sent with every delivery.
+const (
+	SignatureHeader = "X-Boardcast-Signature"
+	EventHeader     = "X-Boardcast-Event"
+	DeliveryHeader  = "X-Boardcast-Delivery"
+)
+
+const (
+	maxAttempts   = 5
+	initialDelay  = time.Second
+	queueSize     = 64
+	deliveryLimit = 50
+)
+
+// ErrHookNotFound is returned when a hook ID is unknown.
+var ErrHookNotFound = errors.New("webhook not found")
+
+// Hook is the configuration of one outgoing webhook.
+type Hook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// wants reports whether the hook subscribes to event.
+func (h Hook) wants(event string) bool {
+	return event == EventPing || slices.Contains(h.Events, EventAll) || slices.Contains(h.Events, event)
+}
+
+// LoadConfig reads a JSON array of hooks from path and validates it.
+func LoadConfig(path string) ([]Hook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []Hook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		return nil, fmt.Errorf("invalid webhook config: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, h := range hooks {
+		if h.ID == "" || seen[h.ID] {
+			return nil, fmt.Errorf("webhook IDs must be unique and non-empty: %q", h.ID)
+		}
+		seen[h.ID] = true
+
+		if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("webhook %s: invalid URL %q", h.ID, h.URL)
+		}
+		for _, event := range h.Events {
+			switch event {
+			case EventEdit, EventSave, EventRestore, EventJoin, EventLeave, EventWipe, EventConflict, EventAll:
+			default:
+				return nil, fmt.Errorf("webhook %s: unknown event %q", h.ID, event)
+			}
+		}
+	}
+
+	return hooks, nil
+}
+
+// Payload is the JSON body posted to hooks.
+type Payload struct {
+	Event     string    `json:"event"`
+	Board     string    `json:"board,omitempty"`
+	Time      time.Time `json:"time"`
+	User      string    `json:"user,omitempty"`
+	Revision  uint64    `json:"revision,omitempty"`
+	Size      int       `json:"size,omitempty"`
+	SizeDelta int       `json:"size_delta,omitempty"`
+	Edits     int       `json:"edits,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// Delivery records the outcome of sending one payload to a hook.
+type Delivery struct {
+	ID       string        `json:"id"`
+	Event    string        `json:"event"`
+	Time     time.Time     `json:"time"`
+	Attempts int           `json:"attempts"`
+	Status   int           `json:"status,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Succeeded reports whether the receiver acknowledged the delivery.
+func (d Delivery) Succeeded() bool {
+	return d.Status >= 200 && d.Status < 300
+}
+
+// Status is a hook's configuration, without its secret, and its recent deliveries.
+type Status struct {
+	ID         string     `json:"id"`
+	URL        string     `json:"url"`
+	Events     []string   `json:"events"`
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// hookState holds the queue and delivery log of a hook.
+type hookState struct {
+	Hook
+	queue      chan Payload
+	mu         sync.Mutex
+	deliveries []Delivery
+}
+
+// log appends d to the hook's delivery log, keeping the most recent entries.
+func (h *hookState) log(d Delivery) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.deliveries = append(h.deliveries, d)
+	if len(h.deliveries) > deliveryLimit {
+		h.deliveries = h.deliveries[len(h.deliveries)-deliveryLimit:]
+	}
+}
+
+// Dispatcher queues events and delivers them to subscribed hooks in the background.
+type Dispatcher struct {
+	hooks  []*hookState
+	client *http.Client
+	logger *slog.Logger
+	// retryDelay is the wait before the first retry, doubled for each one after.
+	retryDelay time.Duration
+	stop       chan struct{}
+	once       sync.Once
+	wg         sync.WaitGroup
+}
+
+// NewDispatcher creates a dispatcher for hooks and starts one worker per hook.
+func NewDispatcher(hooks []Hook, logger *slog.Logger) *Dispatcher {
+	return newDispatcher(hooks, logger, initialDelay)
+}
+
+// newDispatcher creates a dispatcher that waits retryDelay before the first retry.
+func newDispatcher(hooks []Hook, logger *slog.Logger, retryDelay time.Duration) *Dispatcher {
+	d := &Dispatcher{
+		client:     &http.Client{Timeout: 10 * time.Second},
+		logger:     logger,
+		retryDelay: retryDelay,
+		stop:       make(chan struct{}),
+	}
+
+	for _, h := range hooks {
+		state := &hookState{Hook: h, queue: make(chan Payload, queueSize)}
+		d.hooks = append(d.hooks, state)
+		d.wg.Add(1)
+		go d.worker(state)
+	}
+
+	return d
+}
+
+// Notify queues the event for every hook subscribed to its action.
+func (d *Dispatcher) Notify(e audit.Entry) {
+	if d == nil {
+		return
+	}
+
+	payload := Payload{
+		Event:     e.Action,
+		Board:     e.Board,
+		Time:      e.Time,
+		User:      e.User,
+		Revision:  e.Revision,
+		Size:      e.Size,
+		SizeDelta: e.SizeDelta,
+		Edits:     e.Edits,
+		Reason:    e.Reason,
+	}
+	if payload.Time.IsZero() {
+		payload.Time = time.Now().UTC()
+	}
+
+	for _, h := range d.hooks {
+		if !h.wants(payload.Event) {
+			continue
+		}
+		select {
+		case h.queue <- payload:
+		default:
+			d.logger.Warn("Webhook queue full, dropping event", "webhook", h.ID, "event", payload.Event)
+		}
+	}
+}
+
+// Hooks returns the status of every configured hook.
+func (d *Dispatcher) Hooks() []Status {
+	if d == nil {
+		return []Status{}
+	}
+
+	statuses := make([]Status, 0, len(d.hooks))
+	for _, h := range d.hooks {
+		h.mu.Lock()
+		statuses = append(statuses, Status{
+			ID:         h.ID,
+			URL:        h.URL,
+			Events:     h.Events,
+			Deliveries: append([]Delivery{}, h.deliveries...),
+		})
+		h.mu.Unlock()
+	}
+	return statuses
+}
+
+// Test sends a ping payload to the hook with the given ID once and returns the result.
+func (d *Dispatcher) Test(ctx context.Context, id string) (Delivery, error) {
+	if d == nil {
+		return Delivery{}, ErrHookNotFound
+	}
+
+	for _, h := range d.hooks {
+		if h.ID == id {
+			payload := Payload{Event: EventPing, Time: time.Now().UTC()}
+			delivery := d.deliver(ctx, h, payload, 1)
+			h.log(delivery)
+			return delivery, nil
+		}
+	}
+	return Delivery{}, ErrHookNotFound
+}
+
+// Close stops the workers, abandoning queued events, and waits for in-flight
+// deliveries to finish until ctx is done.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.once.Do(func() { close(d.stop) })
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// worker delivers queued payloads for a single hook.
+func (d *Dispatcher) worker(h *hookState) {
+	defer d.wg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-d.stop
+		cancel()
+	}()
+
+	for {
+		select {
+		case payload := <-h.queue:
+			delivery := d.deliver(ctx, h, payload, maxAttempts)
+			h.log(delivery)
+			if !delivery.Succeeded() {
+				d.logger.Warn("Webhook delivery failed",
+					"webhook", h.ID,
+					"event", payload.Event,
+					"attempts", delivery.Attempts,
+					"status", delivery.Status,
+					"error", delivery.Error,
+				)
+			}
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// deliver posts payload to the hook, retrying failures with exponential backoff.
+func (d *Dispatcher) deliver(ctx context.Context, h *hookState, payload Payload, attempts int) Delivery {
+	delivery := Delivery{
+		ID:    newDeliveryID(),
+		Event: payload.Event,
+		Time:  time.Now().UTC(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	delay := d.retryDelay
+	for delivery.Attempts < attempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				delivery.Error = ctx.Err().Error()
+				return delivery
+			}
+			delay *= 2
+		}
+
+		delivery.Attempts++
+		start := time.Now()
+		status, err := d.post(ctx, h.Hook, delivery.ID, payload.Event, body)
+		delivery.Duration = time.Since(start)
+		delivery.Status = status
+		delivery.Error = ""
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+
+		if delivery.Succeeded() || !retryable(status, err) {
+			break
+		}
+	}
+
+	return delivery
+}
+
+// post sends a single signed request.
+func (d *Dispatcher) post(ctx context.Context, h Hook, id, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "boardcast-webhook")
+	req.Header.Set(EventHeader, event)
+	req.Header.Set(DeliveryHeader, id)
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("receiver returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by
+// the hex-encoded HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryable reports whether a failed attempt should be retried.
+func retryable(status int, err error) bool {
+	if status == 0 {
+		return err != nil
+	}
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// newDeliveryID returns a random delivery identifier.
+func newDeliveryID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
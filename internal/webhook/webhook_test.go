@@ -0,0 +1,282 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+)
+
+// received is a request delivered to a test receiver.
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver returns a server that answers the nth request with status(n)
+// and passes every request to the returned channel.
+func newReceiver(t *testing.T, status func(n int) int) (*httptest.Server, <-chan received) {
+	t.Helper()
+	requests := make(chan received, 16)
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header.Clone(), body: body}
+		w.WriteHeader(status(int(n.Add(1))))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, requests
+}
+
+// newTestDispatcher returns a dispatcher for hooks that retries without waiting long.
+func newTestDispatcher(t *testing.T, hooks ...Hook) *Dispatcher {
+	t.Helper()
+	d := newDispatcher(hooks, slog.New(slog.DiscardHandler), time.Millisecond)
+	t.Cleanup(func() { d.Close(context.Background()) })
+	return d
+}
+
+// next returns the next request from requests, failing if none arrives.
+func next(t *testing.T, requests <-chan received) received {
+	t.Helper()
+	select {
+	case r := <-requests:
+		return r
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery")
+		return received{}
+	}
+}
+
+// waitDeliveries waits until the hook has logged n deliveries and returns them.
+func waitDeliveries(t *testing.T, d *Dispatcher, n int) []Delivery {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries := d.Hooks()[0].Deliveries
+		if len(deliveries) >= n {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries = %+v, want %d", deliveries, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSign(t *testing.T) {
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{"valid", `[{"id":"a","url":"https://example.com/hook","events":["edit","save"]},{"id":"b","url":"http://localhost/","events":["*"]}]`, ""},
+		{"wipe and conflict", `[{"id":"a","url":"https://example.com/hook","events":["wipe","conflict"]}]`, ""},
+		{"invalid JSON", `{`, "invalid webhook config"},
+		{"missing ID", `[{"url":"https://example.com/hook"}]`, "unique and non-empty"},
+		{"duplicate ID", `[{"id":"a","url":"https://example.com/"},{"id":"a","url":"https://example.com/"}]`, "unique and non-empty"},
+		{"invalid URL", `[{"id":"a","url":"ftp://example.com/"}]`, "invalid URL"},
+		{"unknown event", `[{"id":"a","url":"https://example.com/","events":["ping"]}]`, "unknown event"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "webhooks.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadConfig(path)
+			if tt.err == "" && err != nil {
+				t.Fatalf("LoadConfig = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("LoadConfig = %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestDispatcherDelivery(t *testing.T) {
+	srv, requests := newReceiver(t, func(int) int { return http.StatusNoContent })
+	d := newTestDispatcher(t, Hook{ID: "a", URL: srv.URL, Secret: "s3cret", Events: []string{EventEdit, EventWipe}})
+
+	// Events the hook does not subscribe to are not delivered.
+	d.Notify(audit.Entry{Action: audit.ActionSave, Board: "default"})
+	d.Notify(audit.Entry{Action: audit.ActionEdit, Board: "default", Actor: audit.Actor{User: "alice"}, Revision: 3, Size: 5, SizeDelta: 2, Edits: 4})
+	d.Notify(audit.Entry{Action: audit.ActionWipe, Board: "default", Reason: "ttl"})
+
+	for _, want := range []Payload{
+		{Event: EventEdit, Board: "default", User: "alice", Revision: 3, Size: 5, SizeDelta: 2, Edits: 4},
+		{Event: EventWipe, Board: "default", Reason: "ttl"},
+	} {
+		r := next(t, requests)
+		if got := r.header.Get(SignatureHeader); got != Sign("s3cret", r.body) {
+			t.Errorf("%s = %q, want %q", SignatureHeader, got, Sign("s3cret", r.body))
+		}
+		if got := r.header.Get(EventHeader); got != want.Event {
+			t.Errorf("%s = %q, want %q", EventHeader, got, want.Event)
+		}
+		if r.header.Get(DeliveryHeader) == "" {
+			t.Errorf("%s is missing", DeliveryHeader)
+		}
+
+		var got Payload
+		if err := json.Unmarshal(r.body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Time.IsZero() {
+			t.Error("payload has no time")
+		}
+		got.Time = time.Time{}
+		if got != want {
+			t.Errorf("payload = %+v, want %+v", got, want)
+		}
+	}
+
+	deliveries := waitDeliveries(t, d, 2)
+	for _, delivery := range deliveries {
+		if !delivery.Succeeded() || delivery.Attempts != 1 {
+			t.Errorf("delivery = %+v, want one successful attempt", delivery)
+		}
+	}
+}
+
+func TestDispatcherUnsigned(t *testing.T) {
+	srv, requests := newReceiver(t, func(int) int { return http.StatusOK })
+	d := newTestDispatcher(t, Hook{ID: "a", URL: srv.URL, Events: []string{EventAll}})
+
+	d.Notify(audit.Entry{Action: audit.ActionConflict})
+	if r := next(t, requests); r.header.Get(SignatureHeader) != "" {
+		t.Errorf("%s = %q, want none without a secret", SignatureHeader, r.header.Get(SignatureHeader))
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   func(n int) int
+		attempts int
+		want     int
+	}{
+		{"server error", func(n int) int {
+			if n < 3 {
+				return http.StatusInternalServerError
+			}
+			return http.StatusOK
+		}, 3, http.StatusOK},
+		{"rate limited", func(n int) int {
+			if n < 2 {
+				return http.StatusTooManyRequests
+			}
+			return http.StatusOK
+		}, 2, http.StatusOK},
+		{"client error", func(int) int { return http.StatusBadRequest }, 1, http.StatusBadRequest},
+		{"gives up", func(int) int { return http.StatusBadGateway }, maxAttempts, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, requests := newReceiver(t, tt.status)
+			d := newTestDispatcher(t, Hook{ID: "a", URL: srv.URL, Events: []string{EventSave}})
+
+			d.Notify(audit.Entry{Action: audit.ActionSave})
+			delivery := waitDeliveries(t, d, 1)[0]
+			if delivery.Attempts != tt.attempts || delivery.Status != tt.want {
+				t.Errorf("delivery = %+v, want %d attempts ending with %d", delivery, tt.attempts, tt.want)
+			}
+			if got := len(requests); got != tt.attempts {
+				t.Errorf("receiver got %d requests, want %d", got, tt.attempts)
+			}
+
+			// Retries of one delivery share its ID.
+			id := next(t, requests).header.Get(DeliveryHeader)
+			for len(requests) > 0 {
+				if got := next(t, requests).header.Get(DeliveryHeader); got != id {
+					t.Errorf("retry %s = %q, want %q", DeliveryHeader, got, id)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatcherUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	d := newTestDispatcher(t, Hook{ID: "a", URL: url, Events: []string{EventAll}})
+	d.Notify(audit.Entry{Action: audit.ActionJoin})
+	delivery := waitDeliveries(t, d, 1)[0]
+	if delivery.Attempts != maxAttempts || delivery.Status != 0 || delivery.Error == "" {
+		t.Errorf("delivery = %+v, want %d failed attempts", delivery, maxAttempts)
+	}
+}
+
+func TestDispatcherTest(t *testing.T) {
+	srv, requests := newReceiver(t, func(int) int { return http.StatusOK })
+	// Pings reach hooks whatever events they subscribe to.
+	d := newTestDispatcher(t, Hook{ID: "a", URL: srv.URL, Secret: "s", Events: []string{EventSave}})
+
+	delivery, err := d.Test(context.Background(), "a")
+	if err != nil || !delivery.Succeeded() {
+		t.Fatalf("Test = %+v, %v", delivery, err)
+	}
+	r := next(t, requests)
+	if got := r.header.Get(EventHeader); got != EventPing {
+		t.Errorf("%s = %q, want %q", EventHeader, got, EventPing)
+	}
+	if got := r.header.Get(SignatureHeader); got != Sign("s", r.body) {
+		t.Errorf("%s = %q, want %q", SignatureHeader, got, Sign("s", r.body))
+	}
+	if got := d.Hooks()[0].Deliveries; len(got) != 1 || got[0].ID != delivery.ID {
+		t.Errorf("deliveries = %+v, want the ping", got)
+	}
+
+	if _, err := d.Test(context.Background(), "b"); !errors.Is(err, ErrHookNotFound) {
+		t.Errorf("Test of an unknown hook = %v, want ErrHookNotFound", err)
+	}
+}
+
+func TestDeliveryLog(t *testing.T) {
+	h := &hookState{}
+	for i := range deliveryLimit + 10 {
+		h.log(Delivery{Attempts: i})
+	}
+	if len(h.deliveries) != deliveryLimit {
+		t.Fatalf("log holds %d deliveries, want %d", len(h.deliveries), deliveryLimit)
+	}
+	if first, last := h.deliveries[0].Attempts, h.deliveries[deliveryLimit-1].Attempts; first != 10 || last != deliveryLimit+9 {
+		t.Errorf("log holds deliveries %d to %d, want the most recent", first, last)
+	}
+}
+
+func TestNilDispatcher(t *testing.T) {
+	var d *Dispatcher
+	d.Notify(audit.Entry{Action: audit.ActionEdit})
+	if got := d.Hooks(); len(got) != 0 {
+		t.Errorf("Hooks = %+v, want none", got)
+	}
+	if _, err := d.Test(context.Background(), "a"); !errors.Is(err, ErrHookNotFound) {
+		t.Errorf("Test = %v, want ErrHookNotFound", err)
+	}
+	if err := d.Close(context.Background()); err != nil {
+		t.Errorf("Close = %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/backplane"
+)
+
+// newInstanceID returns a random identifier distinguishing this hub's
+// messages on the backplane.
+func newInstanceID() string {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// deliver broadcasts message to local clients and watchers and relays local
+// changes to the backplane.
+func (h *Hub) deliver(message BroadcastMessage) {
+	h.broadcastToClients(message.content, message.revision, message.sender, message.reason)
+	h.notifyWatchers(message.content, message.revision)
+	if message.remote || h.backplane == nil {
+		return
+	}
+	h.relay(message.content, message.revision)
+}
+
+// relay publishes the board state to other instances.
+func (h *Hub) relay(content string, revision uint64) {
+	err := h.backplane.Publish(backplane.Message{
+		Type:     backplane.TypeUpdate,
+		Origin:   h.instanceID,
+		Board:    DefaultBoard,
+		Content:  content,
+		Revision: revision,
+	})
+	if err != nil {
+		h.logger.Warn("Failed to relay update to backplane", "board", DefaultBoard, "error", err)
+	}
+}
+
+// receive handles a backplane message.
+func (h *Hub) receive(m backplane.Message) {
+	switch m.Type {
+	case backplane.TypeConnected:
+		// Updates may have been missed in either direction while disconnected.
+		if err := h.backplane.Publish(backplane.Message{Type: backplane.TypeSync, Origin: h.instanceID}); err != nil {
+			h.logger.Warn("Failed to request backplane sync", "error", err)
+		}
+		if content, revision := h.GetState(); revision > 0 {
+			h.relay(content, revision)
+		}
+	case backplane.TypeSync:
+		if m.Origin == h.instanceID {
+			return
+		}
+		if content, revision := h.GetState(); revision > 0 {
+			h.relay(content, revision)
+		}
+	case backplane.TypeUpdate:
+		if m.Origin != h.instanceID && m.Board == DefaultBoard {
+			h.applyRemote(m)
+		}
+	}
+}
+
+// applyRemote adopts content from another instance if it is newer. Revisions
+// act as a Lamport clock: the higher revision wins and ties go to the higher
+// instance ID, so every instance converges on the same content.
+func (h *Hub) applyRemote(m backplane.Message) {
+	h.mu.Lock()
+	if h.closing || m.Revision < h.revision || (m.Revision == h.revision && m.Origin <= h.instanceID) {
+		h.mu.Unlock()
+		return
+	}
+	h.content = m.Content
+	h.revision = m.Revision
+	h.mu.Unlock()
+	h.edited(audit.Actor{})
+
+	h.enqueue(BroadcastMessage{content: m.Content, revision: m.Revision, remote: true})
+}
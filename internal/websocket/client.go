@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+)
+
+// client is a single WebSocket connection together with its identity.
+type client struct {
+	id      uint64
+	conn    *websocket.Conn
+	logger  *slog.Logger
+	actor   audit.Actor
+	v1      bool
+	writeMu sync.Mutex
+	// threshold is the smallest message size worth compressing.
+	threshold int
+
+	connBucket *bucket
+	userBucket *bucket
+	throttled  bool
+
+	// deferred is the latest update refused by the rate limits of a client
+	// without ProtocolV1, applied by deferTimer once they allow it.
+	deferMu    sync.Mutex
+	deferred   *string
+	deferTimer *time.Timer
+
+	editMu      sync.Mutex
+	pendingEdit *audit.Entry
+	editTimer   *time.Timer
+}
+
+// write sends a text message, serializing writers on the connection.
+func (c *client) write(message []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.EnableWriteCompression(len(message) >= c.threshold)
+	return c.conn.WriteMessage(websocket.TextMessage, message)
+}
+
+// writePrepared sends a prepared message of size bytes, reusing its frames
+// across every connection with the same compression settings.
+func (c *client) writePrepared(pm *websocket.PreparedMessage, size int) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.EnableWriteCompression(size >= c.threshold)
+	return c.conn.WritePreparedMessage(pm)
+}
+
+// ping sends a ping control frame to check that the peer is alive.
+func (c *client) ping() error {
+	return c.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second))
+}
@@ -0,0 +1,43 @@
+package websocket
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// etagDigestLength is the number of hex digits of the content digest kept in
+// an entity tag.
+const etagDigestLength = 16
+
+// Digest returns the hex encoded SHA-256 digest of content.
+func Digest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ETag returns the strong entity tag of content at revision, such as
+// "12-3f2a9c0e4b7d1a65". Revisions restart with the server, so the tag also
+// carries a digest of the content: a tag taken before a restart cannot match
+// different content that happens to reach the same revision.
+func ETag(content string, revision uint64) string {
+	return `"` + strconv.FormatUint(revision, 10) + "-" + Digest(content)[:etagDigestLength] + `"`
+}
+
+// ParseETag extracts the revision from an entity tag made by ETag.
+func ParseETag(tag string) (uint64, bool) {
+	tag = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(tag), "W/"))
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	rev, digest, ok := strings.Cut(tag[1:len(tag)-1], "-")
+	if !ok || len(digest) != etagDigestLength {
+		return 0, false
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return 0, false
+	}
+	revision, err := strconv.ParseUint(rev, 10, 64)
+	return revision, err == nil
+}
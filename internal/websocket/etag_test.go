@@ -0,0 +1,37 @@
+package websocket
+
+import "testing"
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		revision uint64
+		ok       bool
+	}{
+		{ETag("hello", 12), 12, true},
+		{"W/" + ETag("", 0), 0, true},
+		{` "3-0123456789abcdef" `, 3, true},
+		{`"3"`, 0, false},
+		{`"3-0123"`, 0, false},
+		{`"3-0123456789abcdeg"`, 0, false},
+		{`"x-0123456789abcdef"`, 0, false},
+		{`3-0123456789abcdef`, 0, false},
+		{`"`, 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		revision, ok := ParseETag(tt.tag)
+		if revision != tt.revision || ok != tt.ok {
+			t.Errorf("ParseETag(%q) = %d, %v, want %d, %v", tt.tag, revision, ok, tt.revision, tt.ok)
+		}
+	}
+}
+
+func TestETagDependsOnContent(t *testing.T) {
+	if ETag("a", 1) == ETag("b", 1) {
+		t.Error("ETag is the same for different content at the same revision")
+	}
+	if ETag("a", 1) == ETag("a", 2) {
+		t.Error("ETag is the same for the same content at different revisions")
+	}
+}
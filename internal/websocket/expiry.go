@@ -0,0 +1,189 @@
+package websocket
+
+import (
+	"errors"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/storage"
+)
+
+// Reasons sent with wiped content.
+const (
+	WipeExpired = "expired"
+	WipeBurned  = "burned"
+)
+
+// defaultBurnDelay is how long content of a burn-after-reading board stays
+// after a recipient first viewed it, giving them time to copy it.
+const defaultBurnDelay = 30 * time.Second
+
+// errInvalidTTL is returned for a negative TTL.
+var errInvalidTTL = errors.New("TTL must not be negative")
+
+// loadExpiry adopts the saved expiry of the board and schedules the wipe of
+// content left from before a restart, counting from its last recorded version.
+func (h *Hub) loadExpiry() {
+	if h.settings != nil {
+		e, err := h.settings.LoadExpiry(DefaultBoard)
+		if err == nil {
+			h.expiry = e
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			h.logger.Error("Failed to load board expiry", "board", DefaultBoard, "error", err)
+		}
+	}
+
+	edited := time.Now()
+	if versions, err := h.Versions(); err == nil && len(versions) > 0 {
+		edited = versions[len(versions)-1].Time
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastEdit = edited
+	h.scheduleExpiry()
+}
+
+// Expiry returns the expiry of the board and, when its content is set to
+// expire, the time it will be wiped.
+func (h *Hub) Expiry() (storage.Expiry, time.Time) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.expiry.TTL <= 0 || h.content == "" {
+		return h.expiry, time.Time{}
+	}
+	return h.expiry, h.lastEdit.Add(h.expiry.TTL)
+}
+
+// SetExpiry changes and saves the expiry of the board. A TTL applies from the
+// last edit, so content already older than it is wiped at once.
+func (h *Hub) SetExpiry(e storage.Expiry) error {
+	if e.TTL < 0 {
+		return errInvalidTTL
+	}
+	if h.settings != nil {
+		if err := h.settings.SaveExpiry(DefaultBoard, e); err != nil {
+			return err
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expiry = e
+	if !e.BurnAfterReading && h.burnTimer != nil {
+		h.burnTimer.Stop()
+		h.burnTimer = nil
+	}
+	h.scheduleExpiry()
+	return nil
+}
+
+// View returns the current content and revision read by actor. On a
+// burn-after-reading board this starts the countdown to wiping the content.
+func (h *Hub) View(actor audit.Actor) (string, uint64) {
+	content, revision := h.GetState()
+	h.viewed(actor)
+	return content, revision
+}
+
+// edited records an edit by actor, restarting the TTL of the content.
+func (h *Hub) edited(actor audit.Actor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.author = readerKey(actor)
+	h.lastEdit = time.Now()
+	h.scheduleExpiry()
+}
+
+// viewed records that actor was shown the content. Once someone other than
+// its author views the content of a burn-after-reading board it is wiped
+// after burnDelay. Requests made with the API token come from programs, such
+// as a replicating server, and are not counted.
+func (h *Hub) viewed(actor audit.Actor) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.expiry.BurnAfterReading || h.content == "" || h.burnTimer != nil || h.closing ||
+		actor.User == auth.TokenUser || readerKey(actor) == h.author {
+		return
+	}
+	h.logger.Info("Board viewed by recipient, burning", "board", DefaultBoard, "user", actor.User, "delay", h.burnDelay)
+	h.burnTimer = time.AfterFunc(h.burnDelay, func() { h.wipe(WipeBurned) })
+}
+
+// scheduleExpiry arms the TTL timer for the current content. h.mu must be held.
+func (h *Hub) scheduleExpiry() {
+	if h.expiryTimer != nil {
+		h.expiryTimer.Stop()
+		h.expiryTimer = nil
+	}
+	if h.expiry.TTL <= 0 || h.content == "" || h.closing {
+		return
+	}
+	h.expiryTimer = time.AfterFunc(time.Until(h.lastEdit.Add(h.expiry.TTL)), h.expire)
+}
+
+// expire wipes the content once its TTL has passed since the last edit.
+func (h *Hub) expire() {
+	h.mu.Lock()
+	if h.expiry.TTL <= 0 || time.Since(h.lastEdit) < h.expiry.TTL {
+		// Edited after the timer fired.
+		h.scheduleExpiry()
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+	h.wipe(WipeExpired)
+}
+
+// wipe clears the content, purges the snapshot and history of the board and
+// broadcasts the empty board to clients with reason.
+func (h *Hub) wipe(reason string) {
+	h.mu.Lock()
+	h.stopExpiryTimers()
+	if h.closing || h.content == "" {
+		h.mu.Unlock()
+		return
+	}
+	h.content = ""
+	h.revision++
+	revision := h.revision
+	// Nothing is left to save or to keep as a version.
+	h.saved = revision
+	h.versioned = revision
+	h.mu.Unlock()
+
+	if err := storage.Purge(h.store, DefaultBoard); err != nil {
+		h.logger.Error("Failed to purge board", "board", DefaultBoard, "error", err)
+	}
+	h.metrics.BoardWipes.Inc(DefaultBoard, reason)
+	h.record(audit.Entry{
+		Action:   audit.ActionWipe,
+		Actor:    SystemActor,
+		Revision: revision,
+		Reason:   reason,
+	})
+	h.enqueue(BroadcastMessage{revision: revision, reason: reason})
+	h.logger.Info("Board wiped", "board", DefaultBoard, "reason", reason)
+}
+
+// stopExpiryTimers stops pending wipes. h.mu must be held.
+func (h *Hub) stopExpiryTimers() {
+	if h.expiryTimer != nil {
+		h.expiryTimer.Stop()
+		h.expiryTimer = nil
+	}
+	if h.burnTimer != nil {
+		h.burnTimer.Stop()
+		h.burnTimer = nil
+	}
+}
+
+// readerKey identifies the person behind actor: the user, or the session
+// for anonymous users.
+func readerKey(actor audit.Actor) string {
+	if (actor.User == "" || actor.User == auth.AnonymousUser) && actor.Session != "" {
+		return "session:" + actor.Session
+	}
+	return actor.User
+}
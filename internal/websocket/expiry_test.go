@@ -0,0 +1,159 @@
+package websocket
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/storage"
+)
+
+// waitContent polls h until it holds want, failing the test after a while.
+func waitContent(t *testing.T, h *Hub, want string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); h.GetContent() != want; {
+		if time.Now().After(deadline) {
+			t.Fatalf("board = %q, want %q", h.GetContent(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExpiryTTL(t *testing.T) {
+	h, srv := newTestHub(t, Options{}, "secret")
+	h.Start()
+	t.Cleanup(h.Stop)
+	if err := h.SaveSnapshot(audit.Actor{User: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	conn := dial(t, srv, true)
+
+	if err := h.SetExpiry(storage.Expiry{TTL: -time.Second}); err == nil {
+		t.Error("SetExpiry() accepted a negative TTL")
+	}
+	if err := h.SetExpiry(storage.Expiry{TTL: 200 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if _, at := h.Expiry(); at.IsZero() {
+		t.Error("Expiry() has no wipe time for content with a TTL")
+	}
+
+	// An edit restarts the TTL.
+	time.Sleep(120 * time.Millisecond)
+	if _, err := h.Update(audit.Actor{User: "alice"}, nil, func(string) (string, error) { return "edited", nil }); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(120 * time.Millisecond)
+	if got := h.GetContent(); got != "edited" {
+		t.Fatalf("board = %q before the TTL passed since the edit", got)
+	}
+
+	waitContent(t, h, "")
+	for {
+		f := readTestFrame(t, conn)
+		if f.Type == FrameContent && f.Content == "" {
+			if f.Reason != WipeExpired {
+				t.Errorf("wipe reason = %q, want %q", f.Reason, WipeExpired)
+			}
+			break
+		}
+	}
+	if _, err := h.LoadSnapshot(); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("LoadSnapshot() error = %v, want the snapshot purged", err)
+	}
+	if versions, _ := h.Versions(); len(versions) != 0 {
+		t.Errorf("history holds %d versions after the wipe", len(versions))
+	}
+	if _, at := h.Expiry(); !at.IsZero() {
+		t.Errorf("Expiry() = %v for an empty board", at)
+	}
+}
+
+func TestBurnAfterReading(t *testing.T) {
+	const burnDelay = 20 * time.Millisecond
+	tests := []struct {
+		name string
+		// read views the board, whose content alice wrote.
+		read func(t *testing.T, h *Hub, srv *httptest.Server)
+		want bool
+	}{
+		{"recipient connects", func(t *testing.T, h *Hub, srv *httptest.Server) { dialAs(t, srv, true, "bob") }, true},
+		{"recipient views", func(t *testing.T, h *Hub, srv *httptest.Server) { h.View(audit.Actor{User: "bob"}) }, true},
+		{"anonymous recipient views", func(t *testing.T, h *Hub, srv *httptest.Server) {
+			h.View(audit.Actor{User: auth.AnonymousUser, Session: "s2"})
+		}, true},
+		{"author connects", func(t *testing.T, h *Hub, srv *httptest.Server) { dialAs(t, srv, true, "alice") }, false},
+		{"author views", func(t *testing.T, h *Hub, srv *httptest.Server) { h.View(audit.Actor{User: "alice"}) }, false},
+		{"raw client connects", func(t *testing.T, h *Hub, srv *httptest.Server) { dialAs(t, srv, false, "bob") }, false},
+		{"token client connects", func(t *testing.T, h *Hub, srv *httptest.Server) { dialAs(t, srv, true, auth.TokenUser) }, false},
+		{"token request", func(t *testing.T, h *Hub, srv *httptest.Server) { h.View(audit.Actor{User: auth.TokenUser}) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, srv := newTestHub(t, Options{BurnDelay: burnDelay}, "")
+			h.Start()
+			t.Cleanup(h.Stop)
+			if _, err := h.Update(audit.Actor{User: "alice"}, nil, func(string) (string, error) { return "secret", nil }); err != nil {
+				t.Fatal(err)
+			}
+			if err := h.SetExpiry(storage.Expiry{BurnAfterReading: true}); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.read(t, h, srv)
+			if tt.want {
+				waitContent(t, h, "")
+				return
+			}
+			time.Sleep(10 * burnDelay)
+			if h.GetContent() == "" {
+				t.Error("board burned")
+			}
+		})
+	}
+}
+
+// TestBurnOnBroadcast checks that content broadcast to a recipient counts as
+// read.
+func TestBurnOnBroadcast(t *testing.T) {
+	h, srv := newTestHub(t, Options{BurnDelay: 20 * time.Millisecond}, "")
+	h.Start()
+	t.Cleanup(h.Stop)
+	author, recipient := dialAs(t, srv, true, "alice"), dialAs(t, srv, true, "bob")
+	if err := h.SetExpiry(storage.Expiry{BurnAfterReading: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := h.Update(audit.Actor{User: "alice"}, nil, func(string) (string, error) { return "secret", nil }); err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range []*websocket.Conn{author, recipient} {
+		if f := readTestFrame(t, conn); f.Content != "secret" {
+			t.Fatalf("broadcast %+v, want the edit", f)
+		}
+	}
+	waitContent(t, h, "")
+	if f := readTestFrame(t, recipient); f.Content != "" || f.Reason != WipeBurned {
+		t.Errorf("broadcast %+v, want the content burned", f)
+	}
+}
+
+func TestBurnAfterReadingDisabled(t *testing.T) {
+	h, _ := newTestHub(t, Options{BurnDelay: time.Millisecond}, "secret")
+	if err := h.SetExpiry(storage.Expiry{BurnAfterReading: true}); err != nil {
+		t.Fatal(err)
+	}
+	h.View(audit.Actor{User: "bob"})
+	// Turning burn-after-reading off stops a pending burn.
+	if err := h.SetExpiry(storage.Expiry{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := h.GetContent(); got != "secret" {
+		t.Errorf("board = %q, want it kept", got)
+	}
+}
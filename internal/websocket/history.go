@@ -0,0 +1,90 @@
+package websocket
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/storage"
+)
+
+// ErrNoHistory is returned when the store keeps no board history.
+var ErrNoHistory = errors.New("store keeps no history")
+
+// recordVersion appends the current content to the board history on behalf
+// of user, unless that revision was already recorded.
+func (h *Hub) recordVersion(user string) {
+	if h.history == nil {
+		return
+	}
+
+	h.mu.Lock()
+	content, revision := h.content, h.revision
+	if revision == h.versioned {
+		h.mu.Unlock()
+		return
+	}
+	h.versioned = revision
+	h.mu.Unlock()
+
+	now := time.Now().UTC()
+	err := h.history.AppendVersion(DefaultBoard, storage.Version{
+		ID:       storage.NewVersionID(now),
+		Revision: revision,
+		Time:     now,
+		User:     user,
+		Size:     len(content),
+		Content:  content,
+	})
+	if err != nil {
+		h.logger.Error("Failed to record version", "board", DefaultBoard, "revision", revision, "error", err)
+	}
+}
+
+// Versions lists the board history without content, oldest first.
+func (h *Hub) Versions() ([]storage.Version, error) {
+	if h.history == nil {
+		return nil, ErrNoHistory
+	}
+	versions, err := h.history.Versions(DefaultBoard)
+	if err != nil {
+		return nil, err
+	}
+	// Versions mirrored from other servers are appended when they arrive.
+	slices.SortStableFunc(versions, func(a, b storage.Version) int {
+		return a.Time.Compare(b.Time)
+	})
+	return versions, nil
+}
+
+// Version returns a version of the board including its content.
+func (h *Hub) Version(id string) (storage.Version, error) {
+	if h.history == nil {
+		return storage.Version{}, ErrNoHistory
+	}
+	return h.history.LoadVersion(DefaultBoard, id)
+}
+
+// ImportVersion adds a version recorded elsewhere to the board history. It
+// reports false if a version with the same ID is already present.
+func (h *Hub) ImportVersion(v storage.Version) (bool, error) {
+	if h.history == nil {
+		return false, ErrNoHistory
+	}
+	if !storage.ValidVersionID(v.ID) {
+		return false, storage.ErrVersionNotFound
+	}
+
+	h.importMu.Lock()
+	defer h.importMu.Unlock()
+
+	versions, err := h.history.Versions(DefaultBoard)
+	if err != nil {
+		return false, err
+	}
+	if slices.ContainsFunc(versions, func(known storage.Version) bool { return known.ID == v.ID }) {
+		return false, nil
+	}
+	v.Size = len(v.Content)
+	return true, h.history.AppendVersion(DefaultBoard, v)
+}
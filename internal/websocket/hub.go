@@ -2,40 +2,156 @@
 package websocket
 
 import (
-	"log"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
-	"os"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/backplane"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/storage"
 )
 
-// BroadcastMessage represents a message to broadcast with sender information.
+// DefaultBoard is the name of the board served by the hub.
+const DefaultBoard = "default"
+
+var (
+	// ErrRevisionMismatch is returned when a conditional update targets a stale revision.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+	// ErrShuttingDown is returned when an update arrives after shutdown began.
+	ErrShuttingDown = errors.New("hub is shutting down")
+)
+
+// restartReason is sent in the close frame when the server shuts down.
+const restartReason = "server restarting"
+
+// SystemActor is recorded for changes made by the server itself.
+var SystemActor = audit.Actor{User: "system"}
+
+// Options configures a Hub.
+type Options struct {
+	Store   storage.Store
+	Logger  *slog.Logger
+	Metrics *metrics.Metrics
+	Audit   audit.Log
+	Limits  Limits
+	Rates   RateLimits
+	// Compression configures permessage-deflate for clients that offer it.
+	Compression Compression
+	// Backplane, when set, relays updates to hubs of other instances.
+	Backplane backplane.Backplane
+	// BroadcastTick is how long updates are gathered before the latest is
+	// broadcast. Zero uses DefaultBroadcastTick; negative broadcasts at once.
+	BroadcastTick time.Duration
+	// OnEvent, when set, is called for edits, saves, restores, joins, leaves and wipes.
+	OnEvent func(audit.Entry)
+	// Expiry applies to the board until another is saved with SetExpiry.
+	Expiry storage.Expiry
+	// BurnDelay is how long content of a burn-after-reading board stays after
+	// a recipient first viewed it. Zero uses 30 seconds.
+	BurnDelay time.Duration
+}
+
+// BroadcastMessage represents content to broadcast with sender information.
+// Remote messages came from the backplane and are not relayed back to it.
 type BroadcastMessage struct {
-	data   []byte
-	sender *websocket.Conn
+	content  string
+	revision uint64
+	sender   *client
+	remote   bool
+	// reason is set when the content was wiped.
+	reason string
 }
 
 // Hub manages WebSocket connections and broadcasting.
 type Hub struct {
-	clients   map[*websocket.Conn]bool
+	clients   map[*client]bool
 	content   string
+	revision  uint64
+	saved     uint64
+	closing   bool
+	conns     sync.WaitGroup
 	broadcast chan BroadcastMessage
 	upgrader  websocket.Upgrader
 	mu        sync.RWMutex
-	cleanup   chan struct{}
+	stop      chan struct{}
+	stopOnce  sync.Once
+	running   atomic.Int32
+	nextID    atomic.Uint64
+	store     storage.Store
+	logger    *slog.Logger
+	metrics   *metrics.Metrics
+	audit     audit.Log
+	limits    Limits
+	rates     RateLimits
+	users     userBuckets
+	tick      time.Duration
+	compress  Compression
+	onEvent   func(audit.Entry)
+	watchers  []func(content string, revision uint64)
+
+	history   storage.History
+	versioned uint64
+	importMu  sync.Mutex
+
+	settings    storage.Settings
+	expiry      storage.Expiry
+	author      string
+	lastEdit    time.Time
+	expiryTimer *time.Timer
+	burnTimer   *time.Timer
+	burnDelay   time.Duration
+
+	backplane   backplane.Backplane
+	instanceID  string
+	unsubscribe func()
 }
 
-// NewHub creates a new WebSocket hub.
-func NewHub() *Hub {
+// NewHub creates a new WebSocket hub from opts.
+func NewHub(opts Options) *Hub {
+	opts.Metrics.Clients.Set(0, DefaultBoard)
+	rates := opts.Rates.withDefaults()
+	if opts.BroadcastTick == 0 {
+		opts.BroadcastTick = DefaultBroadcastTick
+	}
+	compress := opts.Compression.withDefaults()
+	if opts.BurnDelay <= 0 {
+		opts.BurnDelay = defaultBurnDelay
+	}
 	return &Hub{
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan BroadcastMessage, 256),
-		cleanup:   make(chan struct{}),
+		clients:    make(map[*client]bool),
+		broadcast:  make(chan BroadcastMessage, 256),
+		stop:       make(chan struct{}),
+		store:      opts.Store,
+		history:    storage.HistoryOf(opts.Store),
+		settings:   storage.SettingsOf(opts.Store),
+		expiry:     opts.Expiry,
+		logger:     opts.Logger,
+		metrics:    opts.Metrics,
+		audit:      opts.Audit,
+		limits:     opts.Limits.withDefaults(),
+		rates:      rates,
+		users:      userBuckets{limits: rates, buckets: make(map[string]*userBucket)},
+		tick:       opts.BroadcastTick,
+		compress:   compress,
+		onEvent:    opts.OnEvent,
+		backplane:  opts.Backplane,
+		instanceID: newInstanceID(),
+		burnDelay:  opts.BurnDelay,
 		upgrader: websocket.Upgrader{
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
+			ReadBufferSize:    1024,
+			WriteBufferSize:   1024,
+			Subprotocols:      []string{ProtocolV1},
+			EnableCompression: !compress.Disabled,
 			CheckOrigin: func(r *http.Request) bool {
 				// TODO: Implement proper origin checking
 				return true
@@ -44,45 +160,219 @@ func NewHub() *Hub {
 	}
 }
 
-// Start begins the message broadcasting goroutine.
+// hubRoutines is the number of background goroutines started by Start.
+const hubRoutines = 2
+
+// Start loads the stored snapshot and begins the background goroutines.
 func (h *Hub) Start() {
+	if content, err := h.LoadSnapshot(); err == nil {
+		h.mu.Lock()
+		h.content = content
+		h.mu.Unlock()
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		h.logger.Error("Failed to load snapshot", "board", DefaultBoard, "error", err)
+	}
+	if h.limits.Encrypted && !IsSealed(h.GetContent()) {
+		h.logger.Warn("Board is end-to-end encrypted but holds unencrypted content", "board", DefaultBoard)
+	}
+	h.loadExpiry()
+
+	if h.backplane != nil {
+		unsubscribe, err := h.backplane.Subscribe(h.receive)
+		if err != nil {
+			h.logger.Error("Failed to subscribe to backplane", "error", err)
+		} else {
+			h.unsubscribe = unsubscribe
+		}
+	}
+
+	h.running.Add(hubRoutines)
 	go h.run()
 	go h.startCleanupRoutine()
 }
 
-// run handles message broadcasting to all connected clients.
+// Running reports whether all of the hub's background goroutines are alive.
+func (h *Hub) Running() bool {
+	return h.running.Load() == hubRoutines
+}
+
+// run handles message broadcasting to all connected clients. Every message
+// carries the whole board, so updates arriving within one tick are coalesced
+// and only the latest is broadcast.
 func (h *Hub) run() {
-	for message := range h.broadcast {
-		h.broadcastToClients(message.data, message.sender)
+	defer h.running.Add(-1)
+
+	var (
+		pending *BroadcastMessage
+		flush   <-chan time.Time
+	)
+	for {
+		select {
+		case message := <-h.broadcast:
+			if h.tick < 0 {
+				h.deliver(message)
+				continue
+			}
+			if pending == nil {
+				flush = time.After(h.tick)
+			} else {
+				h.metrics.BroadcastsCoalesced.Inc(DefaultBoard)
+			}
+			pending = &message
+		case <-flush:
+			h.deliver(*pending)
+			pending, flush = nil, nil
+		case <-h.stop:
+			return
+		}
 	}
 }
 
-// broadcastToClients sends a message to all connected clients except the sender.
-func (h *Hub) broadcastToClients(message []byte, sender *websocket.Conn) {
+// broadcastToClients sends content to all connected clients except the sender.
+func (h *Hub) broadcastToClients(content string, revision uint64, sender *client, reason string) {
 	h.mu.RLock()
-	clients := make([]*websocket.Conn, 0, len(h.clients))
-	for conn := range h.clients {
-		if conn != sender {
-			clients = append(clients, conn)
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		if c != sender {
+			clients = append(clients, c)
 		}
 	}
 	h.mu.RUnlock()
 
-	for _, conn := range clients {
-		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			log.Printf("Error writing message to WebSocket: %v", err)
-			h.removeClient(conn)
+	h.metrics.MessagesBroadcast.Inc(DefaultBoard)
+
+	// Each format is prepared once so that its compressed frame is built a
+	// single time and shared by every recipient.
+	var raw, frame *prepared
+	for _, c := range clients {
+		var (
+			message *prepared
+			err     error
+		)
+		if c.v1 {
+			if frame == nil {
+				frame, err = prepareFrame(Frame{Type: FrameContent, Content: content, Revision: revision, Reason: reason})
+			}
+			message = frame
+		} else {
+			if raw == nil {
+				raw, err = prepare([]byte(content))
+			}
+			message = raw
+		}
+		if err != nil {
+			h.logger.Error("Failed to prepare broadcast", "board", DefaultBoard, "error", err)
+			return
+		}
+
+		if err := h.writePrepared(c, message); err != nil {
+			c.logger.Warn("Error writing message to WebSocket", "error", err)
+			h.removeClient(c)
+			continue
 		}
+		if c.v1 {
+			h.viewed(c.actor)
+		}
+	}
+}
+
+// prepared is a message prepared for sending to many connections.
+type prepared struct {
+	message *websocket.PreparedMessage
+	size    int
+}
+
+// prepare prepares a text message.
+func prepare(data []byte) (*prepared, error) {
+	pm, err := websocket.NewPreparedMessage(websocket.TextMessage, data)
+	if err != nil {
+		return nil, err
+	}
+	return &prepared{message: pm, size: len(data)}, nil
+}
+
+// prepareFrame encodes and prepares a ProtocolV1 frame.
+func prepareFrame(frame Frame) (*prepared, error) {
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return nil, err
+	}
+	return prepare(data)
+}
+
+// writePrepared sends a prepared message to a single client and records the bytes sent.
+func (h *Hub) writePrepared(c *client, p *prepared) error {
+	if err := c.writePrepared(p.message, p.size); err != nil {
+		return err
+	}
+	h.metrics.BytesSent.Add(float64(p.size), DefaultBoard)
+	return nil
+}
+
+// sendFrame encodes frame and sends it to a ProtocolV1 client.
+func (h *Hub) sendFrame(c *client, frame Frame) error {
+	message, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return h.write(c, message)
+}
+
+// write sends a text message to a single client and records the bytes sent.
+func (h *Hub) write(c *client, message []byte) error {
+	if err := c.write(message); err != nil {
+		return err
 	}
+	h.metrics.BytesSent.Add(float64(len(message)), DefaultBoard)
+	return nil
 }
 
-// HandleConnection handles a new WebSocket connection.
-func (h *Hub) HandleConnection(w http.ResponseWriter, r *http.Request) {
+// HandleConnection handles a new WebSocket connection made by identity.
+func (h *Hub) HandleConnection(w http.ResponseWriter, r *http.Request, identity auth.Identity) {
+	id := h.nextID.Add(1)
+	logger := h.logger.With(
+		"conn_id", id,
+		"remote_addr", r.RemoteAddr,
+		"session", identity.Session,
+		"user", identity.User,
+		"board", DefaultBoard,
+	)
+
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		http.Error(w, "Server shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	h.conns.Add(1)
+	h.mu.Unlock()
+	defer h.conns.Done()
+
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		logger.Warn("WebSocket upgrade error", "error", err)
 		return
 	}
+	c := &client{
+		id:        id,
+		conn:      conn,
+		logger:    logger,
+		actor:     identity.Actor(r),
+		v1:        conn.Subprotocol() == ProtocolV1,
+		threshold: h.compress.Threshold,
+	}
+	if !h.compress.Disabled {
+		conn.SetCompressionLevel(h.compress.Level)
+	}
+	conn.SetReadLimit(h.limits.MaxMessageSize * hardLimitFactor)
+
+	userKey := identity.User
+	if userKey == auth.AnonymousUser && identity.Session != "" {
+		userKey = "session:" + identity.Session
+	}
+	c.connBucket = newBucket(h.rates.PerConnection, h.rates.Burst)
+	c.userBucket = h.users.acquire(userKey)
+	defer h.users.release(userKey)
 
 	// Set read deadline and pong handler
 	conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -91,48 +381,225 @@ func (h *Hub) HandleConnection(w http.ResponseWriter, r *http.Request) {
 		return nil
 	})
 
-	h.addClient(conn)
-	defer h.removeClient(conn)
+	h.addClient(c)
+	defer h.removeClient(c)
+	defer h.flushEdits(c)
+	defer h.flushDeferred(c)
 
 	// Send current content to new client
-	if content := h.GetContent(); content != "" {
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(content)); err != nil {
-			log.Printf("Error sending initial content: %v", err)
-			return
-		}
+	content, revision := h.GetState()
+	if c.v1 {
+		err = h.sendFrame(c, Frame{Type: FrameContent, Content: content, Revision: revision})
+	} else if content != "" {
+		err = h.write(c, []byte(content))
+	}
+	if err != nil {
+		logger.Warn("Error sending initial content", "error", err)
+		return
+	}
+	if c.v1 {
+		// Raw clients are programs, such as the watch and clip commands,
+		// rather than someone reading the board.
+		h.viewed(c.actor)
 	}
 
 	// Handle incoming messages
 	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			h.logConnectionError(err)
+		frame, err := h.readFrame(c)
+		if errors.Is(err, ErrMessageTooLarge) || errors.Is(err, ErrBoardTooLarge) ||
+			errors.Is(err, ErrInvalidUTF8) || errors.Is(err, ErrNotEncrypted) || errors.Is(err, errInvalidFrame) {
+			if !h.reject(c, err) {
+				break
+			}
+			continue
+		} else if err != nil {
+			h.logConnectionError(logger, err)
 			break
 		}
 
-		h.updateContent(string(message))
-		select {
-		case h.broadcast <- BroadcastMessage{data: message, sender: conn}:
-		default:
-			log.Printf("Broadcast channel full, dropping message")
+		if !h.allow(c) {
+			if !c.v1 {
+				h.deferUpdate(c, frame.Content)
+			}
+			continue
+		}
+		if frame.Type == FrameSync {
+			h.syncOffline(c, frame)
+			continue
+		}
+
+		c.deferMu.Lock()
+		// A newer update supersedes any deferred one.
+		c.deferred = nil
+		h.applyUpdate(c, frame.Content)
+		c.deferMu.Unlock()
+	}
+}
+
+// applyUpdate replaces the content with an update from c and broadcasts it.
+// c.deferMu must be held so that deferred updates apply in order.
+func (h *Hub) applyUpdate(c *client, content string) {
+	before, revision, ok := h.updateContent(content)
+	if !ok {
+		return
+	}
+	h.trackEdit(c, len(before), len(content), revision)
+	h.edited(c.actor)
+	h.publish(content, revision, c)
+}
+
+// readFrame reads the next message from c once it passes the size and
+// encoding limits. Messages of clients without ProtocolV1 are returned as
+// update frames.
+func (h *Hub) readFrame(c *client) (Frame, error) {
+	_, r, err := c.conn.NextReader()
+	if err != nil {
+		return Frame{}, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, h.limits.MaxMessageSize+1))
+	if err != nil {
+		return Frame{}, err
+	}
+	h.metrics.MessagesReceived.Inc(DefaultBoard)
+
+	if int64(len(data)) > h.limits.MaxMessageSize {
+		// Drain the rest of the frame; past the hard read limit this fails and
+		// the connection is closed with "message too big".
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			return Frame{}, err
 		}
+		return Frame{}, ErrMessageTooLarge
+	}
+	if !utf8.Valid(data) {
+		return Frame{}, ErrInvalidUTF8
+	}
+
+	frame := Frame{Type: FrameUpdate, Content: string(data)}
+	if c.v1 {
+		if frame, err = decodeFrame(data); err != nil {
+			return Frame{}, err
+		}
+	}
+	return frame, h.limits.Check(frame.Content)
+}
+
+// reject reports a rejected message to c. ProtocolV1 clients receive an error
+// frame and stay connected; other clients cannot tell errors from content, so
+// they are closed with a matching close code. It reports whether the
+// connection remains usable.
+func (h *Hub) reject(c *client, err error) bool {
+	frame := h.limits.errorFrame(err)
+	h.metrics.MessagesRejected.Inc(DefaultBoard, frame.Code)
+	c.logger.Warn("Rejected WebSocket message", "code", frame.Code)
+
+	if c.v1 {
+		if err := h.sendFrame(c, frame); err != nil {
+			c.logger.Warn("Error sending error frame", "error", err)
+			return false
+		}
+		return true
+	}
+
+	code := websocket.CloseMessageTooBig
+	switch frame.Code {
+	case CodeInvalidUTF8:
+		code = websocket.CloseInvalidFramePayloadData
+	case CodeNotEncrypted:
+		code = websocket.ClosePolicyViolation
+	}
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, frame.Message), time.Now().Add(time.Second))
+	return false
+}
+
+// allow reports whether c may apply another update under its connection and
+// user rate limits. Refused updates are reported to ProtocolV1 clients, which
+// are expected to resend their latest content later; other clients cannot be
+// told, so their latest refused update is deferred instead.
+func (h *Hub) allow(c *client) bool {
+	scope := ""
+	switch {
+	case !c.connBucket.allow():
+		scope = scopeConnection
+	case !c.userBucket.allow():
+		scope = scopeUser
+	}
+
+	if scope == "" {
+		if c.throttled {
+			c.throttled = false
+			c.logger.Info("Client no longer throttled")
+		}
+		return true
+	}
+
+	h.metrics.MessagesThrottled.Inc(DefaultBoard, scope)
+	if !c.throttled {
+		c.throttled = true
+		c.logger.Warn("Client throttled", "scope", scope)
+	}
+	if c.v1 {
+		err := h.sendFrame(c, Frame{Type: FrameError, Code: CodeRateLimited, Message: "too many updates, slow down"})
+		if err != nil {
+			c.logger.Warn("Error sending error frame", "error", err)
+		}
+	}
+	return false
+}
+
+// Limits returns the size limits enforced by the hub.
+func (h *Hub) Limits() Limits {
+	return h.limits
+}
+
+// Watch calls fn with the content and revision of every broadcast, after
+// updates arriving within one tick have been coalesced. fn must not block.
+func (h *Hub) Watch(fn func(content string, revision uint64)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.watchers = append(h.watchers, fn)
+}
+
+// notifyWatchers passes a broadcast to every watcher.
+func (h *Hub) notifyWatchers(content string, revision uint64) {
+	h.mu.RLock()
+	watchers := h.watchers
+	h.mu.RUnlock()
+
+	for _, fn := range watchers {
+		fn(content, revision)
+	}
+}
+
+// publish queues content for broadcasting to every client except the sender.
+func (h *Hub) publish(content string, revision uint64, sender *client) {
+	h.enqueue(BroadcastMessage{content: content, revision: revision, sender: sender})
+}
+
+// enqueue queues message for the broadcast loop, dropping it when the queue is full.
+func (h *Hub) enqueue(message BroadcastMessage) {
+	select {
+	case h.broadcast <- message:
+	default:
+		h.metrics.BroadcastsDropped.Inc(DefaultBoard)
+		h.logger.Warn("Broadcast channel full, dropping message", "board", DefaultBoard, "size", len(message.content))
 	}
 }
 
 // logConnectionError logs WebSocket connection errors appropriately.
-func (h *Hub) logConnectionError(err error) {
+func (h *Hub) logConnectionError(logger *slog.Logger, err error) {
 	if websocket.IsCloseError(err,
 		websocket.CloseGoingAway,
 		websocket.CloseNormalClosure,
 		websocket.CloseNoStatusReceived,
 	) {
-		log.Printf("WebSocket connection closed normally")
+		logger.Debug("WebSocket connection closed normally")
 	} else if websocket.IsUnexpectedCloseError(err,
 		websocket.CloseGoingAway,
 		websocket.CloseAbnormalClosure,
 		websocket.CloseNormalClosure,
 	) {
-		log.Printf("WebSocket unexpected error: %v", err)
+		logger.Warn("WebSocket unexpected error", "error", err)
 	}
 }
 
@@ -143,34 +610,122 @@ func (h *Hub) GetContent() string {
 	return h.content
 }
 
-// updateContent updates the stored content safely.
-func (h *Hub) updateContent(content string) {
+// GetState returns the current content together with its revision.
+func (h *Hub) GetState() (string, uint64) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.content, h.revision
+}
+
+// Update applies edit on behalf of actor and broadcasts the result to all clients.
+// When match is non-nil the update only succeeds if it accepts the current
+// content and revision.
+func (h *Hub) Update(actor audit.Actor, match func(content string, revision uint64) bool, edit func(content string) (string, error)) (uint64, error) {
+	revision, err := h.update(actor, match, edit, nil)
+	if err == nil {
+		h.recordVersion(actor.User)
+	}
+	return revision, err
+}
+
+// Mirror replaces the content with content replicated from another server on
+// behalf of actor. Unlike Update it records no version, since the history of
+// the other server is mirrored separately.
+func (h *Hub) Mirror(actor audit.Actor, content string) (uint64, error) {
+	revision, err := h.update(actor, nil, func(string) (string, error) { return content, nil }, nil)
+	if err == nil {
+		// Keep the mirrored revision out of the local history.
+		h.mu.Lock()
+		if h.revision == revision {
+			h.versioned = revision
+		}
+		h.mu.Unlock()
+	}
+	return revision, err
+}
+
+// update applies edit, records it in the audit log and queues the broadcast
+// to every client except sender.
+func (h *Hub) update(actor audit.Actor, match func(content string, revision uint64) bool, edit func(content string) (string, error), sender *client) (uint64, error) {
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		return 0, ErrShuttingDown
+	}
+	if match != nil && !match(h.content, h.revision) {
+		h.mu.Unlock()
+		return 0, ErrRevisionMismatch
+	}
+
+	content, err := edit(h.content)
+	if err == nil {
+		err = h.limits.Check(content)
+	}
+	if err != nil {
+		h.mu.Unlock()
+		return 0, err
+	}
+
+	before := h.content
+	h.content = content
+	h.revision++
+	revision := h.revision
+	h.mu.Unlock()
+
+	h.record(audit.Entry{
+		Action:    audit.ActionEdit,
+		Actor:     actor,
+		Revision:  revision,
+		Size:      len(content),
+		SizeDelta: len(content) - len(before),
+		Edits:     1,
+	})
+	h.edited(actor)
+	h.publish(content, revision, sender)
+	return revision, nil
+}
+
+// updateContent updates the stored content safely and returns the previous
+// content and new revision. It returns false once shutdown has begun and
+// edits are no longer accepted.
+func (h *Hub) updateContent(content string) (string, uint64, bool) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
+	if h.closing {
+		return "", 0, false
+	}
+	before := h.content
 	h.content = content
+	h.revision++
+	return before, h.revision, true
 }
 
 // addClient adds a new client connection safely.
-func (h *Hub) addClient(conn *websocket.Conn) {
+func (h *Hub) addClient(c *client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	h.clients[conn] = true
-	log.Printf("Client connected. Total clients: %d", len(h.clients))
+	h.clients[c] = true
+	h.metrics.Clients.Set(float64(len(h.clients)), DefaultBoard)
+	c.logger.Info("Client connected", "clients", len(h.clients))
+	h.notify(audit.Entry{Action: audit.ActionJoin, Actor: c.actor})
 }
 
 // removeClient removes a client connection safely.
-func (h *Hub) removeClient(conn *websocket.Conn) {
+func (h *Hub) removeClient(c *client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	if _, exists := h.clients[conn]; exists {
-		delete(h.clients, conn)
-		conn.Close()
-		log.Printf("Client disconnected. Total clients: %d", len(h.clients))
+	if _, exists := h.clients[c]; exists {
+		delete(h.clients, c)
+		h.metrics.Clients.Set(float64(len(h.clients)), DefaultBoard)
+		c.conn.Close()
+		c.logger.Info("Client disconnected", "clients", len(h.clients))
+		h.notify(audit.Entry{Action: audit.ActionLeave, Actor: c.actor})
 	}
 }
 
 // startCleanupRoutine starts a background goroutine that periodically checks for dead connections.
 func (h *Hub) startCleanupRoutine() {
+	defer h.running.Add(-1)
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
@@ -178,7 +733,7 @@ func (h *Hub) startCleanupRoutine() {
 		select {
 		case <-ticker.C:
 			h.cleanupDeadConnections()
-		case <-h.cleanup:
+		case <-h.stop:
 			return
 		}
 	}
@@ -187,69 +742,222 @@ func (h *Hub) startCleanupRoutine() {
 // cleanupDeadConnections removes connections that are no longer responsive.
 func (h *Hub) cleanupDeadConnections() {
 	h.mu.RLock()
-	var deadConnections []*websocket.Conn
+	var deadConnections []*client
 
-	for conn := range h.clients {
+	for c := range h.clients {
 		// Send a ping to test if connection is alive
-		if err := conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(5*time.Second)); err != nil {
-			deadConnections = append(deadConnections, conn)
+		if err := c.ping(); err != nil {
+			deadConnections = append(deadConnections, c)
 		}
 	}
 	h.mu.RUnlock()
 
 	// Remove dead connections
-	for _, conn := range deadConnections {
-		log.Printf("Removing dead connection")
-		h.removeClient(conn)
+	for _, c := range deadConnections {
+		c.logger.Info("Removing dead connection")
+		h.removeClient(c)
 	}
 }
 
-// Stop gracefully shuts down the hub.
+// Stop gracefully shuts down the hub's background goroutines.
 func (h *Hub) Stop() {
-	close(h.cleanup)
+	h.stopOnce.Do(func() {
+		if h.unsubscribe != nil {
+			h.unsubscribe()
+		}
+		close(h.stop)
+		h.mu.Lock()
+		h.stopExpiryTimers()
+		h.mu.Unlock()
+	})
 }
 
-// SaveSnapshot saves the current content to a file.
-func (h *Hub) SaveSnapshot() error {
+// Shutdown stops accepting edits and connections, persists unsaved content,
+// asks every client to reconnect and stops the background goroutines. It waits
+// for clients to close their connections until ctx is done, then drops them.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	h.closing = true
+	dirty := h.revision != h.saved
+	clients := make([]*client, 0, len(h.clients))
+	for c := range h.clients {
+		clients = append(clients, c)
+	}
+	h.mu.Unlock()
+
+	var saveErr error
+	if dirty {
+		saveErr = h.SaveSnapshot(SystemActor)
+	}
+
+	message := websocket.FormatCloseMessage(websocket.CloseServiceRestart, restartReason)
+	for _, c := range clients {
+		if err := c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second)); err != nil {
+			h.removeClient(c)
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.conns.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		h.logger.Warn("Shutdown timed out, dropping remaining clients")
+		for _, c := range clients {
+			h.removeClient(c)
+		}
+		<-done
+	}
+
+	h.Stop()
+	h.logger.Info("Hub stopped", "clients", len(clients))
+	return saveErr
+}
+
+// SaveSnapshot saves the current content to the store on behalf of actor.
+func (h *Hub) SaveSnapshot(actor audit.Actor) (err error) {
+	start := time.Now()
+	defer func() { h.metrics.ObserveSnapshot("save", start, err) }()
+
 	h.mu.RLock()
-	content := h.content
+	content, revision := h.content, h.revision
 	h.mu.RUnlock()
 
-	return os.WriteFile("boardcast.txt", []byte(content), 0644)
+	if err := h.store.SaveSnapshot(DefaultBoard, []byte(content)); err != nil {
+		h.logger.Error("Failed to save snapshot", "board", DefaultBoard, "error", err)
+		return err
+	}
+
+	h.mu.Lock()
+	h.saved = max(h.saved, revision)
+	h.mu.Unlock()
+
+	h.record(audit.Entry{
+		Action:   audit.ActionSave,
+		Actor:    actor,
+		Revision: revision,
+		Size:     len(content),
+	})
+
+	h.logger.Info("Snapshot saved", "board", DefaultBoard, "size", len(content))
+	return nil
 }
 
-// LoadSnapshot loads content from the snapshot file.
+// LoadSnapshot loads content from the store.
 func (h *Hub) LoadSnapshot() (string, error) {
-	data, err := os.ReadFile("boardcast.txt")
+	data, err := h.store.LoadSnapshot(DefaultBoard)
 	if err != nil {
 		return "", err
 	}
 	return string(data), nil
 }
 
-// RestoreSnapshot restores content from snapshot file and updates the hub.
-func (h *Hub) RestoreSnapshot() error {
+// RestoreSnapshot restores content from the store on behalf of actor and updates the hub.
+func (h *Hub) RestoreSnapshot(actor audit.Actor) (err error) {
+	start := time.Now()
+	defer func() { h.metrics.ObserveSnapshot("restore", start, err) }()
+
 	content, err := h.LoadSnapshot()
 	if err != nil {
+		h.logger.Error("Failed to restore snapshot", "board", DefaultBoard, "error", err)
 		return err
 	}
 
-	h.updateContent(content)
-
-	// Broadcast the restored content to all connected clients
-	h.mu.RLock()
-	clients := make([]*websocket.Conn, 0, len(h.clients))
-	for conn := range h.clients {
-		clients = append(clients, conn)
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		return ErrShuttingDown
 	}
-	h.mu.RUnlock()
+	before := h.content
+	h.content = content
+	h.revision++
+	h.saved = h.revision
+	revision := h.revision
+	h.mu.Unlock()
+
+	h.record(audit.Entry{
+		Action:    audit.ActionRestore,
+		Actor:     actor,
+		Revision:  revision,
+		Size:      len(content),
+		SizeDelta: len(content) - len(before),
+	})
+	h.recordVersion(actor.User)
+	h.edited(actor)
+
+	// Broadcast the restored content to all connected clients, in order with
+	// any updates still waiting to be broadcast.
+	h.publish(content, revision, nil)
+
+	h.logger.Info("Snapshot restored", "board", DefaultBoard, "size", len(content))
+	return nil
+}
+
+// editWindow is how long a client may stay idle before its edits are
+// written to the audit log as a single entry.
+const editWindow = 5 * time.Second
+
+// trackEdit accumulates an edit by c into its pending audit entry.
+func (h *Hub) trackEdit(c *client, before, after int, revision uint64) {
+	c.editMu.Lock()
+	defer c.editMu.Unlock()
 
-	for _, conn := range clients {
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(content)); err != nil {
-			log.Printf("Error writing restored content to WebSocket: %v", err)
-			h.removeClient(conn)
+	if c.pendingEdit == nil {
+		c.pendingEdit = &audit.Entry{
+			Action:    audit.ActionEdit,
+			Actor:     c.actor,
+			SizeDelta: -before,
 		}
+		c.editTimer = time.AfterFunc(editWindow, func() { h.flushEdits(c) })
+	} else {
+		c.editTimer.Reset(editWindow)
 	}
 
-	return nil
+	c.pendingEdit.Revision = revision
+	c.pendingEdit.Size = after
+	c.pendingEdit.Edits++
+}
+
+// flushEdits writes the pending edits of c to the audit log.
+func (h *Hub) flushEdits(c *client) {
+	c.editMu.Lock()
+	entry := c.pendingEdit
+	c.pendingEdit = nil
+	if c.editTimer != nil {
+		c.editTimer.Stop()
+	}
+	c.editMu.Unlock()
+
+	if entry == nil {
+		return
+	}
+	entry.SizeDelta += entry.Size
+	h.record(*entry)
+	h.recordVersion(entry.User)
+}
+
+// record writes an entry for the default board to the audit log and notifies listeners.
+func (h *Hub) record(e audit.Entry) {
+	e.Board = DefaultBoard
+	e.Time = time.Now().UTC()
+	if err := h.audit.Record(e); err != nil {
+		h.logger.Error("Failed to write audit log", "action", e.Action, "error", err)
+	}
+	h.notify(e)
+}
+
+// notify passes an event for the default board to the OnEvent listener.
+func (h *Hub) notify(e audit.Entry) {
+	if h.onEvent == nil {
+		return
+	}
+	e.Board = DefaultBoard
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	h.onEvent(e)
 }
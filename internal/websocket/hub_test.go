@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+)
+
+func TestEditEvents(t *testing.T) {
+	events := make(chan audit.Entry, 16)
+	h, srv := newTestHub(t, Options{OnEvent: func(e audit.Entry) { events <- e }}, "")
+	h.Start()
+	t.Cleanup(h.Stop)
+
+	conn := dial(t, srv, false)
+	for _, content := range []string{"a", "ab", "abc"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitContent(t, h, "abc")
+	conn.Close()
+
+	// Edits within the window are reported once, when the editor leaves.
+	want := []audit.Entry{
+		{Action: audit.ActionJoin},
+		{Action: audit.ActionEdit, Revision: 3, Size: 3, SizeDelta: 3, Edits: 3},
+		{Action: audit.ActionLeave},
+	}
+	for _, w := range want {
+		select {
+		case e := <-events:
+			if e.Action != w.Action || e.Revision != w.Revision || e.Size != w.Size || e.SizeDelta != w.SizeDelta || e.Edits != w.Edits {
+				t.Errorf("event = %+v, want %+v", e, w)
+			}
+			if e.User != "alice" || e.Board != DefaultBoard || e.Time.IsZero() {
+				t.Errorf("event = %+v, want one by alice on %s", e, DefaultBoard)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no %s event", w.Action)
+		}
+	}
+}
+
+// countingConn counts the bytes read from a connection.
+type countingConn struct {
+	net.Conn
+	n atomic.Int64
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func TestCompression(t *testing.T) {
+	large := strings.Repeat("compressible line\n", 4096)
+	tests := []struct {
+		name        string
+		compression Compression
+		offer       bool
+		content     string
+		compressed  bool
+	}{
+		{"negotiated", Compression{Threshold: 1024}, true, large, true},
+		{"below threshold", Compression{Threshold: 1024}, true, strings.Repeat("a", 1000), false},
+		{"not offered", Compression{}, false, large, false},
+		{"disabled", Compression{Disabled: true}, true, large, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, srv := newTestHub(t, Options{Compression: tt.compression, BroadcastTick: -1}, "")
+			h.Start()
+			t.Cleanup(h.Stop)
+
+			var counted *countingConn
+			dialer := websocket.Dialer{
+				EnableCompression: tt.offer,
+				NetDial: func(network, addr string) (net.Conn, error) {
+					conn, err := net.Dial(network, addr)
+					counted = &countingConn{Conn: conn}
+					return counted, err
+				},
+			}
+			reader, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?user=bob", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			negotiated := strings.Contains(resp.Header.Get("Sec-WebSocket-Extensions"), "permessage-deflate")
+			if negotiated != (tt.offer && !tt.compression.Disabled) {
+				t.Errorf("negotiated = %v", negotiated)
+			}
+
+			// Both formats of the broadcast are delivered intact.
+			v1 := dial(t, srv, true)
+			writer := dial(t, srv, false)
+			before := counted.n.Load()
+			if err := writer.WriteMessage(websocket.TextMessage, []byte(tt.content)); err != nil {
+				t.Fatal(err)
+			}
+			reader.SetReadDeadline(time.Now().Add(5 * time.Second))
+			_, message, err := reader.ReadMessage()
+			if err != nil || string(message) != tt.content {
+				t.Fatalf("raw client read %d bytes, %v, want the content", len(message), err)
+			}
+			if f := readTestFrame(t, v1); f.Content != tt.content {
+				t.Errorf("v1 client read %d bytes, want the content", len(f.Content))
+			}
+
+			wire := counted.n.Load() - before
+			if compressed := wire < int64(len(tt.content)); compressed != tt.compressed {
+				t.Errorf("%d bytes on the wire for %d of content, want compressed = %v", wire, len(tt.content), tt.compressed)
+			}
+		})
+	}
+}
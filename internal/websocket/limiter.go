@@ -0,0 +1,200 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttling scopes reported in metrics and logs.
+const (
+	scopeConnection = "connection"
+	scopeUser       = "user"
+)
+
+// CodeRateLimited is sent in error frames when a client exceeds its update rate.
+const CodeRateLimited = "rate_limited"
+
+// Default rate limits, in updates per second.
+const (
+	DefaultConnectionRate = 20
+	DefaultUserRate       = 50
+	DefaultBurst          = 40
+)
+
+// DefaultBroadcastTick is the default interval at which queued updates are
+// coalesced into a single broadcast.
+const DefaultBroadcastTick = 50 * time.Millisecond
+
+// RateLimits bounds how fast clients may send updates over WebSocket. Rates
+// are in updates per second; a negative rate disables that limit and zero
+// values use the defaults. Users are identified by name, or by session for
+// anonymous users, and share one budget across their connections.
+type RateLimits struct {
+	PerConnection float64
+	PerUser       float64
+	Burst         int
+}
+
+// withDefaults returns r with zero values replaced by the defaults.
+func (r RateLimits) withDefaults() RateLimits {
+	if r.PerConnection == 0 {
+		r.PerConnection = DefaultConnectionRate
+	}
+	if r.PerUser == 0 {
+		r.PerUser = DefaultUserRate
+	}
+	if r.Burst <= 0 {
+		r.Burst = DefaultBurst
+	}
+	return r
+}
+
+// bucket is a token bucket refilled at rate tokens per second up to burst.
+// A nil bucket allows everything.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newBucket returns a full bucket, or nil when rate disables limiting.
+func newBucket(rate float64, burst int) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// allow takes a token if one is available.
+func (b *bucket) allow() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// userBuckets shares one bucket between all connections of a user.
+type userBuckets struct {
+	mu      sync.Mutex
+	limits  RateLimits
+	buckets map[string]*userBucket
+}
+
+// userBucket is a user's bucket and the number of connections holding it.
+type userBucket struct {
+	*bucket
+	refs int
+}
+
+// acquire returns the bucket of key, creating it for the first connection.
+func (u *userBuckets) acquire(key string) *bucket {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	ub, ok := u.buckets[key]
+	if !ok {
+		ub = &userBucket{bucket: newBucket(u.limits.PerUser, u.limits.Burst)}
+		u.buckets[key] = ub
+	}
+	ub.refs++
+	return ub.bucket
+}
+
+// release drops a connection's hold on the bucket of key, forgetting it
+// once no connection uses it.
+func (u *userBuckets) release(key string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if ub, ok := u.buckets[key]; ok {
+		if ub.refs--; ub.refs <= 0 {
+			delete(u.buckets, key)
+		}
+	}
+}
+
+// minDeferDelay bounds how often a deferred update is retried.
+const minDeferDelay = 10 * time.Millisecond
+
+// delay returns how long until a token is available.
+func (b *bucket) delay() time.Duration {
+	if b == nil {
+		return 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tokens := min(b.burst, b.tokens+time.Since(b.last).Seconds()*b.rate)
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / b.rate * float64(time.Second))
+}
+
+// deferUpdate keeps content, refused by the rate limits of c, to apply once
+// they allow it. Every update carries the whole board, so only the latest
+// refused update is kept.
+func (h *Hub) deferUpdate(c *client, content string) {
+	c.deferMu.Lock()
+	defer c.deferMu.Unlock()
+
+	pending := c.deferred != nil
+	c.deferred = &content
+	if pending {
+		return
+	}
+	wait := max(c.connBucket.delay(), c.userBucket.delay(), minDeferDelay)
+	if c.deferTimer == nil {
+		c.deferTimer = time.AfterFunc(wait, func() { h.applyDeferred(c) })
+	} else {
+		c.deferTimer.Reset(wait)
+	}
+}
+
+// applyDeferred applies the deferred update of c if the rate limits allow
+// it, and otherwise waits again.
+func (h *Hub) applyDeferred(c *client) {
+	c.deferMu.Lock()
+	defer c.deferMu.Unlock()
+
+	if c.deferred == nil {
+		return
+	}
+	if !c.connBucket.allow() || !c.userBucket.allow() {
+		c.deferTimer.Reset(max(c.connBucket.delay(), c.userBucket.delay(), minDeferDelay))
+		return
+	}
+	content := *c.deferred
+	c.deferred = nil
+	h.applyUpdate(c, content)
+}
+
+// flushDeferred applies the deferred update of c as it disconnects, so that
+// its last edit is not lost.
+func (h *Hub) flushDeferred(c *client) {
+	c.deferMu.Lock()
+	defer c.deferMu.Unlock()
+
+	if c.deferTimer != nil {
+		c.deferTimer.Stop()
+	}
+	if c.deferred != nil {
+		content := *c.deferred
+		c.deferred = nil
+		h.applyUpdate(c, content)
+	}
+}
@@ -0,0 +1,212 @@
+package websocket
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/storage"
+)
+
+func TestBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		elapsed time.Duration
+		want    int
+	}{
+		{"burst", 1, 3, 0, 3},
+		{"refilled", 10, 3, 200 * time.Millisecond, 5},
+		{"refill capped at burst", 10, 3, time.Hour, 6},
+		{"partial token", 1, 1, 500 * time.Millisecond, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBucket(tt.rate, tt.burst)
+			allowed := 0
+			for b.allow() {
+				allowed++
+			}
+			// Rewind the clock instead of sleeping.
+			b.last = b.last.Add(-tt.elapsed)
+			for b.allow() {
+				allowed++
+			}
+			if allowed != tt.want {
+				t.Errorf("allowed %d updates, want %d", allowed, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketDisabled(t *testing.T) {
+	b := newBucket(-1, 1)
+	for range 1000 {
+		if !b.allow() {
+			t.Fatal("disabled bucket refused an update")
+		}
+	}
+	if d := b.delay(); d != 0 {
+		t.Errorf("delay() = %v, want 0", d)
+	}
+}
+
+func TestBucketDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens float64
+		want   time.Duration
+	}{
+		{"available", 1, 0},
+		{"empty", 0, 100 * time.Millisecond},
+		{"half", 0.5, 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBucket(10, 1)
+			b.tokens = tt.tokens
+			// The bucket refills a little while delay runs.
+			if d := b.delay(); d > tt.want || d < tt.want-10*time.Millisecond {
+				t.Errorf("delay() = %v, want %v", d, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserBuckets(t *testing.T) {
+	u := userBuckets{limits: RateLimits{PerUser: 1, Burst: 2}, buckets: make(map[string]*userBucket)}
+	a1, a2, b := u.acquire("alice"), u.acquire("alice"), u.acquire("bob")
+	if a1 != a2 || a1 == b {
+		t.Fatal("connections of a user do not share one bucket")
+	}
+	if !a1.allow() || !a2.allow() || a1.allow() {
+		t.Error("connections of a user do not share one budget")
+	}
+	if !b.allow() {
+		t.Error("another user was throttled")
+	}
+
+	u.release("alice")
+	if _, ok := u.buckets["alice"]; !ok {
+		t.Error("bucket forgotten while a connection holds it")
+	}
+	u.release("alice")
+	if _, ok := u.buckets["alice"]; ok {
+		t.Error("bucket kept after every connection released it")
+	}
+}
+
+// TestThrottledRawUpdates checks that the last update of a client without
+// ProtocolV1 is applied once the rate limits allow, not dropped.
+func TestThrottledRawUpdates(t *testing.T) {
+	h, srv := newTestHub(t, Options{Rates: RateLimits{PerConnection: 20, PerUser: -1, Burst: 1}}, "")
+	conn := dial(t, srv, false)
+
+	for _, content := range []string{"a", "b", "c"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for h.GetContent() != "c" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	content, revision := h.GetState()
+	if content != "c" || revision != 2 {
+		t.Errorf("board = %q at revision %d, want %q at revision 2", content, revision, "c")
+	}
+}
+
+// TestThrottledRawUpdateOnDisconnect checks that a deferred update is
+// applied when its client disconnects.
+func TestThrottledRawUpdateOnDisconnect(t *testing.T) {
+	h, srv := newTestHub(t, Options{Rates: RateLimits{PerConnection: 0.001, PerUser: -1, Burst: 1}}, "")
+	conn := dial(t, srv, false)
+
+	for _, content := range []string{"a", "b"} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for h.GetContent() != "b" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if content := h.GetContent(); content != "b" {
+		t.Errorf("board = %q, want %q", content, "b")
+	}
+}
+
+// newTestHub returns a hub holding content, served over WebSocket by the
+// returned server. Connections are made by alice unless the user query
+// parameter names someone else.
+func newTestHub(t *testing.T, opts Options, content string) (*Hub, *httptest.Server) {
+	t.Helper()
+	opts.Store = storage.NewMemoryStore()
+	opts.Logger = slog.New(slog.DiscardHandler)
+	opts.Metrics = metrics.New()
+	opts.Audit = audit.Discard
+	h := NewHub(opts)
+	if content != "" {
+		if _, err := h.Update(audit.Actor{}, nil, func(string) (string, error) { return content, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user := r.URL.Query().Get("user")
+		if user == "" {
+			user = "alice"
+		}
+		h.HandleConnection(w, r, auth.Identity{User: user})
+	}))
+	t.Cleanup(srv.Close)
+	return h, srv
+}
+
+// dial connects to srv, with ProtocolV1 when v1 is set, and reads the
+// initial content.
+func dial(t *testing.T, srv *httptest.Server, v1 bool) *websocket.Conn {
+	t.Helper()
+	return dialAs(t, srv, v1, "")
+}
+
+// dialAs is dial for a connection made by user.
+func dialAs(t *testing.T, srv *httptest.Server, v1 bool, user string) *websocket.Conn {
+	t.Helper()
+	dialer := websocket.Dialer{}
+	if v1 {
+		dialer.Subprotocols = []string{ProtocolV1}
+	}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?user="+user, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if v1 {
+		readTestFrame(t, conn)
+	}
+	return conn
+}
+
+// readTestFrame reads the next ProtocolV1 frame from conn.
+func readTestFrame(t *testing.T, conn *websocket.Conn) Frame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var f Frame
+	if err := conn.ReadJSON(&f); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
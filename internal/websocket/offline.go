@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"errors"
+
+	"github.com/yosebyte/boardcast/internal/merge"
+)
+
+// Outcomes of offline syncs reported in metrics.
+const (
+	syncFastForward = "fast_forward"
+	syncMerged      = "merged"
+	syncConflict    = "conflict"
+)
+
+// Labels of the conflict markers around overlapping offline edits.
+const (
+	offlineLabel = "offline edits"
+	serverLabel  = "server"
+)
+
+// syncAttempts bounds how often a merge is retried when the board changes
+// while it is computed.
+const syncAttempts = 3
+
+// testHookSyncUpdate is called before an offline sync applies its result.
+var testHookSyncUpdate = func() {}
+
+// syncOffline applies the content c edited while disconnected. Unless the
+// board changed since the base the client started from, the content simply
+// replaces it; otherwise the client's changes are merged with the changes
+// made meanwhile. Overlapping changes are not applied: the client receives a
+// conflict frame to resolve them, so that nobody's work is overwritten. So
+// does a client whose sync keeps losing the race against other edits.
+//
+// The base is named by its content or digest rather than its revision, since
+// revisions restart with the server while clients keep edits across restarts.
+func (h *Hub) syncOffline(c *client, f Frame) {
+	for attempt := 1; ; attempt++ {
+		current, revision := h.GetState()
+
+		var (
+			result  string
+			clean   bool
+			outcome string
+		)
+		switch {
+		case f.BaseContent != nil && *f.BaseContent == current,
+			f.BaseContent == nil && f.BaseDigest != "" && f.BaseDigest == Digest(current):
+			result, clean, outcome = f.Content, true, syncFastForward
+		case h.limits.Encrypted:
+			// Encrypted changes can only be merged by the client, which
+			// receives no merged content.
+		case f.BaseContent == nil:
+			// Without the base content the changes cannot be told apart.
+			result = merge.Conflict(f.Content, current, offlineLabel, serverLabel)
+		default:
+			result, clean = merge.Merge(*f.BaseContent, f.Content, current, offlineLabel, serverLabel)
+			outcome = syncMerged
+		}
+
+		if !clean {
+			c.logger.Info("Offline edits conflict with changes on the server", "revision", revision)
+			h.syncConflict(c, current, revision, result)
+			return
+		}
+		if result == current {
+			h.metrics.OfflineSyncs.Inc(DefaultBoard, outcome)
+			h.reply(c, Frame{Type: FrameSynced, Content: current, Revision: revision})
+			return
+		}
+
+		testHookSyncUpdate()
+		revision, err := h.update(c.actor, atRevision(revision), func(string) (string, error) { return result, nil }, c)
+		switch {
+		case errors.Is(err, ErrRevisionMismatch) && attempt < syncAttempts:
+			continue
+		case errors.Is(err, ErrRevisionMismatch):
+			current, revision := h.GetState()
+			var merged string
+			if !h.limits.Encrypted {
+				merged = merge.Conflict(f.Content, current, offlineLabel, serverLabel)
+			}
+			c.logger.Info("Offline edits kept conflicting with changes on the server", "revision", revision, "attempts", attempt)
+			h.syncConflict(c, current, revision, merged)
+			return
+		case errors.Is(err, ErrShuttingDown):
+			return
+		case err != nil:
+			if !h.reject(c, err) {
+				c.conn.Close()
+			}
+			return
+		}
+
+		h.recordVersion(c.actor.User)
+		h.metrics.OfflineSyncs.Inc(DefaultBoard, outcome)
+		c.logger.Info("Offline edits synced", "outcome", outcome, "revision", revision)
+		h.reply(c, Frame{Type: FrameSynced, Content: result, Revision: revision})
+		return
+	}
+}
+
+// syncConflict leaves the offline edits of c for its user to resolve against
+// current, offering merged content when there is any.
+func (h *Hub) syncConflict(c *client, current string, revision uint64, merged string) {
+	h.metrics.OfflineSyncs.Inc(DefaultBoard, syncConflict)
+	h.reply(c, Frame{Type: FrameConflict, Content: current, Revision: revision, Merged: merged})
+}
+
+// reply sends frame to c, logging failures; the read loop notices a broken
+// connection on its own.
+func (h *Hub) reply(c *client, frame Frame) {
+	if err := h.sendFrame(c, frame); err != nil {
+		c.logger.Warn("Error sending frame", "type", frame.Type, "error", err)
+	}
+}
+
+// atRevision returns an update precondition accepting only revision. The
+// revision is taken from this process, so it cannot predate a restart.
+func atRevision(revision uint64) func(string, uint64) bool {
+	return func(_ string, current uint64) bool { return current == revision }
+}
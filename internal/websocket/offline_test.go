@@ -0,0 +1,119 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+)
+
+// ptr returns a pointer to s.
+func ptr(s string) *string { return &s }
+
+func TestSyncOffline(t *testing.T) {
+	const current = "a\nb\nc\n"
+	base := uint64(1)
+
+	tests := []struct {
+		name      string
+		frame     Frame
+		wantType  string
+		wantBoard string
+	}{
+		{"base content unchanged", Frame{BaseContent: ptr(current), Content: "a\nb\nc\nd\n"}, FrameSynced, "a\nb\nc\nd\n"},
+		{"base digest unchanged", Frame{BaseDigest: Digest(current), Content: "x\n"}, FrameSynced, "x\n"},
+		{"same content", Frame{BaseContent: ptr("old\n"), Content: current}, FrameSynced, current},
+		{"merged", Frame{BaseContent: ptr("a\nb\nc\nd\n"), Content: "A\nb\nc\nd\n"}, FrameSynced, "A\nb\nc\n"},
+		{"overlapping", Frame{BaseContent: ptr("a\nb\nc\n\n"), Content: "a\nb\nC\n\n"}, FrameConflict, current},
+		{"stale digest", Frame{BaseDigest: Digest("a\n"), Content: "x\n"}, FrameConflict, current},
+		// The revision of a base may come from before a restart, so it
+		// does not identify the content the edits started from.
+		{"bare revision", Frame{Base: &base, Content: "x\n"}, FrameConflict, current},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, srv := newTestHub(t, Options{}, current)
+			conn := dial(t, srv, true)
+
+			tt.frame.Type = FrameSync
+			if err := conn.WriteJSON(tt.frame); err != nil {
+				t.Fatal(err)
+			}
+			f := readTestFrame(t, conn)
+			if f.Type != tt.wantType {
+				t.Fatalf("reply = %+v, want a %s frame", f, tt.wantType)
+			}
+			if got := h.GetContent(); got != tt.wantBoard {
+				t.Errorf("board = %q, want %q", got, tt.wantBoard)
+			}
+		})
+	}
+}
+
+func TestSyncOfflineEncrypted(t *testing.T) {
+	sealed := func(b byte) string { return SealedPrefix + strings.Repeat(string(b), 40) }
+	current := sealed('A')
+
+	tests := []struct {
+		name      string
+		frame     Frame
+		wantType  string
+		wantBoard string
+	}{
+		{"base digest unchanged", Frame{BaseDigest: Digest(current), Content: sealed('B')}, FrameSynced, sealed('B')},
+		{"stale digest", Frame{BaseDigest: Digest(sealed('C')), Content: sealed('B')}, FrameConflict, current},
+		{"no base", Frame{Content: sealed('B')}, FrameConflict, current},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, srv := newTestHub(t, Options{Limits: Limits{Encrypted: true}}, current)
+			conn := dial(t, srv, true)
+
+			tt.frame.Type = FrameSync
+			if err := conn.WriteJSON(tt.frame); err != nil {
+				t.Fatal(err)
+			}
+			f := readTestFrame(t, conn)
+			if f.Type != tt.wantType {
+				t.Fatalf("reply = %+v, want a %s frame", f, tt.wantType)
+			}
+			if f.Merged != "" {
+				t.Errorf("merged = %q, want none for encrypted content", f.Merged)
+			}
+			if got := h.GetContent(); got != tt.wantBoard {
+				t.Errorf("board = %q, want %q", got, tt.wantBoard)
+			}
+		})
+	}
+}
+
+// TestSyncOfflineRace checks that a sync losing every attempt to other edits
+// gets a conflict to resolve rather than an error.
+func TestSyncOfflineRace(t *testing.T) {
+	const base = "a\n1\n2\n3\n"
+	h, srv := newTestHub(t, Options{}, base)
+	conn := dial(t, srv, true)
+
+	edits := 0
+	testHookSyncUpdate = func() {
+		edits++
+		h.Update(audit.Actor{User: "bob"}, nil, func(content string) (string, error) { return content + "b\n", nil })
+	}
+	t.Cleanup(func() { testHookSyncUpdate = func() {} })
+
+	// Every attempt merges cleanly with the edit made meanwhile.
+	if err := conn.WriteJSON(Frame{Type: FrameSync, BaseContent: ptr(base), Content: "A\n1\n2\n3\n"}); err != nil {
+		t.Fatal(err)
+	}
+	f := readTestFrame(t, conn)
+	current, revision := h.GetState()
+	if f.Type != FrameConflict || f.Content != current || f.Revision != revision {
+		t.Fatalf("reply = %+v, want a conflict frame with revision %d of %q", f, revision, current)
+	}
+	if !strings.Contains(f.Merged, "A\n1\n") || !strings.Contains(f.Merged, current) {
+		t.Errorf("merged = %q, want both versions", f.Merged)
+	}
+	if edits != syncAttempts {
+		t.Errorf("sync attempted %d times, want %d", edits, syncAttempts)
+	}
+}
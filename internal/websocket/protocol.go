@@ -0,0 +1,173 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"unicode/utf8"
+)
+
+// ProtocolV1 is the WebSocket subprotocol exchanging JSON frames. Connections
+// that do not negotiate it exchange the raw board content as text frames.
+const ProtocolV1 = "boardcast.v1"
+
+// Frame types.
+const (
+	// FrameContent carries the board content from the server. Its reason is
+	// set when the content was wiped.
+	FrameContent = "content"
+	// FrameUpdate carries new board content from a client.
+	FrameUpdate = "update"
+	// FrameError reports a rejected client frame.
+	FrameError = "error"
+	// FrameSync carries content a client edited while disconnected, with the
+	// revision and content its edits were based on. Clients of encrypted
+	// boards send the Digest of the base as the server held it instead of
+	// its content.
+	FrameSync = "sync"
+	// FrameSynced answers a sync frame with the content resulting from it.
+	FrameSynced = "synced"
+	// FrameConflict answers a sync frame whose edits overlap changes made on
+	// the server meanwhile. It carries the server content and the merged
+	// content with both versions of the overlapping lines.
+	FrameConflict = "conflict"
+)
+
+// Error codes sent in error frames.
+const (
+	CodeMessageTooLarge = "message_too_large"
+	CodeBoardTooLarge   = "board_too_large"
+	CodeInvalidUTF8     = "invalid_utf8"
+	CodeInvalidFrame    = "invalid_frame"
+	CodeNotEncrypted    = "not_encrypted"
+)
+
+// Default size limits in bytes.
+const (
+	DefaultMaxMessageSize = 1 << 20
+	DefaultMaxBoardSize   = 1 << 20
+)
+
+// hardLimitFactor sets the read limit, relative to the message size limit,
+// beyond which a connection is closed instead of the frame being rejected.
+const hardLimitFactor = 4
+
+var (
+	// ErrMessageTooLarge is returned when a message exceeds the message size limit.
+	ErrMessageTooLarge = errors.New("message exceeds size limit")
+	// ErrBoardTooLarge is returned when an edit would exceed the board size limit.
+	ErrBoardTooLarge = errors.New("board content exceeds size limit")
+	// ErrInvalidUTF8 is returned when content is not valid UTF-8.
+	ErrInvalidUTF8 = errors.New("content is not valid UTF-8")
+	// ErrNotEncrypted is returned when content written to an end-to-end
+	// encrypted board was not encrypted by the client.
+	ErrNotEncrypted = errors.New("board is end-to-end encrypted; content must be encrypted by the client")
+	// errInvalidFrame is returned when a ProtocolV1 frame cannot be decoded.
+	errInvalidFrame = errors.New("invalid frame")
+)
+
+// Limits bounds the size of client messages and board content. Zero values
+// use the defaults.
+type Limits struct {
+	MaxMessageSize int64
+	MaxBoardSize   int64
+	// Encrypted accepts only content encrypted by clients, see SealedPrefix.
+	Encrypted bool
+}
+
+// withDefaults returns l with zero values replaced by the defaults.
+func (l Limits) withDefaults() Limits {
+	if l.MaxMessageSize <= 0 {
+		l.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if l.MaxBoardSize <= 0 {
+		l.MaxBoardSize = DefaultMaxBoardSize
+	}
+	return l
+}
+
+// Check validates content against the board limits.
+func (l Limits) Check(content string) error {
+	if int64(len(content)) > l.MaxBoardSize {
+		return ErrBoardTooLarge
+	}
+	if !utf8.ValidString(content) {
+		return ErrInvalidUTF8
+	}
+	if l.Encrypted && !IsSealed(content) {
+		return ErrNotEncrypted
+	}
+	return nil
+}
+
+// Frame is a JSON message exchanged with ProtocolV1 clients.
+type Frame struct {
+	Type        string  `json:"type"`
+	Content     string  `json:"content"`
+	Revision    uint64  `json:"revision,omitempty"`
+	Base        *uint64 `json:"base,omitempty"`
+	BaseContent *string `json:"base_content,omitempty"`
+	BaseDigest  string  `json:"base_digest,omitempty"`
+	Merged      string  `json:"merged,omitempty"`
+	Code        string  `json:"code,omitempty"`
+	Message     string  `json:"message,omitempty"`
+	Limit       int64   `json:"limit,omitempty"`
+	Reason      string  `json:"reason,omitempty"`
+}
+
+// decodeFrame decodes a ProtocolV1 update or sync frame.
+func decodeFrame(data []byte) (Frame, error) {
+	var frame Frame
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return Frame{}, errInvalidFrame
+	}
+	switch frame.Type {
+	case FrameUpdate, FrameSync:
+		return frame, nil
+	default:
+		return Frame{}, errInvalidFrame
+	}
+}
+
+// errorFrame describes err as a ProtocolV1 error frame.
+func (l Limits) errorFrame(err error) Frame {
+	frame := Frame{Type: FrameError, Message: err.Error()}
+	switch {
+	case errors.Is(err, ErrMessageTooLarge):
+		frame.Code, frame.Limit = CodeMessageTooLarge, l.MaxMessageSize
+	case errors.Is(err, ErrBoardTooLarge):
+		frame.Code, frame.Limit = CodeBoardTooLarge, l.MaxBoardSize
+	case errors.Is(err, ErrInvalidUTF8):
+		frame.Code = CodeInvalidUTF8
+	case errors.Is(err, ErrNotEncrypted):
+		frame.Code = CodeNotEncrypted
+	default:
+		frame.Code = CodeInvalidFrame
+	}
+	return frame
+}
+
+// Compression defaults.
+const (
+	DefaultCompressionLevel     = 1
+	DefaultCompressionThreshold = 1024
+)
+
+// Compression configures permessage-deflate. Level is a compress/flate level
+// from -2 to 9 and Threshold the smallest message size in bytes that is
+// compressed; zero values use the defaults.
+type Compression struct {
+	Disabled  bool
+	Level     int
+	Threshold int
+}
+
+// withDefaults returns c with zero values replaced by the defaults.
+func (c Compression) withDefaults() Compression {
+	if c.Level == 0 {
+		c.Level = DefaultCompressionLevel
+	}
+	if c.Threshold <= 0 {
+		c.Threshold = DefaultCompressionThreshold
+	}
+	return c
+}
@@ -0,0 +1,138 @@
+package websocket
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	sealed := SealedPrefix + strings.Repeat("A", 40)
+	tests := []struct {
+		name    string
+		limits  Limits
+		content string
+		want    error
+	}{
+		{"empty", Limits{MaxBoardSize: 4}, "", nil},
+		{"at limit", Limits{MaxBoardSize: 4}, "abcd", nil},
+		{"over limit", Limits{MaxBoardSize: 4}, "abcde", ErrBoardTooLarge},
+		{"limit counts bytes", Limits{MaxBoardSize: 4}, "ééé", ErrBoardTooLarge},
+		{"invalid UTF-8", Limits{MaxBoardSize: 4}, "a\xffb", ErrInvalidUTF8},
+		{"encrypted", Limits{MaxBoardSize: 100, Encrypted: true}, sealed, nil},
+		{"encrypted empty", Limits{MaxBoardSize: 100, Encrypted: true}, "", nil},
+		{"not encrypted", Limits{MaxBoardSize: 100, Encrypted: true}, "plain", ErrNotEncrypted},
+		{"prefix only", Limits{MaxBoardSize: 100, Encrypted: true}, SealedPrefix + "AAAA", ErrNotEncrypted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.limits.Check(tt.content); !errors.Is(err, tt.want) {
+				t.Errorf("Check() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorFrame(t *testing.T) {
+	limits := Limits{MaxMessageSize: 10, MaxBoardSize: 20}
+	tests := []struct {
+		err   error
+		code  string
+		limit int64
+	}{
+		{ErrMessageTooLarge, CodeMessageTooLarge, 10},
+		{ErrBoardTooLarge, CodeBoardTooLarge, 20},
+		{ErrInvalidUTF8, CodeInvalidUTF8, 0},
+		{ErrNotEncrypted, CodeNotEncrypted, 0},
+		{errInvalidFrame, CodeInvalidFrame, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			f := limits.errorFrame(tt.err)
+			if f.Type != FrameError || f.Code != tt.code || f.Limit != tt.limit || f.Message != tt.err.Error() {
+				t.Errorf("errorFrame() = %+v, want code %s with limit %d", f, tt.code, tt.limit)
+			}
+		})
+	}
+}
+
+// TestRejectFrame checks that ProtocolV1 clients receive an error frame for
+// each rejected message and stay connected.
+func TestRejectFrame(t *testing.T) {
+	sealed := SealedPrefix + strings.Repeat("A", 40)
+	tests := []struct {
+		name    string
+		limits  Limits
+		message string
+		code    string
+		limit   int64
+	}{
+		{"message too large", Limits{MaxMessageSize: 40}, `{"type":"update","content":"` + strings.Repeat("a", 40) + `"}`, CodeMessageTooLarge, 40},
+		{"board too large", Limits{MaxMessageSize: 64, MaxBoardSize: 4}, `{"type":"update","content":"abcde"}`, CodeBoardTooLarge, 4},
+		{"invalid UTF-8", Limits{}, "{\"type\":\"update\",\"content\":\"\xff\"}", CodeInvalidUTF8, 0},
+		{"not JSON", Limits{}, "hello", CodeInvalidFrame, 0},
+		{"server frame type", Limits{}, `{"type":"content","content":"a"}`, CodeInvalidFrame, 0},
+		{"not encrypted", Limits{Encrypted: true}, `{"type":"update","content":"plain"}`, CodeNotEncrypted, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, srv := newTestHub(t, Options{Limits: tt.limits}, "")
+			conn := dial(t, srv, true)
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.message)); err != nil {
+				t.Fatal(err)
+			}
+			f := readTestFrame(t, conn)
+			if f.Type != FrameError || f.Code != tt.code || f.Limit != tt.limit {
+				t.Errorf("reply = %+v, want code %s with limit %d", f, tt.code, tt.limit)
+			}
+
+			// The connection stays usable.
+			content := "ok"
+			if tt.limits.Encrypted {
+				content = sealed
+			}
+			if err := conn.WriteJSON(Frame{Type: FrameUpdate, Content: content}); err != nil {
+				t.Fatal(err)
+			}
+			waitContent(t, h, content)
+		})
+	}
+}
+
+// TestRejectRaw checks that clients without ProtocolV1 are closed with a
+// close code matching the rejected message.
+func TestRejectRaw(t *testing.T) {
+	tests := []struct {
+		name    string
+		limits  Limits
+		message string
+		code    int
+	}{
+		{"message too large", Limits{MaxMessageSize: 4}, "abcde", websocket.CloseMessageTooBig},
+		{"board too large", Limits{MaxMessageSize: 64, MaxBoardSize: 4}, "abcde", websocket.CloseMessageTooBig},
+		{"invalid UTF-8", Limits{}, "a\xff", websocket.CloseInvalidFramePayloadData},
+		{"not encrypted", Limits{Encrypted: true}, "plain", websocket.ClosePolicyViolation},
+		// Past the hard limit the frame is not even read.
+		{"far too large", Limits{MaxMessageSize: 4}, strings.Repeat("a", 4*hardLimitFactor+1), websocket.CloseMessageTooBig},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, srv := newTestHub(t, Options{Limits: tt.limits}, "")
+			conn := dial(t, srv, false)
+
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(tt.message)); err != nil {
+				t.Fatal(err)
+			}
+			_, _, err := conn.ReadMessage()
+			if !websocket.IsCloseError(err, tt.code) {
+				t.Errorf("read error = %v, want close code %d", err, tt.code)
+			}
+			if got := h.GetContent(); got != "" {
+				t.Errorf("board = %q, want the message rejected", got)
+			}
+		})
+	}
+}
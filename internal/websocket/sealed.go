@@ -0,0 +1,29 @@
+package websocket
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// SealedPrefix starts board content encrypted by clients of end-to-end
+// encrypted boards. It is followed by the standard base64 encoding of a 12
+// byte AES-GCM nonce and the ciphertext including its 16 byte tag. The key is
+// derived from a passphrase the server never sees.
+const SealedPrefix = "e2e1:"
+
+// sealedOverhead is the size of the nonce and tag of sealed content.
+const sealedOverhead = 12 + 16
+
+// IsSealed reports whether content is empty or has the form of content
+// encrypted by a client.
+func IsSealed(content string) bool {
+	if content == "" {
+		return true
+	}
+	data, ok := strings.CutPrefix(content, SealedPrefix)
+	if !ok {
+		return false
+	}
+	n, err := base64.StdEncoding.DecodeString(data)
+	return err == nil && len(n) >= sealedOverhead
+}
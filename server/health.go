@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yosebyte/boardcast/internal/storage"
+)
+
+// componentStatus is the health of a single component.
+type componentStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthResponse is the JSON body returned by the health endpoints.
+type healthResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]componentStatus `json:"components,omitempty"`
+}
+
+// handleHealthz reports that the process is alive.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, healthResponse{Status: "ok"})
+}
+
+// handleReadyz reports whether the server can accept traffic.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	components := map[string]componentStatus{
+		"hub":      {Status: "ok"},
+		"storage":  {Status: "ok"},
+		"shutdown": {Status: "ok"},
+	}
+
+	if !s.wsHub.Running() {
+		components["hub"] = componentStatus{Status: "fail", Error: "hub goroutines not running"}
+	}
+	if err := storage.Check(s.store); err != nil {
+		components["storage"] = componentStatus{Status: "fail", Error: err.Error()}
+	}
+	if s.draining.Load() {
+		components["shutdown"] = componentStatus{Status: "fail", Error: "shutting down"}
+	}
+
+	resp := healthResponse{Status: "ok", Components: components}
+	status := http.StatusOK
+	for _, c := range components {
+		if c.Status != "ok" {
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	writeHealth(w, status, resp)
+}
+
+// writeHealth writes a health response as JSON.
+func writeHealth(w http.ResponseWriter, status int, resp healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
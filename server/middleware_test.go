@@ -0,0 +1,44 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/items/7", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	accessLog(logger, mux).ServeHTTP(httptest.NewRecorder(), r)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("log %q is not one JSON record: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":         "HTTP request",
+		"method":      "POST",
+		"path":        "/items/7",
+		"route":       "/items/{id}",
+		"status":      float64(http.StatusCreated),
+		"remote_addr": "192.0.2.1:1234",
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("%s = %v, want %v", key, record[key], value)
+		}
+	}
+	if _, ok := record["duration"]; !ok {
+		t.Error("record has no duration")
+	}
+}
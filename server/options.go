@@ -0,0 +1,283 @@
+package server
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/backplane"
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/webhook"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// Store persists board snapshots.
+type Store = storage.Store
+
+// AuthProvider authenticates requests and handles login and logout.
+type AuthProvider = auth.Provider
+
+// AuditLog stores and queries audit entries.
+type AuditLog = audit.Log
+
+// NewFileAuditLog opens or creates an append-only JSON lines audit log at path.
+func NewFileAuditLog(path string) (AuditLog, error) {
+	return audit.NewFileLog(path)
+}
+
+// Webhook is the configuration of one outgoing webhook.
+type Webhook = webhook.Hook
+
+// LoadWebhooks reads a JSON array of webhook configurations from path.
+func LoadWebhooks(path string) ([]Webhook, error) {
+	return webhook.LoadConfig(path)
+}
+
+// InboundWebhook is the configuration of one inbound webhook URL.
+type InboundWebhook = webhook.InboundHook
+
+// LoadInboundWebhooks reads a JSON array of inbound webhook configurations from path.
+func LoadInboundWebhooks(path string) ([]InboundWebhook, error) {
+	return webhook.LoadInboundConfig(path)
+}
+
+// Backplane relays board updates between instances.
+type Backplane = backplane.Backplane
+
+// NewLocalBackplane returns a backplane connecting servers in the same process.
+func NewLocalBackplane() Backplane {
+	return backplane.NewLocal()
+}
+
+// NewRedisBackplane returns a backplane using the pub/sub commands of the
+// Redis-protocol server at rawURL, e.g. "redis://localhost:6379/0".
+func NewRedisBackplane(rawURL string, logger *slog.Logger) (Backplane, error) {
+	return backplane.NewRedis(rawURL, logger)
+}
+
+// NewRedisStore returns a Store keeping snapshots in the Redis-protocol server at rawURL.
+func NewRedisStore(rawURL string) (Store, error) {
+	return storage.NewRedisStore(rawURL)
+}
+
+// NewFileStore returns a Store keeping snapshot files in dir.
+func NewFileStore(dir string) Store {
+	return storage.NewFileStore(dir)
+}
+
+// NewMemoryStore returns a Store keeping snapshots in memory.
+func NewMemoryStore() Store {
+	return storage.NewMemoryStore()
+}
+
+// Keyring holds the keys encrypting snapshots and history at rest.
+type Keyring = storage.Keyring
+
+// ParseKeyring parses storage keys given one per line, or separated by
+// commas, as "id:hexkey" with 32-byte keys. The first key encrypts new data.
+func ParseKeyring(text string) (*Keyring, error) {
+	return storage.ParseKeyring(text)
+}
+
+// ReadKeyring parses the storage keys in the file at path.
+func ReadKeyring(path string) (*Keyring, error) {
+	return storage.ReadKeyring(path)
+}
+
+// options holds the settings collected from Option values.
+type options struct {
+	store          Store
+	storageKeys    *Keyring
+	auth           AuthProvider
+	audit          AuditLog
+	webhooks       []Webhook
+	inbound        []InboundWebhook
+	attachmentDir  string
+	attachmentSize int64
+	maxMessage     int64
+	maxBoard       int64
+	encrypted      bool
+	rates          websocket.RateLimits
+	broadcastTick  time.Duration
+	compression    websocket.Compression
+	backplane      Backplane
+	replicaPeer    string
+	replicaToken   string
+	replicaState   string
+	expiry         storage.Expiry
+	sessionKey     []byte
+	password       string
+	token          string
+	logger         *slog.Logger
+	basePath       string
+	version        string
+}
+
+// Option configures a Server.
+type Option func(*options)
+
+// WithStore sets the snapshot store. Defaults to an in-memory store.
+func WithStore(store Store) Option {
+	return func(o *options) { o.store = store }
+}
+
+// WithStorageKeys encrypts snapshots and history with AES-GCM before they
+// reach the store, which must be a file or Redis store. Data written before
+// encryption was enabled stays readable until rewritten.
+func WithStorageKeys(keys *Keyring) Option {
+	return func(o *options) { o.storageKeys = keys }
+}
+
+// WithAuth sets a custom authentication provider, overriding WithPassword and WithToken.
+func WithAuth(provider AuthProvider) Option {
+	return func(o *options) { o.auth = provider }
+}
+
+// WithAuditLog records logins, edits, saves and restores. Disabled by default.
+func WithAuditLog(log AuditLog) Option {
+	return func(o *options) { o.audit = log }
+}
+
+// WithWebhooks sends signed notifications about board events to hooks.
+func WithWebhooks(hooks []Webhook) Option {
+	return func(o *options) { o.webhooks = hooks }
+}
+
+// WithInboundWebhooks accepts messages posted to /api/v1/hooks/{id} by hooks.
+func WithInboundWebhooks(hooks []InboundWebhook) Option {
+	return func(o *options) { o.inbound = hooks }
+}
+
+// WithAttachments stores uploaded files in dir, rejecting files larger than
+// maxSize bytes. A maxSize of zero uses a 10 MiB limit. Disabled by default.
+func WithAttachments(dir string, maxSize int64) Option {
+	return func(o *options) {
+		o.attachmentDir = dir
+		o.attachmentSize = maxSize
+	}
+}
+
+// WithMaxMessageSize limits the size in bytes of WebSocket messages and HTTP
+// write request bodies. Defaults to 1 MiB.
+func WithMaxMessageSize(n int64) Option {
+	return func(o *options) { o.maxMessage = n }
+}
+
+// WithMaxBoardSize limits the size in bytes of board content. Defaults to 1 MiB.
+func WithMaxBoardSize(n int64) Option {
+	return func(o *options) { o.maxBoard = n }
+}
+
+// WithEndToEndEncryption accepts only board content encrypted by browsers
+// with a key derived from a passphrase kept in the URL fragment, so that the
+// server, its storage and history hold ciphertext only. Browsers require
+// HTTPS, or localhost, for the encryption. Merging offline edits is left to
+// the browser, and PATCH requests and inbound webhooks, which change the
+// content on the server, are rejected.
+func WithEndToEndEncryption() Option {
+	return func(o *options) { o.encrypted = true }
+}
+
+// WithRateLimits limits WebSocket updates to perConnection updates per second
+// on each connection and perUser across a user's connections, allowing bursts
+// of burst updates. Negative rates disable a limit; zero values keep the
+// defaults of 20, 50 and 40.
+func WithRateLimits(perConnection, perUser float64, burst int) Option {
+	return func(o *options) {
+		o.rates = websocket.RateLimits{PerConnection: perConnection, PerUser: perUser, Burst: burst}
+	}
+}
+
+// WithBroadcastTick coalesces WebSocket updates arriving within tick into a
+// single broadcast. Defaults to 50ms; a negative tick broadcasts every update.
+func WithBroadcastTick(tick time.Duration) Option {
+	return func(o *options) { o.broadcastTick = tick }
+}
+
+// WithCompression negotiates permessage-deflate at the given compress/flate
+// level, compressing messages of at least threshold bytes. Compression is
+// enabled by default at level 1 with a 1 KiB threshold.
+func WithCompression(level, threshold int) Option {
+	return func(o *options) {
+		o.compression = websocket.Compression{Level: level, Threshold: threshold}
+	}
+}
+
+// WithoutCompression disables permessage-deflate.
+func WithoutCompression() Option {
+	return func(o *options) { o.compression = websocket.Compression{Disabled: true} }
+}
+
+// WithBackplane relays edits through bp so that clients of every server
+// sharing it see the same board. Servers should also share a Store and a
+// session key.
+func WithBackplane(bp Backplane) Option {
+	return func(o *options) { o.backplane = bp }
+}
+
+// WithReplication keeps the board in sync with the board of the boardcast
+// server at peer, its base URL, authenticating with token. Edits made on both
+// servers while disconnected are joined with conflict markers and both
+// versions kept in the history. statePath is where the replication state is
+// saved across restarts; without it every restart is treated as a first sync.
+func WithReplication(peer, token, statePath string) Option {
+	return func(o *options) {
+		o.replicaPeer = peer
+		o.replicaToken = token
+		o.replicaState = statePath
+	}
+}
+
+// WithExpiry wipes the board content ttl after the last edit, or, with
+// burnAfterReading, shortly after someone other than its author viewed it.
+// The wipe also purges the stored snapshot and history. It applies until a
+// different expiry is set through the API; a zero ttl keeps content.
+func WithExpiry(ttl time.Duration, burnAfterReading bool) Option {
+	return func(o *options) {
+		o.expiry = storage.Expiry{TTL: ttl, BurnAfterReading: burnAfterReading}
+	}
+}
+
+// WithSessionKey sets the key signing session cookies of the built-in
+// authentication, letting servers that share it accept each other's sessions.
+// Defaults to a random key.
+func WithSessionKey(key []byte) Option {
+	return func(o *options) { o.sessionKey = key }
+}
+
+// WithPassword enables the built-in password authentication.
+func WithPassword(password string) Option {
+	return func(o *options) { o.password = password }
+}
+
+// WithToken sets the API token accepted by the built-in authentication.
+func WithToken(token string) Option {
+	return func(o *options) { o.token = token }
+}
+
+// WithLogger sets the structured logger. Defaults to slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// WithBasePath mounts every route under path, e.g. "/board".
+func WithBasePath(path string) Option {
+	return func(o *options) { o.basePath = normalizeBasePath(path) }
+}
+
+// WithVersion sets the version shown in the page footer.
+func WithVersion(version string) Option {
+	return func(o *options) { o.version = version }
+}
+
+// normalizeBasePath returns path with a leading slash and no trailing slash,
+// or an empty string for the root.
+func normalizeBasePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
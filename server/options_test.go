@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// headerAuth accepts requests carrying the X-Test header.
+type headerAuth struct{}
+
+func (headerAuth) IsAuthenticated(r *http.Request) bool      { return r.Header.Get("X-Test") != "" }
+func (headerAuth) Login(http.ResponseWriter, *http.Request)  {}
+func (headerAuth) Logout(http.ResponseWriter, *http.Request) {}
+
+// get requests path from s with header and returns the response.
+func get(s http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, values := range header {
+		r.Header[key] = values
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	return w
+}
+
+func TestNewRequiresAuth(t *testing.T) {
+	if _, err := New(); err == nil {
+		t.Error("New() without authentication succeeded")
+	}
+}
+
+func TestWithAuth(t *testing.T) {
+	// The provider replaces the password.
+	s := newTestServer(t, WithAuth(headerAuth{}))
+	if w := get(s, "/content", http.Header{"X-Test": {"1"}}); w.Code != http.StatusOK {
+		t.Errorf("GET /content by the provider = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := get(s, "/content", http.Header{"Authorization": {"Bearer tk"}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /content with the token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestWithStore(t *testing.T) {
+	store := NewMemoryStore()
+	s := newTestServer(t, WithStore(store))
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/v1/boards/default/content", strings.NewReader(`{"op":"append","text":"kept"}`))
+	r.Header.Set("Authorization", "Bearer tk")
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("PATCH = %d: %s", w.Code, w.Body)
+	}
+
+	// Shutdown saves unsaved content to the store.
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if snapshot, err := store.LoadSnapshot("default"); err != nil || string(snapshot) != "kept" {
+		t.Fatalf("snapshot = %q, %v, want %q", snapshot, err, "kept")
+	}
+
+	restarted := newTestServer(t, WithStore(store))
+	if w := get(restarted, "/content", http.Header{"Authorization": {"Bearer tk"}}); w.Body.String() != "kept" {
+		t.Errorf("content after restart = %q, want %q", w.Body, "kept")
+	}
+}
+
+func TestServersAreIndependent(t *testing.T) {
+	a := newTestServer(t, WithBasePath("/a"))
+	b := newTestServer(t, WithBasePath("/b"), WithToken("other"))
+
+	// Each server has its own routes and credentials, and none are added
+	// to the default mux.
+	if w := get(a, "/a/content", http.Header{"Authorization": {"Bearer tk"}}); w.Code != http.StatusOK {
+		t.Errorf("GET /a/content = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w := get(a, "/b/content", http.Header{"Authorization": {"Bearer tk"}}); w.Code != http.StatusNotFound {
+		t.Errorf("GET /b/content from a = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := get(b, "/b/content", http.Header{"Authorization": {"Bearer tk"}}); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /b/content with a's token = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, "/a/content", nil)); pattern != "" {
+		t.Errorf("default mux serves /a/content with %q", pattern)
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	for path, want := range map[string]string{
+		"":        "",
+		"/":       "",
+		"board":   "/board",
+		"/board/": "/board",
+		"/a/b":    "/a/b",
+	} {
+		if got := normalizeBasePath(path); got != want {
+			t.Errorf("normalizeBasePath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package server provides an embeddable boardcast HTTP handler for use inside other Go programs.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yosebyte/boardcast/internal/attachment"
+	"github.com/yosebyte/boardcast/internal/audit"
+	"github.com/yosebyte/boardcast/internal/auth"
+	"github.com/yosebyte/boardcast/internal/handler"
+	"github.com/yosebyte/boardcast/internal/metrics"
+	"github.com/yosebyte/boardcast/internal/replica"
+	"github.com/yosebyte/boardcast/internal/storage"
+	"github.com/yosebyte/boardcast/internal/webhook"
+	"github.com/yosebyte/boardcast/internal/websocket"
+)
+
+// Server is a self-contained boardcast instance served through its own ServeMux.
+type Server struct {
+	mux      *http.ServeMux
+	handler  http.Handler
+	metrics  *metrics.Metrics
+	webhooks *webhook.Dispatcher
+	wsHub    *websocket.Hub
+	handlers *handler.Handlers
+	store    Store
+	basePath string
+	draining atomic.Bool
+
+	attachments *attachment.Store
+	replica     *replica.Replicator
+	logger      *slog.Logger
+	stop        chan struct{}
+	stopOnce    sync.Once
+}
+
+// attachmentGCInterval is how often unreferenced attachments are removed.
+const attachmentGCInterval = time.Hour
+
+// New creates a server from the given options and starts its WebSocket hub.
+func New(opts ...Option) (*Server, error) {
+	o := options{version: "dev"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.store == nil {
+		o.store = storage.NewMemoryStore()
+	}
+	if o.storageKeys != nil {
+		if err := storage.Encrypt(o.store, o.storageKeys); err != nil {
+			return nil, err
+		}
+	}
+	// Refuse to start with an empty board that would overwrite content
+	// sealed with a key that is not configured.
+	if _, err := o.store.LoadSnapshot(websocket.DefaultBoard); storage.IsKeyError(err) {
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	if o.logger == nil {
+		o.logger = slog.Default()
+	}
+	if o.audit == nil {
+		o.audit = audit.Discard
+	}
+
+	if o.auth == nil {
+		if o.password == "" {
+			return nil, errors.New("no authentication configured: use WithPassword or WithAuth")
+		}
+		authManager, err := auth.NewManager(o.password, auth.Options{
+			Token:      o.token,
+			BasePath:   o.basePath,
+			SessionKey: o.sessionKey,
+			Logger:     o.logger,
+			Audit:      o.audit,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create auth manager: %w", err)
+		}
+		o.auth = authManager
+	}
+
+	inbound, err := webhook.NewInbound(o.inbound)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments *attachment.Store
+	if o.attachmentDir != "" {
+		attachments, err = attachment.NewStore(o.attachmentDir, o.attachmentSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create attachment store: %w", err)
+		}
+	}
+
+	m := metrics.New()
+	dispatcher := webhook.NewDispatcher(o.webhooks, o.logger)
+	wsHub := websocket.NewHub(websocket.Options{
+		Store:   o.store,
+		Logger:  o.logger,
+		Metrics: m,
+		Audit:   o.audit,
+		Limits: websocket.Limits{
+			MaxMessageSize: o.maxMessage,
+			MaxBoardSize:   o.maxBoard,
+			Encrypted:      o.encrypted,
+		},
+		Rates:         o.rates,
+		BroadcastTick: o.broadcastTick,
+		Compression:   o.compression,
+		Backplane:     o.backplane,
+		OnEvent:       dispatcher.Notify,
+		Expiry:        o.expiry,
+	})
+
+	var replicator *replica.Replicator
+	if o.replicaPeer != "" {
+		replicator, err = replica.New(replica.Options{
+			Peer:      o.replicaPeer,
+			Token:     o.replicaToken,
+			Hub:       wsHub,
+			StatePath: o.replicaState,
+			Audit:     o.audit,
+			OnEvent:   dispatcher.Notify,
+			Metrics:   m,
+			Logger:    o.logger,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create replicator: %w", err)
+		}
+	}
+
+	s := &Server{
+		mux:      http.NewServeMux(),
+		metrics:  m,
+		webhooks: dispatcher,
+		wsHub:    wsHub,
+		handlers: handler.New(handler.Options{
+			Auth:        o.auth,
+			Hub:         wsHub,
+			Version:     o.version,
+			BasePath:    o.basePath,
+			Metrics:     m,
+			Audit:       o.audit,
+			Webhooks:    dispatcher,
+			Inbound:     inbound,
+			Attachments: attachments,
+			Replica:     replicator,
+			Logger:      o.logger,
+		}),
+		store:       o.store,
+		basePath:    o.basePath,
+		attachments: attachments,
+		replica:     replicator,
+		logger:      o.logger,
+		stop:        make(chan struct{}),
+	}
+
+	s.registerRoutes()
+	s.handler = accessLog(o.logger, m.Middleware(s.basePath, s.mux))
+	wsHub.Start()
+	if replicator != nil {
+		replicator.Start()
+	}
+	// The references on encrypted boards cannot be read, so attachments
+	// uploaded before the board was encrypted are kept.
+	if attachments != nil && !wsHub.Limits().Encrypted {
+		go s.collectAttachments(attachmentGCInterval)
+	}
+
+	return s, nil
+}
+
+// ServeHTTP dispatches the request to the server's routes.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// Drain marks the server as shutting down so readiness checks fail and load
+// balancers stop routing new traffic. Requests are still served normally.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+// Shutdown drains the server, persists unsaved content, closes every WebSocket
+// client with a restart notice and stops the hub. It does not affect the
+// enclosing HTTP server.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.Drain()
+	s.stopOnce.Do(func() { close(s.stop) })
+	if s.replica != nil {
+		if err := s.replica.Close(); err != nil {
+			s.logger.Warn("Failed to save replication state", "error", err)
+		}
+	}
+	return errors.Join(s.wsHub.Shutdown(ctx), s.webhooks.Close(ctx))
+}
+
+// registerRoutes sets up all HTTP routes under the base path.
+func (s *Server) registerRoutes() {
+	s.handle("/", s.handlers.ServeWhiteboard)
+	s.handle("/auth", s.handlers.HandleAuth)
+	s.handle("/logout", s.handlers.HandleLogout)
+	s.handle("/ws", s.handlers.HandleWebSocket)
+	s.handle("/content", s.handlers.HandleContent)
+	s.handle("/save", s.handlers.HandleSave)
+	s.handle("/restore", s.handlers.HandleRestore)
+	s.handle("/export", s.handlers.HandleExport)
+	s.handle("/manifest.webmanifest", s.handlers.HandleManifest)
+	s.handle("/sw.js", s.handlers.HandleServiceWorker)
+	s.handle("/icons/{name}", s.handlers.HandleIcon)
+	s.handle("/api/v1/boards/{name}/content", s.handlers.HandleBoardContent)
+	s.handle("/api/v1/boards/{name}/expiry", s.handlers.HandleExpiry)
+	s.handle("/api/v1/boards/{name}/history", s.handlers.HandleHistory)
+	s.handle("/api/v1/boards/{name}/history/{id}", s.handlers.HandleVersion)
+	s.handle("/api/v1/replication", s.handlers.HandleReplication)
+	s.handle("/api/v1/audit", s.handlers.HandleAudit)
+	s.handle("/api/v1/webhooks", s.handlers.HandleWebhooks)
+	s.handle("/api/v1/webhooks/{id}/test", s.handlers.HandleWebhookTest)
+	s.handle("/api/v1/hooks/{id}", s.handlers.HandleInboundHook)
+	s.handle("/api/v1/attachments", s.handlers.HandleUpload)
+	s.handle("/attachments/{name}", s.handlers.HandleAttachment)
+	s.mux.Handle(s.basePath+"/metrics", s.metrics.Handler())
+	s.handle("/healthz", s.handleHealthz)
+	s.handle("/readyz", s.handleReadyz)
+}
+
+// collectAttachments periodically removes attachments referenced neither by
+// the live board, its saved snapshot nor any version in its history.
+func (s *Server) collectAttachments(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+
+		contents, err := s.boardContents()
+		if err != nil {
+			s.logger.Warn("Skipping attachment collection, board content unavailable", "error", err)
+			continue
+		}
+
+		removed, err := s.attachments.Collect(contents...)
+		if err != nil {
+			s.logger.Error("Failed to collect attachments", "error", err)
+		} else if removed > 0 {
+			s.logger.Info("Removed unreferenced attachments", "count", removed)
+		}
+	}
+}
+
+// boardContents returns every content that may reference attachments: the
+// live board, its snapshot and its history, since restoring a version brings
+// its references back.
+func (s *Server) boardContents() ([]string, error) {
+	contents := []string{s.wsHub.GetContent()}
+	snapshot, err := s.store.LoadSnapshot(websocket.DefaultBoard)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return nil, err
+	}
+	contents = append(contents, string(snapshot))
+
+	versions, err := s.wsHub.Versions()
+	if errors.Is(err, websocket.ErrNoHistory) {
+		return contents, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, v := range versions {
+		version, err := s.wsHub.Version(v.ID)
+		if errors.Is(err, storage.ErrVersionNotFound) {
+			// Dropped from the history meanwhile.
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		contents = append(contents, version.Content)
+	}
+	return contents, nil
+}
+
+// handle registers a handler for pattern prefixed with the base path.
+func (s *Server) handle(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(s.basePath+pattern, handler)
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/yosebyte/boardcast/internal/audit"
+)
+
+func TestCollectAttachmentsKeepsHistory(t *testing.T) {
+	dir := t.TempDir()
+	s, err := New(WithPassword("pw"), WithAttachments(dir, 0), WithLogger(slog.New(slog.DiscardHandler)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+
+	old := strings.Repeat("a", 64) + ".png"
+	live := strings.Repeat("b", 64) + ".png"
+	unused := strings.Repeat("c", 64) + ".png"
+	for _, name := range []string{old, live, unused} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		past := time.Now().Add(-48 * time.Hour)
+		if err := os.Chtimes(path, past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The old attachment is only referenced by a version in the history.
+	for _, content := range []string{"![old](/attachments/" + old + ")", "![live](/attachments/" + live + ")"} {
+		if _, err := s.wsHub.Update(audit.Actor{}, nil, func(string) (string, error) { return content, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	contents, err := s.boardContents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	removed, err := s.attachments.Collect(contents...)
+	if err != nil || removed != 1 {
+		t.Fatalf("Collect() = %d, %v, want 1 removed", removed, err)
+	}
+	for name, want := range map[string]bool{old: true, live: true, unused: false} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s kept = %v, want %v", name, err == nil, want)
+		}
+	}
+}
+
+// newTestServer returns a server accepting the password "pw" and the token "tk".
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	opts = append([]Option{WithPassword("pw"), WithToken("tk"), WithLogger(slog.New(slog.DiscardHandler))}, opts...)
+	s, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Shutdown(context.Background()) })
+	return s
+}
+
+// readiness requests path from s and returns the status code and response.
+func readiness(t *testing.T, s *Server, path string) (int, healthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var resp healthResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	return w.Code, resp
+}
+
+func TestReadyz(t *testing.T) {
+	s := newTestServer(t)
+
+	if code, resp := readiness(t, s, "/readyz"); code != http.StatusOK || resp.Status != "ok" {
+		t.Fatalf("readyz = %d %+v, want ready", code, resp)
+	}
+
+	s.Drain()
+	code, resp := readiness(t, s, "/readyz")
+	if code != http.StatusServiceUnavailable || resp.Status != "unavailable" || resp.Components["shutdown"].Status != "fail" {
+		t.Errorf("readyz after Drain = %d %+v, want unavailable while shutting down", code, resp)
+	}
+	if resp.Components["hub"].Status != "ok" || resp.Components["storage"].Status != "ok" {
+		t.Errorf("readyz after Drain = %+v, want the hub and storage still ok", resp)
+	}
+	// Draining servers are still alive.
+	if code, resp := readiness(t, s, "/healthz"); code != http.StatusOK || resp.Status != "ok" {
+		t.Errorf("healthz after Drain = %d %+v, want ok", code, resp)
+	}
+}
+
+func TestShutdownRestartsClients(t *testing.T) {
+	s := newTestServer(t)
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	header := http.Header{"Authorization": {"Bearer tk"}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- s.Shutdown(ctx)
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseServiceRestart) {
+		t.Fatalf("read = %v, want a %d close frame", err, websocket.CloseServiceRestart)
+	}
+	// The client answered the close frame, so Shutdown does not wait for
+	// the timeout.
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	if !s.draining.Load() {
+		t.Error("Shutdown did not drain the server")
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	s := newTestServer(t, WithBasePath("board/"))
+
+	tests := []struct {
+		path string
+		want int
+		body string
+	}{
+		{"/board/", http.StatusOK, `basePath = "/board"`},
+		{"/board/manifest.webmanifest", http.StatusOK, `"start_url": "./"`},
+		{"/board/sw.js", http.StatusOK, `const base = "/board"`},
+		{"/board/icons/icon.svg", http.StatusOK, "<svg"},
+		{"/board/healthz", http.StatusOK, `"ok"`},
+		{"/board/metrics", http.StatusOK, ""},
+		{"/board/content", http.StatusUnauthorized, ""},
+		{"/", http.StatusNotFound, ""},
+		{"/ws", http.StatusNotFound, ""},
+		{"/healthz", http.StatusNotFound, ""},
+		{"/sw.js", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.want || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("GET %s = %d %.80q, want %d with %q", tt.path, w.Code, w.Body, tt.want, tt.body)
+		}
+	}
+
+	// The session cookie is scoped to the base path and opens the WebSocket.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/board/auth", strings.NewReader(`{"password":"pw"}`)))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Path != "/board" {
+		t.Fatalf("login = %d with cookies %v, want one for /board", w.Code, cookies)
+	}
+
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+	header := http.Header{"Cookie": {cookies[0].String()}}
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/board/ws", header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}